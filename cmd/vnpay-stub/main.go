@@ -0,0 +1,355 @@
+// Server giả lập VNPAY để thử đối soát querydr ở local.
+//
+//	VNPAY_TMNCODE=... VNPAY_HASH_SECRET=... go run ./cmd/vnpay-stub
+//
+// Sau đó trỏ VNPAY_URL=http://localhost:9090/paymentv2/vpcpay.html và
+// VNPAY_API_URL=http://localhost:9090/merchant_webapi/api/transaction.
+// STUB_IPN_URL (mặc định http://localhost:8080/vnpay_ipn) là IPN URL như khai báo trên cổng merchant.
+//
+// Trang thanh toán nhận thêm ?stub_outcome= (không nằm trong chữ ký):
+//   - success:  ghi nhận đã thanh toán, gọi IPN rồi chuyển hướng về vnp_ReturnUrl (mặc định)
+//   - lost_ipn: ghi nhận đã thanh toán nhưng KHÔNG gọi IPN (mô phỏng mất IPN)
+//   - pending:  giao dịch đang xử lý (TransactionStatus 01), không gọi IPN
+//   - failure:  khách hủy giao dịch (ResponseCode 24), gọi IPN rồi chuyển hướng về vnp_ReturnUrl
+//
+// POST /stub/transactions {"txn_ref","amount","status","transaction_no"} để tạo sẵn giao dịch,
+// GET /stub/transactions để xem các giao dịch đang giữ.
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var vnpayLoc = time.FixedZone("Asia/Ho_Chi_Minh", 7*60*60)
+
+type transaction struct {
+	TxnRef        string `json:"txn_ref"`
+	Amount        int64  `json:"amount"` // VNĐ
+	Status        string `json:"status"` // vnp_TransactionStatus: 00 thành công, 01 đang xử lý, 02 lỗi
+	TransactionNo string `json:"transaction_no"`
+	BankCode      string `json:"bank_code"`
+	OrderInfo     string `json:"order_info"`
+	PayDate       string `json:"pay_date"` // yyyyMMddHHmmss
+	RefundedTotal int64  `json:"refunded_total"`
+}
+
+type stubServer struct {
+	tmnCode    string
+	hashSecret string
+	ipnUrl     string
+
+	mu           sync.Mutex
+	transactions map[string]*transaction
+}
+
+func main() {
+	addr := os.Getenv("STUB_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+	s := &stubServer{
+		tmnCode:      strings.TrimSpace(os.Getenv("VNPAY_TMNCODE")),
+		hashSecret:   strings.TrimSpace(os.Getenv("VNPAY_HASH_SECRET")),
+		ipnUrl:       strings.TrimSpace(os.Getenv("STUB_IPN_URL")),
+		transactions: map[string]*transaction{},
+	}
+	if s.hashSecret == "" {
+		log.Fatal("Thiếu VNPAY_HASH_SECRET")
+	}
+	if s.ipnUrl == "" {
+		s.ipnUrl = "http://localhost:8080/vnpay_ipn"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/paymentv2/vpcpay.html", s.handlePay)
+	mux.HandleFunc("/merchant_webapi/api/transaction", s.handleMerchantAPI)
+	mux.HandleFunc("/stub/transactions", s.handleTransactions)
+
+	log.Printf("VNPAY stub đang chạy tại %s", addr)
+	log.Fatal(http.ListenAndServe(addr, mux))
+}
+
+// Trang thanh toán: kiểm tra chữ ký như VNPAY rồi xử lý theo stub_outcome
+func (s *stubServer) handlePay(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	outcome := params.Get("stub_outcome")
+	params.Del("stub_outcome")
+
+	if params.Get("vnp_SecureHash") != s.signQuery(params) {
+		http.Error(w, "Sai chữ ký (code 97)", http.StatusBadRequest)
+		return
+	}
+
+	amount, _ := strconv.ParseInt(params.Get("vnp_Amount"), 10, 64)
+	now := time.Now().In(vnpayLoc)
+	txn := &transaction{
+		TxnRef:        params.Get("vnp_TxnRef"),
+		Amount:        amount / 100,
+		Status:        "00",
+		TransactionNo: strconv.FormatInt(now.UnixNano()%100000000, 10),
+		BankCode:      "NCB",
+		OrderInfo:     params.Get("vnp_OrderInfo"),
+		PayDate:       now.Format("20060102150405"),
+	}
+	responseCode := "00"
+	redirect := true
+	switch outcome {
+	case "", "success":
+	case "lost_ipn":
+		redirect = false
+	case "pending":
+		txn.Status = "01"
+		redirect = false
+	case "failure":
+		txn.Status = "02"
+		responseCode = "24"
+	default:
+		http.Error(w, "stub_outcome phải là success, lost_ipn, pending hoặc failure", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.transactions[txn.TxnRef] = txn
+	s.mu.Unlock()
+	log.Printf("PAY %s: %d VNĐ, outcome=%s, TransactionNo=%s", txn.TxnRef, txn.Amount, outcome, txn.TransactionNo)
+
+	if !redirect {
+		fmt.Fprintf(w, "Đã ghi nhận giao dịch %s (trạng thái %s), không gửi IPN về server.\n", txn.TxnRef, txn.Status)
+		return
+	}
+
+	ret := url.Values{}
+	ret.Set("vnp_Amount", params.Get("vnp_Amount"))
+	ret.Set("vnp_BankCode", txn.BankCode)
+	ret.Set("vnp_OrderInfo", txn.OrderInfo)
+	ret.Set("vnp_PayDate", txn.PayDate)
+	ret.Set("vnp_ResponseCode", responseCode)
+	ret.Set("vnp_TmnCode", params.Get("vnp_TmnCode"))
+	ret.Set("vnp_TransactionNo", txn.TransactionNo)
+	ret.Set("vnp_TransactionStatus", txn.Status)
+	ret.Set("vnp_TxnRef", txn.TxnRef)
+	ret.Set("vnp_SecureHash", s.signQuery(ret))
+
+	// IPN server-to-server trước, trình duyệt về trang kết quả sau
+	if ipnRes, err := http.Get(s.ipnUrl + "?" + ret.Encode()); err != nil {
+		log.Printf("IPN %s lỗi: %v", txn.TxnRef, err)
+	} else {
+		var rsp map[string]string
+		json.NewDecoder(ipnRes.Body).Decode(&rsp)
+		ipnRes.Body.Close()
+		log.Printf("IPN %s -> RspCode %s (%s)", txn.TxnRef, rsp["RspCode"], rsp["Message"])
+	}
+
+	http.Redirect(w, r, params.Get("vnp_ReturnUrl")+"?"+ret.Encode(), http.StatusFound)
+}
+
+// merchant_webapi: querydr và refund
+func (s *stubServer) handleMerchantAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	get := func(k string) string {
+		switch v := req[k].(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatInt(int64(v), 10)
+		default:
+			return ""
+		}
+	}
+
+	switch get("vnp_Command") {
+	case "querydr":
+		s.handleQueryDR(w, get)
+	case "refund":
+		s.handleRefund(w, get)
+	default:
+		writeJSON(w, map[string]string{"vnp_ResponseCode": "99", "vnp_Message": "Unsupported command"})
+	}
+}
+
+func (s *stubServer) handleQueryDR(w http.ResponseWriter, get func(string) string) {
+	hashData := strings.Join([]string{
+		get("vnp_RequestId"), get("vnp_Version"), get("vnp_Command"), get("vnp_TmnCode"), get("vnp_TxnRef"),
+		get("vnp_TransactionDate"), get("vnp_CreateDate"), get("vnp_IpAddr"), get("vnp_OrderInfo"),
+	}, "|")
+	if hmacSha512(s.hashSecret, hashData) != get("vnp_SecureHash") {
+		writeJSON(w, map[string]string{"vnp_ResponseCode": "97", "vnp_Message": "Invalid Checksum"})
+		return
+	}
+
+	res := map[string]string{
+		"vnp_ResponseId": strconv.FormatInt(time.Now().UnixNano(), 10),
+		"vnp_Command":    "querydr",
+		"vnp_TmnCode":    get("vnp_TmnCode"),
+		"vnp_TxnRef":     get("vnp_TxnRef"),
+	}
+
+	s.mu.Lock()
+	txn, ok := s.transactions[get("vnp_TxnRef")]
+	if ok {
+		res["vnp_ResponseCode"] = "00"
+		res["vnp_Message"] = "QueryDR Success"
+		res["vnp_Amount"] = strconv.FormatInt(txn.Amount*100, 10)
+		res["vnp_BankCode"] = txn.BankCode
+		res["vnp_PayDate"] = txn.PayDate
+		res["vnp_TransactionNo"] = txn.TransactionNo
+		res["vnp_TransactionType"] = "01"
+		res["vnp_TransactionStatus"] = txn.Status
+		res["vnp_OrderInfo"] = txn.OrderInfo
+	} else {
+		res["vnp_ResponseCode"] = "91"
+		res["vnp_Message"] = "Transaction not found"
+	}
+	s.mu.Unlock()
+
+	res["vnp_SecureHash"] = hmacSha512(s.hashSecret, strings.Join([]string{
+		res["vnp_ResponseId"], res["vnp_Command"], res["vnp_ResponseCode"], res["vnp_Message"], res["vnp_TmnCode"],
+		res["vnp_TxnRef"], res["vnp_Amount"], res["vnp_BankCode"], res["vnp_PayDate"], res["vnp_TransactionNo"],
+		res["vnp_TransactionType"], res["vnp_TransactionStatus"], res["vnp_OrderInfo"], res["vnp_PromotionCode"],
+		res["vnp_PromotionAmount"],
+	}, "|"))
+	log.Printf("QUERYDR %s -> %s/%s", get("vnp_TxnRef"), res["vnp_ResponseCode"], res["vnp_TransactionStatus"])
+	writeJSON(w, res)
+}
+
+func (s *stubServer) handleRefund(w http.ResponseWriter, get func(string) string) {
+	hashData := strings.Join([]string{
+		get("vnp_RequestId"), get("vnp_Version"), get("vnp_Command"), get("vnp_TmnCode"), get("vnp_TransactionType"),
+		get("vnp_TxnRef"), get("vnp_Amount"), get("vnp_TransactionNo"), get("vnp_TransactionDate"), get("vnp_CreateBy"),
+		get("vnp_CreateDate"), get("vnp_IpAddr"), get("vnp_OrderInfo"),
+	}, "|")
+	if hmacSha512(s.hashSecret, hashData) != get("vnp_SecureHash") {
+		writeJSON(w, map[string]string{"vnp_ResponseCode": "97", "vnp_Message": "Invalid Checksum"})
+		return
+	}
+
+	amount, _ := strconv.ParseInt(get("vnp_Amount"), 10, 64)
+	res := map[string]string{
+		"vnp_ResponseId":      strconv.FormatInt(time.Now().UnixNano(), 10),
+		"vnp_Command":         "refund",
+		"vnp_TmnCode":         get("vnp_TmnCode"),
+		"vnp_TxnRef":          get("vnp_TxnRef"),
+		"vnp_Amount":          get("vnp_Amount"),
+		"vnp_TransactionType": get("vnp_TransactionType"),
+		"vnp_OrderInfo":       get("vnp_OrderInfo"),
+	}
+
+	s.mu.Lock()
+	txn, ok := s.transactions[get("vnp_TxnRef")]
+	switch {
+	case !ok:
+		res["vnp_ResponseCode"] = "91"
+		res["vnp_Message"] = "Transaction not found"
+	case txn.Status != "00":
+		res["vnp_ResponseCode"] = "95"
+		res["vnp_Message"] = "Transaction not successful"
+	case txn.RefundedTotal+amount/100 > txn.Amount:
+		res["vnp_ResponseCode"] = "02"
+		res["vnp_Message"] = "Refund amount exceeds"
+	default:
+		txn.RefundedTotal += amount / 100
+		res["vnp_ResponseCode"] = "00"
+		res["vnp_Message"] = "Refund Success"
+		res["vnp_BankCode"] = txn.BankCode
+		res["vnp_PayDate"] = time.Now().In(vnpayLoc).Format("20060102150405")
+		res["vnp_TransactionNo"] = strconv.FormatInt(time.Now().UnixNano()%100000000, 10)
+		res["vnp_TransactionStatus"] = "05"
+	}
+	s.mu.Unlock()
+
+	res["vnp_SecureHash"] = hmacSha512(s.hashSecret, strings.Join([]string{
+		res["vnp_ResponseId"], res["vnp_Command"], res["vnp_ResponseCode"], res["vnp_Message"], res["vnp_TmnCode"],
+		res["vnp_TxnRef"], res["vnp_Amount"], res["vnp_BankCode"], res["vnp_PayDate"], res["vnp_TransactionNo"],
+		res["vnp_TransactionType"], res["vnp_TransactionStatus"], res["vnp_OrderInfo"],
+	}, "|"))
+	log.Printf("REFUND %s %s VNĐ -> %s", get("vnp_TxnRef"), strconv.FormatInt(amount/100, 10), res["vnp_ResponseCode"])
+	writeJSON(w, res)
+}
+
+// Tạo sẵn / xem giao dịch trong bộ nhớ của stub
+func (s *stubServer) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, s.transactions)
+	case http.MethodPost:
+		var txn transaction
+		if err := json.NewDecoder(r.Body).Decode(&txn); err != nil || txn.TxnRef == "" {
+			http.Error(w, "cần txn_ref, amount, status", http.StatusBadRequest)
+			return
+		}
+		if txn.Status == "" {
+			txn.Status = "00"
+		}
+		if txn.TransactionNo == "" {
+			txn.TransactionNo = strconv.FormatInt(time.Now().UnixNano()%100000000, 10)
+		}
+		if txn.PayDate == "" {
+			txn.PayDate = time.Now().In(vnpayLoc).Format("20060102150405")
+		}
+		if txn.BankCode == "" {
+			txn.BankCode = "NCB"
+		}
+		s.transactions[txn.TxnRef] = &txn
+		writeJSON(w, txn)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// Ký tham số giống VNPAY: sắp xếp key, bỏ giá trị rỗng và vnp_SecureHash*, giá trị được URL encode
+func (s *stubServer) signQuery(params url.Values) string {
+	var keys []string
+	for k := range params {
+		if k == "vnp_SecureHash" || k == "vnp_SecureHashType" || params.Get(k) == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var data strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			data.WriteString("&")
+		}
+		data.WriteString(k)
+		data.WriteString("=")
+		data.WriteString(url.QueryEscape(params.Get(k)))
+	}
+	return hmacSha512(s.hashSecret, data.String())
+}
+
+func hmacSha512(secret, data string) string {
+	h := hmac.New(sha512.New, []byte(secret))
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
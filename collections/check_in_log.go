@@ -0,0 +1,123 @@
+package collections
+
+import (
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Lịch sử lượt quét vé được đồng bộ từ thiết bị check-in offline
+type CheckInLog struct {
+	ID        primitive.ObjectID    `bson:"_id" json:"id"`
+	EventID   primitive.ObjectID    `bson:"event_id" json:"event_id"`
+	TicketID  primitive.ObjectID    `bson:"ticket_id,omitempty" json:"ticket_id,omitempty"`
+	DeviceID  string                `bson:"device_id" json:"device_id"`
+	Gate      string                `bson:"gate,omitempty" json:"gate,omitempty"`
+	ScannedAt time.Time             `bson:"scanned_at" json:"scanned_at"`
+	Result    consts.CheckInResult  `bson:"result" json:"result"` // accepted / duplicate / rejected
+	Reason    string                `bson:"reason,omitempty" json:"reason,omitempty"`
+	Winner    *CheckInLogWinnerScan `bson:"winner,omitempty" json:"winner,omitempty"`
+
+	SyncedAt time.Time          `bson:"synced_at" json:"synced_at"`
+	SyncedBy primitive.ObjectID `bson:"synced_by" json:"synced_by"`
+}
+
+// Lượt quét được giữ lại khi xảy ra trùng
+type CheckInLogWinnerScan struct {
+	CheckedInAt string `bson:"checked_in_at" json:"checked_in_at"`
+	DeviceID    string `bson:"device_id,omitempty" json:"device_id,omitempty"`
+	Gate        string `bson:"gate,omitempty" json:"gate,omitempty"`
+}
+
+type CheckInLogs []CheckInLog
+
+func (u *CheckInLog) getCollectionName() string {
+	return "check_in_logs"
+}
+
+func (u *CheckInLog) CreateMany(ctx context.Context, logs []CheckInLog, opts ...*options.InsertManyOptions) error {
+	var (
+		db  = database.GetDB()
+		err error
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if len(logs) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(logs))
+	for _, log := range logs {
+		if log.ID.IsZero() {
+			log.ID = primitive.NewObjectID()
+		}
+		documents = append(documents, log)
+	}
+
+	_, err = db.Collection(u.getCollectionName()).InsertMany(ctx, documents, opts...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *CheckInLog) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (CheckInLogs, error) {
+	var (
+		db   = database.GetDB()
+		logs CheckInLogs
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &logs); err != nil {
+		return nil, err
+	}
+
+	if logs == nil {
+		logs = []CheckInLog{}
+	}
+
+	return logs, nil
+}
+
+func (u *CheckInLog) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	count, err := db.Collection(u.getCollectionName()).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
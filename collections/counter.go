@@ -0,0 +1,49 @@
+package collections
+
+import (
+	"EventHunting/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Bộ đếm tăng dần theo key (vd: số hóa đơn theo năm)
+type Counter struct {
+	ID        string    `bson:"_id" json:"id"`
+	Seq       int64     `bson:"seq" json:"seq"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+func (u *Counter) getCollectionName() string {
+	return "counters"
+}
+
+// Tăng bộ đếm một cách nguyên tử và trả về giá trị mới, tạo bộ đếm nếu chưa có.
+// Gọi trong transaction thì số chỉ được giữ khi transaction commit, không để lại khoảng trống.
+func (u *Counter) Next(ctx context.Context, key string) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := db.Collection(u.getCollectionName()).FindOneAndUpdate(ctx,
+		bson.M{"_id": key},
+		bson.M{
+			"$inc": bson.M{"seq": 1},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+		opts,
+	).Decode(u)
+	if err != nil {
+		return 0, err
+	}
+	return u.Seq, nil
+}
@@ -44,6 +44,11 @@ type Event struct {
 	IsEdit           bool                 `bson:"is_edit" json:"is_edit"`
 	ProvinceId       primitive.ObjectID   `bson:"province_id" json:"province_id"`
 	MaxTicketPerUser int                  `bson:"max_ticket_per_user" json:"max_ticket_per_user"`
+	RefundPolicy     *EventRefundPolicy   `bson:"refund_policy,omitempty" json:"refund_policy,omitempty"`
+	WaitingRoom      *EventWaitingRoom    `bson:"waiting_room,omitempty" json:"waiting_room,omitempty"`
+	Lottery          *EventLottery        `bson:"lottery,omitempty" json:"lottery,omitempty"`
+
+	RegistrationForm *EventRegistrationForm `bson:"registration_form,omitempty" json:"registration_form,omitempty"`
 
 	Status       string      `bson:"-" json:"status,omitempty"`
 	Account      Account     `bson:"-" json:"organizer_info,omitempty"`
@@ -62,6 +67,77 @@ type Event struct {
 	DeletedBy primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
 }
 
+// Chính sách hoàn tiền người mua tự yêu cầu
+type EventRefundPolicy struct {
+	Enabled       bool `bson:"enabled" json:"enabled"`
+	DeadlineHours int  `bson:"deadline_hours" json:"deadline_hours"` // Số giờ trước khi sự kiện bắt đầu
+	RefundPercent int  `bson:"refund_percent" json:"refund_percent"` // Phần trăm giá vé được hoàn (1-100)
+}
+
+// Phòng chờ khi mở bán: người mua xếp hàng và được cấp vé vào cửa để gọi API đăng ký theo tốc độ cấu hình
+type EventWaitingRoom struct {
+	Enabled        bool `bson:"enabled" json:"enabled"`
+	AdmitPerMinute int  `bson:"admit_per_minute" json:"admit_per_minute"` // Số người được vào mỗi phút
+}
+
+func (e *Event) WaitingRoomEnabled() bool {
+	return e.WaitingRoom != nil && e.WaitingRoom.Enabled
+}
+
+// Phân bổ vé miễn phí bằng bốc thăm thay cho đăng ký trực tiếp
+type EventLottery struct {
+	Enabled      bool                 `bson:"enabled" json:"enabled"`
+	ApplyStartAt time.Time            `bson:"apply_start_at" json:"apply_start_at"`
+	ApplyEndAt   time.Time            `bson:"apply_end_at" json:"apply_end_at"` // Hết thời gian đăng ký thì job bốc thăm
+	Status       consts.LotteryStatus `bson:"status,omitempty" json:"status,omitempty"`
+	DrawnAt      *time.Time           `bson:"drawn_at,omitempty" json:"drawn_at,omitempty"`
+}
+
+func (e *Event) LotteryEnabled() bool {
+	return e.Lottery != nil && e.Lottery.Enabled
+}
+
+// Form thông tin người tham dự, người mua điền cho từng vé khi đăng ký
+type EventRegistrationForm struct {
+	CollectAttendees bool             `bson:"collect_attendees" json:"collect_attendees"` // Bắt buộc tên, email người tham dự kể cả khi không có câu hỏi
+	Fields           []EventFormField `bson:"fields" json:"fields"`
+}
+
+type EventFormField struct {
+	ID            string               `bson:"id" json:"id"` // Khóa câu trả lời, không đổi khi sửa nhãn
+	Label         string               `bson:"label" json:"label"`
+	Type          consts.FormFieldType `bson:"type" json:"type"` // text / choice / checkbox
+	Required      bool                 `bson:"required" json:"required"`
+	Options       []string             `bson:"options,omitempty" json:"options,omitempty"`
+	TicketTypeIDs []primitive.ObjectID `bson:"ticket_type_ids,omitempty" json:"ticket_type_ids,omitempty"` // Rỗng = áp dụng mọi loại vé
+}
+
+// Câu hỏi áp dụng cho loại vé
+func (f *EventRegistrationForm) FieldsFor(ticketTypeID primitive.ObjectID) []EventFormField {
+	if f == nil {
+		return nil
+	}
+	var fields []EventFormField
+	for _, field := range f.Fields {
+		if len(field.TicketTypeIDs) == 0 {
+			fields = append(fields, field)
+			continue
+		}
+		for _, id := range field.TicketTypeIDs {
+			if id == ticketTypeID {
+				fields = append(fields, field)
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// Vé của loại vé này phải kèm thông tin người tham dự
+func (f *EventRegistrationForm) RequiresAttendees(ticketTypeID primitive.ObjectID) bool {
+	return f != nil && (f.CollectAttendees || len(f.FieldsFor(ticketTypeID)) > 0)
+}
+
 type Events []Event
 
 func (u *Event) getCollectionName() string {
@@ -587,6 +663,7 @@ func (u *Event) ParseEntry() bson.M {
 			"map_url": u.EventLocation.MapURL,
 		},
 		"max_ticket_per_user": u.MaxTicketPerUser,
+		"refund_policy":       u.RefundPolicy,
 		"province":            u.Province,
 		"topic_ids":           u.TopicIDs,
 		"comment_count":       u.CommentCount,
@@ -0,0 +1,27 @@
+package collections
+
+import (
+	"log"
+)
+
+// Tạo các index bắt buộc khi khởi động. Lỗi chỉ log (vd: dữ liệu cũ đang trùng), server vẫn chạy.
+func EnsureIndexes() {
+	if err := (&Invoice{}).EnsureIndexes(nil); err != nil {
+		log.Printf("CRITICAL: Không tạo được unique index invoice_number (kiểm tra số hóa đơn trùng): %v", err)
+	}
+	if err := (&PromoCode{}).EnsureIndexes(nil); err != nil {
+		log.Printf("CRITICAL: Không tạo được unique index mã giảm giá theo sự kiện: %v", err)
+	}
+	if err := (&PromoCodeUsage{}).EnsureIndexes(nil); err != nil {
+		log.Printf("CRITICAL: Không tạo được unique index lượt dùng mã giảm giá: %v", err)
+	}
+	if err := (&WaitlistEntry{}).EnsureIndexes(nil); err != nil {
+		log.Printf("CRITICAL: Không tạo được index danh sách chờ: %v", err)
+	}
+	if err := (&LotteryEntry{}).EnsureIndexes(nil); err != nil {
+		log.Printf("CRITICAL: Không tạo được unique index lượt bốc thăm: %v", err)
+	}
+	if err := (&LotteryDraw{}).EnsureIndexes(nil); err != nil {
+		log.Printf("CRITICAL: Không tạo được unique index lần bốc thăm theo loại vé: %v", err)
+	}
+}
@@ -1,11 +1,15 @@
 package collections
 
 import (
+	"EventHunting/consts"
 	"EventHunting/database"
 	"context"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type InvoicePaymentDetails struct {
@@ -26,6 +30,9 @@ type InvoiceLstItem struct {
 	Quantity    int                `bson:"quantity" json:"quantity"`
 	UnitPrice   int                `bson:"unit_price" json:"unit_price"`
 	TotalAmount int                `bson:"total_amount" json:"total_amount"`
+	VATRate     *int               `bson:"vat_rate,omitempty" json:"vat_rate,omitempty"` // Thuế suất GTGT (%), giá đã gồm thuế. Hóa đơn cũ không có thì lấy einvoice.vat_rate
+	// Phần giảm giá của mã khuyến mãi phân bổ vào dòng này (đã thể hiện ở dòng giảm giá riêng)
+	DiscountAmount int `bson:"discount_amount,omitempty" json:"discount_amount,omitempty"`
 }
 
 type InvoiceEventDetails struct {
@@ -35,15 +42,36 @@ type InvoiceEventDetails struct {
 	EndDate   time.Time          `bson:"end_date" json:"end_date"`
 }
 
+// Trạng thái phát hành hóa đơn điện tử qua nhà cung cấp
+type InvoiceEInvoice struct {
+	Provider          string                `bson:"provider" json:"provider"`
+	Status            consts.EInvoiceStatus `bson:"status" json:"status"`
+	ProviderInvoiceID string                `bson:"provider_invoice_id,omitempty" json:"provider_invoice_id,omitempty"`
+	LookupCode        string                `bson:"lookup_code,omitempty" json:"lookup_code,omitempty"` // Mã tra cứu cho người mua
+	Error             string                `bson:"error,omitempty" json:"error,omitempty"`
+	Attempts          int                   `bson:"attempts" json:"attempts"`
+	SubmittedAt       *time.Time            `bson:"submitted_at,omitempty" json:"submitted_at,omitempty"`
+	IssuedAt          *time.Time            `bson:"issued_at,omitempty" json:"issued_at,omitempty"`
+	UpdatedAt         time.Time             `bson:"updated_at" json:"updated_at"`
+}
+
 type Invoice struct {
-	ID              primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
-	InvoiceNumber   string                 `bson:"invoice_number" json:"invoice_number"`
-	RegistrationID  primitive.ObjectID     `bson:"registration_id" json:"registration_id"`
-	Status          string                 `bson:"status" json:"status"` // "Completed", "Refunded"
-	PaymentDetails  InvoicePaymentDetails  `bson:"payment_details" json:"payment_details"`
-	CustomerDetails InvoiceCustomerDetails `bson:"customer_details" json:"customer_details"`
-	LineItems       []InvoiceLstItem       `bson:"line_items" json:"line_items"`
-	EventDetails    InvoiceEventDetails    `bson:"event_details" json:"event_details"`
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	InvoiceNumber  string             `bson:"invoice_number" json:"invoice_number"`
+	InvoiceSeq     int64              `bson:"invoice_seq,omitempty" json:"invoice_seq,omitempty"`   // Số thứ tự trong kỳ đánh số của số hóa đơn
+	EInvoiceSeq    int64              `bson:"einvoice_seq,omitempty" json:"einvoice_seq,omitempty"` // SHDon của hóa đơn điện tử, đánh số lại theo năm
+	RegistrationID primitive.ObjectID `bson:"registration_id" json:"registration_id"`
+	Status         string             `bson:"status" json:"status"`                 // "Completed", "PartiallyRefunded", "Refunded"
+	Type           string             `bson:"type,omitempty" json:"type,omitempty"` // invoice / credit_note (rỗng = invoice)
+	// Hóa đơn điều chỉnh giảm (credit note) trỏ về hóa đơn gốc
+	OriginalInvoiceID primitive.ObjectID     `bson:"original_invoice_id,omitempty" json:"original_invoice_id,omitempty"`
+	RefundID          primitive.ObjectID     `bson:"refund_id,omitempty" json:"refund_id,omitempty"`
+	TotalAmount       int                    `bson:"total_amount" json:"total_amount"`
+	PaymentDetails    InvoicePaymentDetails  `bson:"payment_details" json:"payment_details"`
+	CustomerDetails   InvoiceCustomerDetails `bson:"customer_details" json:"customer_details"`
+	LineItems         []InvoiceLstItem       `bson:"line_items" json:"line_items"`
+	EventDetails      InvoiceEventDetails    `bson:"event_details" json:"event_details"`
+	EInvoice          *InvoiceEInvoice       `bson:"einvoice,omitempty" json:"einvoice,omitempty"`
 
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
@@ -79,3 +107,100 @@ func (u *Invoice) Create(ctx context.Context) error {
 	}
 	return nil
 }
+
+func (u *Invoice) First(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	err := db.Collection(u.getCollectionName()).FindOne(ctx, filter, opts...).Decode(u)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *Invoice) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (Invoices, error) {
+	var (
+		db       = database.GetDB()
+		invoices Invoices
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &invoices); err != nil {
+		return nil, err
+	}
+
+	if invoices == nil {
+		invoices = []Invoice{}
+	}
+
+	return invoices, nil
+}
+
+func (u *Invoice) Update(ctx context.Context, filter bson.M, updateDoc bson.M, opts ...*options.UpdateOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	res, err := db.Collection(u.getCollectionName()).UpdateOne(ctx, filter, updateDoc, opts...)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Số hóa đơn là duy nhất, chặn trùng số kể cả khi bộ đếm bị cấu hình sai
+func (u *Invoice) EnsureIndexes(ctx context.Context) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+	}
+
+	_, err := db.Collection(u.getCollectionName()).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "invoice_number", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("uniq_invoice_number"),
+	})
+	return err
+}
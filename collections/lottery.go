@@ -0,0 +1,297 @@
+package collections
+
+import (
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Một lượt đăng ký bốc thăm của tài khoản cho một loại vé
+type LotteryEntry struct {
+	ID           primitive.ObjectID        `bson:"_id" json:"id"`
+	EventID      primitive.ObjectID        `bson:"event_id" json:"event_id"`
+	TicketTypeID primitive.ObjectID        `bson:"ticket_type_id" json:"ticket_type_id"`
+	AccountID    primitive.ObjectID        `bson:"account_id" json:"account_id"`
+	Quantity     int                       `bson:"quantity" json:"quantity"`
+	Status       consts.LotteryEntryStatus `bson:"status" json:"status"` // pending / won / lost / cancelled
+
+	// Kết quả bốc thăm: thứ tự sau khi xáo trộn (bắt đầu từ 1) và đơn đăng ký nếu trúng
+	DrawID         primitive.ObjectID `bson:"draw_id,omitempty" json:"draw_id,omitempty"`
+	DrawRank       int                `bson:"draw_rank,omitempty" json:"draw_rank,omitempty"`
+	RegistrationID primitive.ObjectID `bson:"registration_id,omitempty" json:"registration_id,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type LotteryEntries []LotteryEntry
+
+func (u *LotteryEntry) getCollectionName() string {
+	return "lottery_entries"
+}
+
+func (u *LotteryEntry) Create(ctx context.Context) error {
+	var (
+		db  = database.GetDB()
+		err error
+	)
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	_, err = db.Collection(u.getCollectionName()).InsertOne(ctx, u)
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *LotteryEntry) First(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	return db.Collection(u.getCollectionName()).FindOne(ctx, filter, opts...).Decode(u)
+}
+
+func (u *LotteryEntry) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (LotteryEntries, error) {
+	var (
+		db      = database.GetDB()
+		entries LotteryEntries
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	if entries == nil {
+		entries = LotteryEntries{}
+	}
+
+	return entries, nil
+}
+
+func (u *LotteryEntry) Update(ctx context.Context, filter bson.M, updateDoc bson.M, opts ...*options.UpdateOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	res, err := db.Collection(u.getCollectionName()).UpdateOne(ctx, filter, updateDoc, opts...)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Mỗi tài khoản chỉ có một lượt cho mỗi loại vé
+func (u *LotteryEntry) EnsureIndexes(ctx context.Context) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+	}
+
+	_, err := db.Collection(u.getCollectionName()).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "ticket_type_id", Value: 1}, {Key: "account_id", Value: 1}},
+			Options: options.Index().SetName("ticket_type_account_unique").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "event_id", Value: 1}, {Key: "status", Value: 1}},
+			Options: options.Index().SetName("event_status"),
+		},
+	})
+	return err
+}
+
+// Một lần bốc thăm của loại vé. Seed và mã băm danh sách tham gia được ghi trước khi xáo trộn
+// để bất kỳ ai cũng có thể chạy lại thuật toán và so kết quả.
+type LotteryDraw struct {
+	ID           primitive.ObjectID       `bson:"_id" json:"id"`
+	EventID      primitive.ObjectID       `bson:"event_id" json:"event_id"`
+	TicketTypeID primitive.ObjectID       `bson:"ticket_type_id" json:"ticket_type_id"`
+	Algorithm    string                   `bson:"algorithm" json:"algorithm"`
+	Seed         string                   `bson:"seed" json:"seed"`                 // 32 bytes hex từ crypto/rand
+	EntriesHash  string                   `bson:"entries_hash" json:"entries_hash"` // SHA-256 danh sách tham gia theo thứ tự _id
+	EntryCount   int                      `bson:"entry_count" json:"entry_count"`
+	Capacity     *int                     `bson:"capacity,omitempty" json:"capacity"` // Số vé được chia, nil = không giới hạn
+	WinnerCount  int                      `bson:"winner_count" json:"winner_count"`
+	WonQuantity  int                      `bson:"won_quantity" json:"won_quantity"`
+	Status       consts.LotteryDrawStatus `bson:"status" json:"status"`
+	CreatedAt    time.Time                `bson:"created_at" json:"created_at"`
+	CompletedAt  *time.Time               `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+type LotteryDraws []LotteryDraw
+
+func (u *LotteryDraw) getCollectionName() string {
+	return "lottery_draws"
+}
+
+func (u *LotteryDraw) Create(ctx context.Context) error {
+	var (
+		db  = database.GetDB()
+		err error
+	)
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	_, err = db.Collection(u.getCollectionName()).InsertOne(ctx, u)
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *LotteryDraw) First(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	return db.Collection(u.getCollectionName()).FindOne(ctx, filter, opts...).Decode(u)
+}
+
+func (u *LotteryDraw) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (LotteryDraws, error) {
+	var (
+		db    = database.GetDB()
+		draws LotteryDraws
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &draws); err != nil {
+		return nil, err
+	}
+
+	if draws == nil {
+		draws = LotteryDraws{}
+	}
+
+	return draws, nil
+}
+
+func (u *LotteryDraw) Update(ctx context.Context, filter bson.M, updateDoc bson.M, opts ...*options.UpdateOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	res, err := db.Collection(u.getCollectionName()).UpdateOne(ctx, filter, updateDoc, opts...)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Mỗi loại vé chỉ được bốc thăm một lần, hai job chạy song song không thể chốt hai seed khác nhau
+func (u *LotteryDraw) EnsureIndexes(ctx context.Context) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+	}
+
+	_, err := db.Collection(u.getCollectionName()).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "ticket_type_id", Value: 1}},
+		Options: options.Index().SetName("ticket_type_unique").SetUnique(true),
+	})
+	return err
+}
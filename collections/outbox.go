@@ -0,0 +1,180 @@
+package collections
+
+import (
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Message chờ đẩy sang queue, được ghi cùng transaction với thay đổi nghiệp vụ
+type OutboxMessage struct {
+	ID          primitive.ObjectID  `bson:"_id" json:"id"`
+	Topic       string              `bson:"topic" json:"topic"`
+	Data        bson.M              `bson:"data" json:"data"`
+	Status      consts.OutboxStatus `bson:"status" json:"status"`
+	Attempts    int                 `bson:"attempts" json:"attempts"`
+	LastError   string              `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	AvailableAt time.Time           `bson:"available_at" json:"available_at"` // Relay chỉ lấy message đã tới hạn (dùng cho lease và backoff)
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	PublishedAt *time.Time          `bson:"published_at,omitempty" json:"published_at,omitempty"`
+}
+
+type OutboxMessages []OutboxMessage
+
+func (u *OutboxMessage) getCollectionName() string {
+	return "outbox_messages"
+}
+
+func (u *OutboxMessage) Create(ctx context.Context) error {
+	var (
+		db  = database.GetDB()
+		err error
+	)
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	_, err = db.Collection(u.getCollectionName()).InsertOne(ctx, u)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *OutboxMessage) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (OutboxMessages, error) {
+	var (
+		db       = database.GetDB()
+		messages OutboxMessages
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+
+	if messages == nil {
+		messages = []OutboxMessage{}
+	}
+
+	return messages, nil
+}
+
+func (u *OutboxMessage) Update(ctx context.Context, filter bson.M, update bson.M) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	result, err := db.Collection(u.getCollectionName()).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Nhận một message đã tới hạn và giữ nó trong thời gian lease, tránh nhiều relay cùng đẩy một message.
+// Nếu relay chết giữa chừng, message tự được nhận lại khi hết lease.
+func (u *OutboxMessage) Claim(ctx context.Context, now time.Time, lease time.Duration) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "available_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	return db.Collection(u.getCollectionName()).FindOneAndUpdate(ctx,
+		bson.M{
+			"status":       consts.OutboxStatusPending,
+			"available_at": bson.M{"$lte": now},
+		},
+		bson.M{
+			"$set": bson.M{"available_at": now.Add(lease)},
+			"$inc": bson.M{"attempts": 1},
+		},
+		opts,
+	).Decode(u)
+}
+
+func (u *OutboxMessage) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	count, err := db.Collection(u.getCollectionName()).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Chạy aggregation trên outbox, dùng cho thống kê độ trễ relay
+func (u *OutboxMessage) Aggregate(ctx context.Context, pipeline mongo.Pipeline) ([]bson.M, error) {
+	var (
+		db      = database.GetDB()
+		results []bson.M
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,157 @@
+package collections
+
+import (
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Sai lệch giữa trạng thái đơn đăng ký và giao dịch ở cổng thanh toán, chờ admin xử lý
+type PaymentDiscrepancy struct {
+	ID             primitive.ObjectID              `bson:"_id" json:"id"`
+	RegistrationID primitive.ObjectID              `bson:"registration_id" json:"registration_id"`
+	EventID        primitive.ObjectID              `bson:"event_id" json:"event_id"`
+	OwnerID        primitive.ObjectID              `bson:"owner_id" json:"owner_id"`
+	Provider       string                          `bson:"provider" json:"provider"`
+	Type           consts.PaymentDiscrepancyType   `bson:"type" json:"type"`     // paid_cancelled, amount_mismatch, refund_pending
+	Status         consts.PaymentDiscrepancyStatus `bson:"status" json:"status"` // open, resolved
+	TransactionNo  string                          `bson:"transaction_no,omitempty" json:"transaction_no,omitempty"`
+	PaidAmount     int64                           `bson:"paid_amount" json:"paid_amount"`         // Số tiền cổng báo đã thu
+	ExpectedAmount int                             `bson:"expected_amount" json:"expected_amount"` // Tổng tiền của đơn
+	RegisStatus    string                          `bson:"regis_status" json:"regis_status"`       // Trạng thái đơn lúc phát hiện
+	Note           string                          `bson:"note,omitempty" json:"note,omitempty"`
+	ResolvedBy     primitive.ObjectID              `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+	ResolvedAt     *time.Time                      `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type PaymentDiscrepancies []PaymentDiscrepancy
+
+func (u *PaymentDiscrepancy) getCollectionName() string {
+	return "payment_discrepancies"
+}
+
+func (u *PaymentDiscrepancy) Create(ctx context.Context) error {
+	var (
+		db  = database.GetDB()
+		err error
+	)
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	_, err = db.Collection(u.getCollectionName()).InsertOne(ctx, u)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *PaymentDiscrepancy) First(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	err := db.Collection(u.getCollectionName()).FindOne(ctx, filter, opts...).Decode(u)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *PaymentDiscrepancy) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (PaymentDiscrepancies, error) {
+	var (
+		db            = database.GetDB()
+		discrepancies PaymentDiscrepancies
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &discrepancies); err != nil {
+		return nil, err
+	}
+
+	if discrepancies == nil {
+		discrepancies = []PaymentDiscrepancy{}
+	}
+
+	return discrepancies, nil
+}
+
+func (u *PaymentDiscrepancy) Update(ctx context.Context, filter bson.M, updateDoc bson.M, opts ...*options.UpdateOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	res, err := db.Collection(u.getCollectionName()).UpdateOne(ctx, filter, updateDoc, opts...)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (u *PaymentDiscrepancy) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	count, err := db.Collection(u.getCollectionName()).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,110 @@
+package collections
+
+import (
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Bản ghi thô của mỗi callback/IPN từ cổng thanh toán, phục vụ đối soát và khiếu nại
+type PaymentEvent struct {
+	ID             primitive.ObjectID         `bson:"_id" json:"id"`
+	Provider       string                     `bson:"provider" json:"provider"`
+	Endpoint       string                     `bson:"endpoint" json:"endpoint"` // ipn, return, callback
+	HTTPMethod     string                     `bson:"http_method" json:"http_method"`
+	ClientIP       string                     `bson:"client_ip" json:"client_ip"`
+	RawParams      map[string][]string        `bson:"raw_params" json:"raw_params"`
+	SignatureValid bool                       `bson:"signature_valid" json:"signature_valid"`
+	OrderID        string                     `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	RegistrationID primitive.ObjectID         `bson:"registration_id,omitempty" json:"registration_id,omitempty"` // Đơn khớp được trong DB
+	TransactionNo  string                     `bson:"transaction_no,omitempty" json:"transaction_no,omitempty"`
+	Amount         int64                      `bson:"amount" json:"amount"`
+	ResponseCode   string                     `bson:"response_code,omitempty" json:"response_code,omitempty"`
+	Outcome        consts.PaymentEventOutcome `bson:"outcome" json:"outcome"`
+	Error          string                     `bson:"error,omitempty" json:"error,omitempty"`
+	LatencyMs      int64                      `bson:"latency_ms" json:"latency_ms"`
+	ReceivedAt     time.Time                  `bson:"received_at" json:"received_at"`
+}
+
+type PaymentEvents []PaymentEvent
+
+func (u *PaymentEvent) getCollectionName() string {
+	return "payment_events"
+}
+
+func (u *PaymentEvent) Create(ctx context.Context) error {
+	var (
+		db  = database.GetDB()
+		err error
+	)
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	_, err = db.Collection(u.getCollectionName()).InsertOne(ctx, u)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *PaymentEvent) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (PaymentEvents, error) {
+	var (
+		db     = database.GetDB()
+		events PaymentEvents
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	if events == nil {
+		events = []PaymentEvent{}
+	}
+
+	return events, nil
+}
+
+func (u *PaymentEvent) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	count, err := db.Collection(u.getCollectionName()).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
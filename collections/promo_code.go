@@ -0,0 +1,232 @@
+package collections
+
+import (
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Mã giảm giá do ban tổ chức tạo cho một sự kiện
+type PromoCode struct {
+	ID            primitive.ObjectID       `bson:"_id" json:"id"`
+	EventID       primitive.ObjectID       `bson:"event_id" json:"event_id"`
+	Code          string                   `bson:"code" json:"code"` // Luôn viết hoa
+	Description   string                   `bson:"description,omitempty" json:"description,omitempty"`
+	DiscountType  consts.PromoDiscountType `bson:"discount_type" json:"discount_type"`                   // percent / fixed
+	DiscountValue int                      `bson:"discount_value" json:"discount_value"`                 // % hoặc số tiền VNĐ
+	MaxDiscount   *int                     `bson:"max_discount,omitempty" json:"max_discount,omitempty"` // Mức giảm tối đa cho mã phần trăm
+	// Rỗng = áp dụng cho mọi loại vé của sự kiện
+	TicketTypeIDs []primitive.ObjectID `bson:"ticket_type_ids,omitempty" json:"ticket_type_ids,omitempty"`
+
+	MaxUses        *int `bson:"max_uses,omitempty" json:"max_uses,omitempty"`                   // nil là không giới hạn
+	MaxUsesPerUser *int `bson:"max_uses_per_user,omitempty" json:"max_uses_per_user,omitempty"` // nil là không giới hạn
+	UsedCount      int  `bson:"used_count" json:"used_count"`
+
+	StartAt *time.Time             `bson:"start_at,omitempty" json:"start_at,omitempty"`
+	EndAt   *time.Time             `bson:"end_at,omitempty" json:"end_at,omitempty"`
+	Status  consts.PromoCodeStatus `bson:"status" json:"status"`
+
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	UpdatedBy primitive.ObjectID `bson:"updated_by" json:"updated_by"`
+}
+
+type PromoCodes []PromoCode
+
+func (u *PromoCode) getCollectionName() string {
+	return "promo_codes"
+}
+
+func (u *PromoCode) Create(ctx context.Context) error {
+	var (
+		db  = database.GetDB()
+		err error
+	)
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	_, err = db.Collection(u.getCollectionName()).InsertOne(ctx, u)
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *PromoCode) First(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	return db.Collection(u.getCollectionName()).FindOne(ctx, filter, opts...).Decode(u)
+}
+
+func (u *PromoCode) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (PromoCodes, error) {
+	var (
+		db         = database.GetDB()
+		promoCodes PromoCodes
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &promoCodes); err != nil {
+		return nil, err
+	}
+
+	if promoCodes == nil {
+		promoCodes = PromoCodes{}
+	}
+
+	return promoCodes, nil
+}
+
+func (u *PromoCode) Update(ctx context.Context, filter bson.M, updateDoc bson.M, opts ...*options.UpdateOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	res, err := db.Collection(u.getCollectionName()).UpdateOne(ctx, filter, updateDoc, opts...)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// Mỗi sự kiện không có hai mã trùng nhau
+func (u *PromoCode) EnsureIndexes(ctx context.Context) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+	}
+
+	_, err := db.Collection(u.getCollectionName()).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "event_id", Value: 1}, {Key: "code", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("uniq_event_code"),
+	})
+	return err
+}
+
+// Số lượt đã dùng mã giảm giá của từng tài khoản
+type PromoCodeUsage struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	PromoCodeID primitive.ObjectID `bson:"promo_code_id" json:"promo_code_id"`
+	AccountID   primitive.ObjectID `bson:"account_id" json:"account_id"`
+	Count       int                `bson:"count" json:"count"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func (u *PromoCodeUsage) getCollectionName() string {
+	return "promo_code_usages"
+}
+
+func (u *PromoCodeUsage) First(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	return db.Collection(u.getCollectionName()).FindOne(ctx, filter, opts...).Decode(u)
+}
+
+// Cộng (hoặc trừ khi delta âm) số lượt dùng của tài khoản, tạo bản ghi nếu chưa có. Trả về số lượt sau khi cập nhật.
+func (u *PromoCodeUsage) Increase(ctx context.Context, promoCodeID, accountID primitive.ObjectID, delta int) (int, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := db.Collection(u.getCollectionName()).FindOneAndUpdate(ctx,
+		bson.M{"promo_code_id": promoCodeID, "account_id": accountID},
+		bson.M{
+			"$inc": bson.M{"count": delta},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+		opts,
+	).Decode(u)
+	if err != nil {
+		return 0, err
+	}
+	return u.Count, nil
+}
+
+func (u *PromoCodeUsage) EnsureIndexes(ctx context.Context) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+	}
+
+	_, err := db.Collection(u.getCollectionName()).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "promo_code_id", Value: 1}, {Key: "account_id", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("uniq_promo_account"),
+	})
+	return err
+}
@@ -0,0 +1,165 @@
+package collections
+
+import (
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Yêu cầu hoàn tiền cho một phần hoặc toàn bộ vé của đơn đăng ký
+type Refund struct {
+	ID             primitive.ObjectID   `bson:"_id" json:"id"`
+	RegistrationID primitive.ObjectID   `bson:"registration_id" json:"registration_id"`
+	EventID        primitive.ObjectID   `bson:"event_id" json:"event_id"`
+	InvoiceID      primitive.ObjectID   `bson:"invoice_id" json:"invoice_id"`
+	TicketIDs      []primitive.ObjectID `bson:"ticket_ids" json:"ticket_ids"`
+	Amount         int                  `bson:"amount" json:"amount"`
+	RefundPercent  int                  `bson:"refund_percent" json:"refund_percent"`
+	Reason         string               `bson:"reason,omitempty" json:"reason,omitempty"`
+	Status         consts.RefundStatus  `bson:"status" json:"status"` // requested, processing, completed, rejected, failed
+
+	Provider         string             `bson:"provider,omitempty" json:"provider,omitempty"`
+	ProviderRefundID string             `bson:"provider_refund_id,omitempty" json:"provider_refund_id,omitempty"`
+	FailureReason    string             `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	RejectReason     string             `bson:"reject_reason,omitempty" json:"reject_reason,omitempty"`
+	CreditNoteID     primitive.ObjectID `bson:"credit_note_id,omitempty" json:"credit_note_id,omitempty"`
+	ReviewedBy       primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	ReviewedAt       *time.Time         `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	CompletedAt      *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	InitiatedByStaff bool               `bson:"initiated_by_staff" json:"initiated_by_staff"`
+	OwnerID          primitive.ObjectID `bson:"owner_id" json:"owner_id"` // Người mua của đơn đăng ký
+
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	UpdatedBy primitive.ObjectID `bson:"updated_by" json:"updated_by"`
+}
+
+type Refunds []Refund
+
+func (u *Refund) getCollectionName() string {
+	return "refunds"
+}
+
+func (u *Refund) Create(ctx context.Context) error {
+	var (
+		db  = database.GetDB()
+		err error
+	)
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	_, err = db.Collection(u.getCollectionName()).InsertOne(ctx, u)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *Refund) First(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	err := db.Collection(u.getCollectionName()).FindOne(ctx, filter, opts...).Decode(u)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *Refund) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (Refunds, error) {
+	var (
+		db      = database.GetDB()
+		refunds Refunds
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &refunds); err != nil {
+		return nil, err
+	}
+
+	if refunds == nil {
+		refunds = []Refund{}
+	}
+
+	return refunds, nil
+}
+
+func (u *Refund) Update(ctx context.Context, filter bson.M, updateDoc bson.M, opts ...*options.UpdateOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	res, err := db.Collection(u.getCollectionName()).UpdateOne(ctx, filter, updateDoc, opts...)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (u *Refund) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	count, err := db.Collection(u.getCollectionName()).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -17,19 +17,27 @@ type Registration struct {
 	EventID                primitive.ObjectID `bson:"event_id" json:"event_id"`
 	InvoiceID              primitive.ObjectID `bson:"invoice_id" json:"invoice_id"`
 	PaymentTransactionCode string             `bson:"payment_transaction_code" json:"payment_transaction_code"`
-	//PaymentMethod *string    `bson:"payment_method" json:"payment_method"`
+	PaymentMethod          string             `bson:"payment_method,omitempty" json:"payment_method,omitempty"` // VNPAY, MOMO, ZALOPAY, FREE, FAKE... (rỗng = VNPAY)
 	//Type
-	Tickets []struct {
-		TicketTypeID primitive.ObjectID `json:"ticket_type_id"`
-		Quantity     int                `json:"quantity"`
-	} `bson:"tickets" json:"tickets"`
+	Tickets       []RegistrationTicket           `bson:"tickets" json:"tickets"`
 	TotalQuantity int                            `bson:"total_quantity" json:"total_quantity"`
-	TotalPrice    int                            `bson:"total_price" json:"total_price"`
+	SubtotalPrice int                            `bson:"subtotal_price,omitempty" json:"subtotal_price,omitempty"` // Tổng tiền vé trước giảm giá
+	TotalPrice    int                            `bson:"total_price" json:"total_price"`                           // Số tiền phải trả sau giảm giá
+	Discount      *RegistrationDiscount          `bson:"discount,omitempty" json:"discount,omitempty"`
 	Status        consts.EventRegistrationStatus `bson:"status" json:"status"` // pending, paid, cancelled, refunded
 
 	PaidAt            *time.Time `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
 	CancelledAt       *time.Time `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	CancelReason      string     `bson:"cancel_reason,omitempty" json:"cancel_reason,omitempty"` // expired, user_cancelled
+	PaymentVoidedAt   *time.Time `bson:"payment_voided_at,omitempty" json:"payment_voided_at,omitempty"`
+	RefundedAmount    int        `bson:"refunded_amount,omitempty" json:"refunded_amount,omitempty"`
+	RefundedAt        *time.Time `bson:"refunded_at,omitempty" json:"refunded_at,omitempty"`
 	TicketEmailSentAt *time.Time `bson:"ticket_email_sent_at,omitempty" json:"ticket_email_sent_at,omitempty"`
+	ReconciledAt      *time.Time `bson:"reconciled_at,omitempty" json:"reconciled_at,omitempty"` // Đã đối soát lại với cổng sau khi hủy
+
+	RefundPendingAmount int `bson:"refund_pending_amount,omitempty" json:"refund_pending_amount,omitempty"` // Tổng tiền của các yêu cầu hoàn tiền chưa kết thúc
+
+	LotteryEntryID primitive.ObjectID `bson:"lottery_entry_id,omitempty" json:"lottery_entry_id,omitempty"` // Đơn tạo từ lượt trúng bốc thăm
 
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
@@ -40,6 +48,37 @@ type Registration struct {
 }
 type Registrations []Registration
 
+// Một dòng vé của đơn. Giá được chốt lúc đăng ký theo bậc giá đang áp dụng.
+type RegistrationTicket struct {
+	TicketTypeID primitive.ObjectID `bson:"tickettypeid" json:"ticket_type_id"`
+	Quantity     int                `bson:"quantity" json:"quantity"`
+	UnitPrice    *int               `bson:"unit_price,omitempty" json:"unit_price,omitempty"` // nil với đơn cũ, khi đó lấy giá loại vé
+	PriceTier    string             `bson:"price_tier,omitempty" json:"price_tier,omitempty"`
+	Attendees    []TicketAttendee   `bson:"attendees,omitempty" json:"attendees,omitempty"` // Theo thứ tự vé sinh ra, chép sang Ticket khi xuất vé
+}
+
+// Giá đã chốt trên đơn, đơn cũ chưa chốt giá thì lấy giá gốc của loại vé
+func (t RegistrationTicket) GetUnitPrice(ticketType TicketType) int {
+	if t.UnitPrice != nil {
+		return *t.UnitPrice
+	}
+	return ticketType.Price
+}
+
+// Mã giảm giá đã áp dụng cho đơn, chốt tại thời điểm đăng ký
+type RegistrationDiscount struct {
+	PromoCodeID primitive.ObjectID `bson:"promo_code_id" json:"promo_code_id"`
+	Code        string             `bson:"code" json:"code"`
+	Amount      int                `bson:"amount" json:"amount"`
+	// Phần giảm giá phân bổ cho từng loại vé, dùng để tính lại số tiền khi hoàn vé
+	Allocations []RegistrationDiscountAllocation `bson:"allocations" json:"allocations"`
+}
+
+type RegistrationDiscountAllocation struct {
+	TicketTypeID primitive.ObjectID `bson:"ticket_type_id" json:"ticket_type_id"`
+	Amount       int                `bson:"amount" json:"amount"`
+}
+
 func (u *Registration) getCollectionName() string {
 	return "registrations"
 }
@@ -187,3 +226,26 @@ func (u *Registration) DeleteMany(ctx context.Context, filter bson.M) error {
 
 	return nil
 }
+
+func (u *Registration) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+	filter["deleted_at"] = bson.M{"$exists": false}
+
+	count, err := db.Collection(u.getCollectionName()).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -4,10 +4,12 @@ import (
 	"EventHunting/consts"
 	"EventHunting/database"
 	"context"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -20,10 +22,13 @@ type Ticket struct {
 
 	QRCodeData string `json:"qr_code_data" bson:"qr_code_data"`
 
-	Status consts.TicketStatus `json:"status" bson:"status"`
+	Status   consts.TicketStatus `json:"status" bson:"status"`
+	RefundID primitive.ObjectID  `json:"refund_id,omitempty" bson:"refund_id,omitempty"` // Yêu cầu hoàn tiền đang giữ vé
 
 	CheckedInAt []string `json:"checked_in_at,omitempty" bson:"checked_in_at,omitempty"`
 
+	Attendee *TicketAttendee `json:"attendee,omitempty" bson:"attendee,omitempty"`
+
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 	CreatedBy primitive.ObjectID `json:"created_by" bson:"created_by"`
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
@@ -32,6 +37,32 @@ type Ticket struct {
 
 type Tickets []Ticket
 
+// Người tham dự của vé và câu trả lời form đăng ký của sự kiện
+type TicketAttendee struct {
+	Name    string         `json:"name" bson:"name"`
+	Email   string         `json:"email" bson:"email"`
+	Answers []TicketAnswer `json:"answers,omitempty" bson:"answers,omitempty"`
+}
+
+// Nhãn câu hỏi được lưu lại để vé / file xuất vẫn đọc được khi ban tổ chức sửa form
+type TicketAnswer struct {
+	FieldID string   `json:"field_id" bson:"field_id"`
+	Label   string   `json:"label" bson:"label"`
+	Values  []string `json:"values" bson:"values"` // text / choice: một giá trị, checkbox: các lựa chọn đã chọn
+}
+
+func (u *Ticket) AttendeeName() string {
+	if u.Attendee == nil {
+		return ""
+	}
+	return u.Attendee.Name
+}
+
+// Câu trả lời dạng chuỗi để hiển thị
+func (a TicketAnswer) Display() string {
+	return strings.Join(a.Values, "; ")
+}
+
 func (u *Ticket) getCollectionName() string {
 	return "tickets"
 }
@@ -136,3 +167,98 @@ func toTicketInterfaceSlice(tickets []Ticket) []interface{} {
 	}
 	return result
 }
+
+func (u *Ticket) Update(ctx context.Context, filter bson.M, updateDoc bson.M, opts ...*options.UpdateOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	res, err := db.Collection(u.getCollectionName()).UpdateOne(ctx, filter, updateDoc, opts...)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (u *Ticket) UpdateMany(ctx context.Context, filter bson.M, updateDoc bson.M, opts ...*options.UpdateOptions) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	res, err := db.Collection(u.getCollectionName()).UpdateMany(ctx, filter, updateDoc, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
+func (t *Ticket) ParseEntry() bson.M {
+	result := bson.M{
+		"_id":            t.ID,
+		"event_id":       t.EventID,
+		"ticket_type_id": t.TicketTypeID,
+		"regis_id":       t.RegisID,
+		"qr_code_data":   t.QRCodeData,
+		"status":         t.Status,
+		"checked_in_at":  t.CheckedInAt,
+		"created_at":     t.CreatedAt,
+		"created_by":     t.CreatedBy,
+		"updated_at":     t.UpdatedAt,
+		"updated_by":     t.UpdatedBy,
+	}
+
+	if t.CheckedInAt == nil {
+		result["checked_in_at"] = []string{}
+	}
+
+	return result
+}
+
+func (u *Ticket) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+	filter["deleted_at"] = bson.M{"$exists": false}
+
+	count, err := db.Collection(u.getCollectionName()).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
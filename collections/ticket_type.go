@@ -21,7 +21,16 @@ type TicketType struct {
 	Quantity        *int               `bson:"quantity,omitempty" json:"quantity"`
 	RegisteredCount int                `bson:"registered_count" json:"registered_count"`
 
-	Status consts.TicketTypeStatus `bson:"status"` // active / inactive / canceled
+	Status        consts.TicketTypeStatus `bson:"status"`                                                     // active / inactive / canceled
+	CheckInPolicy consts.CheckInPolicy    `bson:"check_in_policy,omitempty" json:"check_in_policy,omitempty"` // single / daily / unlimited
+
+	// Thời gian mở bán (nil = không giới hạn)
+	SaleStartAt *time.Time `bson:"sale_start_at,omitempty" json:"sale_start_at,omitempty"`
+	SaleEndAt   *time.Time `bson:"sale_end_at,omitempty" json:"sale_end_at,omitempty"`
+	// Bậc giá xét theo thứ tự, hết bậc thì dùng Price
+	PriceTiers []TicketPriceTier `bson:"price_tiers,omitempty" json:"price_tiers,omitempty"`
+	// Bán vé số lượng lớn: giữ vé trên bộ đếm Redis trước khi ghi Mongo (chỉ áp dụng vé có giới hạn số lượng)
+	HighDemand bool `bson:"high_demand,omitempty" json:"high_demand,omitempty"`
 	//Tiện ích danh sách tiện ích (optional)
 
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
@@ -34,6 +43,29 @@ type TicketType struct {
 
 type TicketTypes []TicketType
 
+// Bậc giá (early bird...) hết hiệu lực theo thời gian và/hoặc khi số vé đã bán đạt mức
+type TicketPriceTier struct {
+	Name    string     `bson:"name" json:"name"`
+	Price   int        `bson:"price" json:"price"`
+	EndAt   *time.Time `bson:"end_at,omitempty" json:"end_at,omitempty"`
+	MaxSold *int       `bson:"max_sold,omitempty" json:"max_sold,omitempty"` // Áp dụng khi registered_count còn nhỏ hơn mức này
+}
+
+// Giá vé áp dụng tại một thời điểm
+type TicketPrice struct {
+	Price int
+	Tier  *TicketPriceTier // nil = giá gốc
+	index int
+}
+
+// Lần đổi giá kế tiếp của bậc giá hiện tại
+type TicketPriceChange struct {
+	Price     int        `json:"price"`
+	Tier      string     `json:"tier,omitempty"`      // Rỗng = giá gốc
+	At        *time.Time `json:"at,omitempty"`        // Đổi giá theo thời gian
+	Remaining *int       `json:"remaining,omitempty"` // Số vé còn lại ở giá hiện tại
+}
+
 func (u *TicketType) getCollectionName() string {
 	return "ticket_types"
 }
@@ -182,6 +214,81 @@ func (u *TicketType) DeleteMany(ctx context.Context, filter bson.M) error {
 	return nil
 }
 
+// Vé cũ chưa cấu hình chính sách check-in được coi là vé vào cửa một lần
+func (t *TicketType) GetCheckInPolicy() consts.CheckInPolicy {
+	if t.CheckInPolicy == "" {
+		return consts.CheckInPolicySingle
+	}
+	return t.CheckInPolicy
+}
+
+// Loại vé có bộ đếm tồn kho trên Redis
+func (t *TicketType) UsesInventoryCounter() bool {
+	return t.HighDemand && t.Quantity != nil
+}
+
+// Trạng thái mở bán theo thời gian
+func (t *TicketType) SaleStatus(now time.Time) consts.TicketSaleStatus {
+	if t.SaleStartAt != nil && now.Before(*t.SaleStartAt) {
+		return consts.TicketSaleUpcoming
+	}
+	if t.SaleEndAt != nil && !now.Before(*t.SaleEndAt) {
+		return consts.TicketSaleEnded
+	}
+	return consts.TicketSaleOpen
+}
+
+// Giá áp dụng: bậc đầu tiên còn hạn và chưa bán hết theo số lượng, không còn bậc nào thì lấy giá gốc
+func (t *TicketType) EffectivePrice(now time.Time) TicketPrice {
+	return t.priceFrom(0, now, t.RegisteredCount)
+}
+
+func (t *TicketType) priceFrom(start int, at time.Time, sold int) TicketPrice {
+	for i := start; i < len(t.PriceTiers); i++ {
+		tier := &t.PriceTiers[i]
+		if tier.EndAt != nil && !at.Before(*tier.EndAt) {
+			continue
+		}
+		if tier.MaxSold != nil && sold >= *tier.MaxSold {
+			continue
+		}
+		return TicketPrice{Price: tier.Price, Tier: tier, index: i}
+	}
+	return TicketPrice{Price: t.Price, index: len(t.PriceTiers)}
+}
+
+// Lần đổi giá kế tiếp, nil nếu đang ở giá gốc hoặc đợt bán kết thúc trước khi đổi giá
+func (t *TicketType) NextPriceChange(now time.Time) *TicketPriceChange {
+	current := t.EffectivePrice(now)
+	if current.Tier == nil {
+		return nil
+	}
+
+	at, sold := now, t.RegisteredCount
+	change := &TicketPriceChange{}
+	if current.Tier.EndAt != nil {
+		at = *current.Tier.EndAt
+		change.At = current.Tier.EndAt
+	}
+	if current.Tier.MaxSold != nil {
+		remaining := *current.Tier.MaxSold - t.RegisteredCount
+		change.Remaining = &remaining
+		if change.At == nil {
+			sold = *current.Tier.MaxSold
+		}
+	}
+	if t.SaleEndAt != nil && change.At != nil && !change.At.Before(*t.SaleEndAt) {
+		return nil
+	}
+
+	next := t.priceFrom(current.index+1, at, sold)
+	change.Price = next.Price
+	if next.Tier != nil {
+		change.Tier = next.Tier.Name
+	}
+	return change
+}
+
 func (t *TicketType) ParseEntry() bson.M {
 	result := bson.M{
 		"_id":              t.ID,
@@ -190,17 +297,32 @@ func (t *TicketType) ParseEntry() bson.M {
 		"description":      t.Description,
 		"registered_count": t.RegisteredCount,
 		"status":           t.Status,
+		"check_in_policy":  t.GetCheckInPolicy(),
+		"high_demand":      t.HighDemand,
 		"created_at":       t.CreatedAt,
 		"created_by":       t.CreatedBy,
 		"updated_at":       t.UpdatedAt,
 		"updated_by":       t.UpdatedBy,
 	}
 
-	if t.Price == 0 {
+	// Giá hiển thị là giá đang áp dụng theo bậc giá
+	now := time.Now()
+	current := t.EffectivePrice(now)
+	if current.Price == 0 {
 		result["price"] = "Vé miễn phí"
 	} else {
-		result["price"] = t.Price
+		result["price"] = current.Price
+	}
+	result["base_price"] = t.Price
+	result["sale_status"] = t.SaleStatus(now)
+	result["sale_start_at"] = t.SaleStartAt
+	result["sale_end_at"] = t.SaleEndAt
+	result["price_tiers"] = t.PriceTiers
+	result["current_tier"] = nil
+	if current.Tier != nil {
+		result["current_tier"] = current.Tier.Name
 	}
+	result["next_price_change"] = t.NextPriceChange(now)
 
 	if t.Quantity != nil {
 		result["quantity"] = *t.Quantity
@@ -0,0 +1,203 @@
+package collections
+
+import (
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Một lượt xếp hàng chờ vé của loại vé đã bán hết, thứ tự theo created_at
+type WaitlistEntry struct {
+	ID           primitive.ObjectID    `bson:"_id" json:"id"`
+	EventID      primitive.ObjectID    `bson:"event_id" json:"event_id"`
+	TicketTypeID primitive.ObjectID    `bson:"ticket_type_id" json:"ticket_type_id"`
+	AccountID    primitive.ObjectID    `bson:"account_id" json:"account_id"`
+	Quantity     int                   `bson:"quantity" json:"quantity"` // Số vé muốn mua
+	Status       consts.WaitlistStatus `bson:"status" json:"status"`     // waiting / offered / claimed / expired / cancelled
+
+	// Lượt giữ vé: số vé đã được giữ (cộng vào registered_count) đến hết hạn
+	OfferedQuantity int                `bson:"offered_quantity,omitempty" json:"offered_quantity,omitempty"`
+	OfferedAt       *time.Time         `bson:"offered_at,omitempty" json:"offered_at,omitempty"`
+	OfferExpiresAt  *time.Time         `bson:"offer_expires_at,omitempty" json:"offer_expires_at,omitempty"`
+	RegistrationID  primitive.ObjectID `bson:"registration_id,omitempty" json:"registration_id,omitempty"` // Đơn đã dùng lượt giữ vé
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type WaitlistEntries []WaitlistEntry
+
+func (u *WaitlistEntry) getCollectionName() string {
+	return "waitlist_entries"
+}
+
+func (u *WaitlistEntry) Create(ctx context.Context) error {
+	var (
+		db  = database.GetDB()
+		err error
+	)
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if u.ID.IsZero() {
+		u.ID = primitive.NewObjectID()
+	}
+	_, err = db.Collection(u.getCollectionName()).InsertOne(ctx, u)
+
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (u *WaitlistEntry) First(ctx context.Context, filter bson.M, opts ...*options.FindOneOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	return db.Collection(u.getCollectionName()).FindOne(ctx, filter, opts...).Decode(u)
+}
+
+func (u *WaitlistEntry) Find(ctx context.Context, filter bson.M, opts ...*options.FindOptions) (WaitlistEntries, error) {
+	var (
+		db      = database.GetDB()
+		entries WaitlistEntries
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	cursor, err := db.Collection(u.getCollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	if entries == nil {
+		entries = WaitlistEntries{}
+	}
+
+	return entries, nil
+}
+
+func (u *WaitlistEntry) Update(ctx context.Context, filter bson.M, updateDoc bson.M, opts ...*options.UpdateOptions) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	res, err := db.Collection(u.getCollectionName()).UpdateOne(ctx, filter, updateDoc, opts...)
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (u *WaitlistEntry) CountDocuments(ctx context.Context, filter bson.M) (int64, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+	}
+
+	count, err := db.Collection(u.getCollectionName()).CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Danh sách loại vé đang có người xếp hàng
+func (u *WaitlistEntry) DistinctTicketTypeIDs(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+	}
+
+	values, err := db.Collection(u.getCollectionName()).Distinct(ctx, "ticket_type_id", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Index phục vụ lấy người kế tiếp trong hàng chờ và quét lượt giữ vé hết hạn
+func (u *WaitlistEntry) EnsureIndexes(ctx context.Context) error {
+	var (
+		db = database.GetDB()
+	)
+
+	if ctx == nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+	}
+
+	_, err := db.Collection(u.getCollectionName()).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "ticket_type_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("ticket_type_queue"),
+		},
+		{
+			Keys:    bson.D{{Key: "status", Value: 1}, {Key: "offer_expires_at", Value: 1}},
+			Options: options.Index().SetName("offer_expiry"),
+		},
+	})
+	return err
+}
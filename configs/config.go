@@ -104,6 +104,16 @@ func GetRedisDB() int {
 	return int(redis["db"].(int))
 }
 
+func GetIdempotencyTTLHours() int {
+	idempotency := mpConfig["idempotency"].(map[string]interface{})
+	return idempotency["ttl_hours"].(int)
+}
+
+func GetIdempotencyLockSeconds() int {
+	idempotency := mpConfig["idempotency"].(map[string]interface{})
+	return idempotency["lock_seconds"].(int)
+}
+
 func GetSMTPHost() string {
 	smtp := mpConfig["smtp"].(map[string]interface{})
 	return fmt.Sprintf("%v", smtp["host"])
@@ -185,13 +195,240 @@ func GetVNPAYUrl() string {
 	return fmt.Sprintf("%v", vnPay["url"])
 }
 
+func GetVNPAYApiUrl() string {
+	vnPay := mpConfig["vn_pay"].(map[string]interface{})
+	return fmt.Sprintf("%v", vnPay["api_url"])
+}
+
 func GetRegisExpirationMinutes() int {
 	jobs := mpConfig["jobs"].(map[string]interface{})
 	target := jobs["registration"].(map[string]interface{})
 	return target["expiration_minutes"].(int)
 }
 
+func GetWaitlistOfferMinutes() int {
+	jobs := mpConfig["jobs"].(map[string]interface{})
+	target := jobs["waitlist"].(map[string]interface{})
+	return target["offer_minutes"].(int)
+}
+
+func GetReconcileMinAgeMinutes() int {
+	jobs := mpConfig["jobs"].(map[string]interface{})
+	target := jobs["reconciliation"].(map[string]interface{})
+	return target["min_age_minutes"].(int)
+}
+
+func GetReconcileLookbackHours() int {
+	jobs := mpConfig["jobs"].(map[string]interface{})
+	target := jobs["reconciliation"].(map[string]interface{})
+	return target["lookback_hours"].(int)
+}
+
+func outboxConfig() map[string]interface{} {
+	jobs := mpConfig["jobs"].(map[string]interface{})
+	return jobs["outbox"].(map[string]interface{})
+}
+
+func GetOutboxPollIntervalSeconds() int {
+	return outboxConfig()["poll_interval_seconds"].(int)
+}
+
+func GetOutboxBatchSize() int {
+	return outboxConfig()["batch_size"].(int)
+}
+
+func GetOutboxLeaseSeconds() int {
+	return outboxConfig()["lease_seconds"].(int)
+}
+
+func GetOutboxMaxBackoffSeconds() int {
+	return outboxConfig()["max_backoff_seconds"].(int)
+}
+
 func GetMaxRetries() int {
 	jobs := mpConfig["jobs"].(map[string]interface{})
 	return jobs["max_retries"].(int)
 }
+
+func GetCheckInEarlyMinutes() int {
+	checkin := mpConfig["checkin"].(map[string]interface{})
+	return checkin["early_minutes"].(int)
+}
+
+func GetTicketQRActiveKeyID() string {
+	ticketQR := mpConfig["ticket_qr"].(map[string]interface{})
+	return fmt.Sprintf("%v", ticketQR["active_key_id"])
+}
+
+func GetTicketQRSigningKeys() string {
+	ticketQR := mpConfig["ticket_qr"].(map[string]interface{})
+	return fmt.Sprintf("%v", ticketQR["signing_keys"])
+}
+
+func GetTicketQRAcceptLegacy() bool {
+	ticketQR := mpConfig["ticket_qr"].(map[string]interface{})
+	return ticketQR["accept_legacy"].(bool)
+}
+
+func GetWaitingRoomTokenSecret() string {
+	waitingRoom := mpConfig["waiting_room"].(map[string]interface{})
+	return fmt.Sprintf("%v", waitingRoom["token_secret"])
+}
+
+func GetWaitingRoomTokenTTLSeconds() int {
+	waitingRoom := mpConfig["waiting_room"].(map[string]interface{})
+	return waitingRoom["token_ttl_seconds"].(int)
+}
+
+func GetWaitingRoomTickSeconds() int {
+	waitingRoom := mpConfig["waiting_room"].(map[string]interface{})
+	return waitingRoom["tick_seconds"].(int)
+}
+
+func GetWaitingRoomQueueTTLHours() int {
+	waitingRoom := mpConfig["waiting_room"].(map[string]interface{})
+	return waitingRoom["queue_ttl_hours"].(int)
+}
+
+func GetPaymentDefaultProvider() string {
+	payment := mpConfig["payment"].(map[string]interface{})
+	return optionalString(payment["default_provider"])
+}
+
+func GetPaymentResultPageUrl() string {
+	payment := mpConfig["payment"].(map[string]interface{})
+	return optionalString(payment["result_page_url"])
+}
+
+func GetInvoiceNumberFormat() string {
+	invoice := mpConfig["invoice"].(map[string]interface{})
+	return optionalString(invoice["number_format"])
+}
+
+func GetInvoiceNumberReset() string {
+	invoice := mpConfig["invoice"].(map[string]interface{})
+	return optionalString(invoice["number_reset"])
+}
+
+func GetInvoiceSellerName() string {
+	invoice := mpConfig["invoice"].(map[string]interface{})
+	return optionalString(invoice["seller_name"])
+}
+
+func GetInvoiceSellerTaxCode() string {
+	invoice := mpConfig["invoice"].(map[string]interface{})
+	return optionalString(invoice["seller_tax_code"])
+}
+
+func GetInvoiceSellerAddress() string {
+	invoice := mpConfig["invoice"].(map[string]interface{})
+	return optionalString(invoice["seller_address"])
+}
+
+func GetInvoicePDFFontDir() string {
+	invoice := mpConfig["invoice"].(map[string]interface{})
+	return optionalString(invoice["pdf_font_dir"])
+}
+
+func eInvoiceConfig() map[string]interface{} {
+	return mpConfig["einvoice"].(map[string]interface{})
+}
+
+func GetEInvoiceProvider() string {
+	return optionalString(eInvoiceConfig()["provider"])
+}
+
+func GetEInvoiceFormNumber() string {
+	return optionalString(eInvoiceConfig()["form_number"])
+}
+
+func GetEInvoiceSymbol() string {
+	return optionalString(eInvoiceConfig()["symbol"])
+}
+
+func GetEInvoiceVATRate() int {
+	return eInvoiceConfig()["vat_rate"].(int)
+}
+
+func GetEInvoiceSolutionTaxCode() string {
+	return optionalString(eInvoiceConfig()["solution_tax_code"])
+}
+
+func GetEInvoiceFileDir() string {
+	file := eInvoiceConfig()["file"].(map[string]interface{})
+	return optionalString(file["dir"])
+}
+
+// Giá trị cho phép để trống (biến môi trường không đặt thì YAML trả về nil)
+func optionalString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func GetFakePaymentEnabled() bool {
+	payment := mpConfig["payment"].(map[string]interface{})
+	fake := payment["fake"].(map[string]interface{})
+	return fake["enabled"].(bool)
+}
+
+func GetFakePaymentSecret() string {
+	payment := mpConfig["payment"].(map[string]interface{})
+	fake := payment["fake"].(map[string]interface{})
+	return fmt.Sprintf("%v", fake["secret"])
+}
+
+func GetFakePaymentOutcome() string {
+	payment := mpConfig["payment"].(map[string]interface{})
+	fake := payment["fake"].(map[string]interface{})
+	return fmt.Sprintf("%v", fake["outcome"])
+}
+
+func GetFakePaymentDelaySeconds() int {
+	payment := mpConfig["payment"].(map[string]interface{})
+	fake := payment["fake"].(map[string]interface{})
+	return fake["delay_seconds"].(int)
+}
+
+func momoConfig() map[string]interface{} {
+	payment := mpConfig["payment"].(map[string]interface{})
+	return payment["momo"].(map[string]interface{})
+}
+
+func GetMoMoEndpoint() string {
+	return fmt.Sprintf("%v", momoConfig()["endpoint"])
+}
+
+func GetMoMoPartnerCode() string {
+	return fmt.Sprintf("%v", momoConfig()["partner_code"])
+}
+
+func GetMoMoAccessKey() string {
+	return fmt.Sprintf("%v", momoConfig()["access_key"])
+}
+
+func GetMoMoSecretKey() string {
+	return fmt.Sprintf("%v", momoConfig()["secret_key"])
+}
+
+func zaloPayConfig() map[string]interface{} {
+	payment := mpConfig["payment"].(map[string]interface{})
+	return payment["zalopay"].(map[string]interface{})
+}
+
+func GetZaloPayEndpoint() string {
+	return fmt.Sprintf("%v", zaloPayConfig()["endpoint"])
+}
+
+func GetZaloPayAppID() string {
+	return fmt.Sprintf("%v", zaloPayConfig()["app_id"])
+}
+
+func GetZaloPayKey1() string {
+	return fmt.Sprintf("%v", zaloPayConfig()["key1"])
+}
+
+func GetZaloPayKey2() string {
+	return fmt.Sprintf("%v", zaloPayConfig()["key2"])
+}
@@ -0,0 +1,16 @@
+package consts
+
+// Trạng thái hóa đơn điện tử của một hóa đơn
+type EInvoiceStatus string
+
+const (
+	EInvoiceStatusSubmitting EInvoiceStatus = "submitting" // Đang gửi sang nhà cung cấp
+	EInvoiceStatusSubmitted  EInvoiceStatus = "submitted"  // Nhà cung cấp đã nhận, chờ cơ quan thuế cấp mã
+	EInvoiceStatusIssued     EInvoiceStatus = "issued"     // Đã cấp mã / phát hành
+	EInvoiceStatusFailed     EInvoiceStatus = "failed"     // Gửi lỗi, có thể gửi lại
+)
+
+// Nhà cung cấp hóa đơn điện tử
+const (
+	EInvoiceProviderFile = "FILE" // Ghi XML ra thư mục local, dùng khi chưa tích hợp nhà cung cấp thật
+)
@@ -11,13 +11,74 @@ var (
 	ErrFatalDataNotFound = errors.New("dữ liệu không tồn tại trong DB -> drop job")
 	ErrFatalInvalidData  = errors.New("dữ liệu sai định dạng logic -> drop job")
 
-	ErrRegistrationNotFound = errors.New("không tìm thấy đăng ký hoặc chưa thanh toán")
-	ErrEventNotFound        = errors.New("không tìm thấy sự kiện hoặc sự kiện không hoạt động")
-	ErrAccountNotFound      = errors.New("không tìm thấy tài khoản đăng ký")
-	ErrTicketAlreadySent    = errors.New("email vé đã được gửi trước đó")
-	ErrTicketTypeFetch      = errors.New("lỗi khi lấy thông tin loại vé")
-	ErrTicketProcessing     = errors.New("lỗi khi xử lý vé")
-	ErrEmailBuild           = errors.New("lỗi khi tạo nội dung email")
+	ErrRegistrationNotFound   = errors.New("không tìm thấy đăng ký hoặc chưa thanh toán")
+	ErrEventNotFound          = errors.New("không tìm thấy sự kiện hoặc sự kiện không hoạt động")
+	ErrAccountNotFound        = errors.New("không tìm thấy tài khoản đăng ký")
+	ErrTicketAlreadySent      = errors.New("email vé đã được gửi trước đó")
+	ErrTicketTypeFetch        = errors.New("lỗi khi lấy thông tin loại vé")
+	ErrTicketProcessing       = errors.New("lỗi khi xử lý vé")
+	ErrEmailBuild             = errors.New("lỗi khi tạo nội dung email")
+	ErrRegistrationNotPending = errors.New("đơn đăng ký không còn ở trạng thái chờ thanh toán")
+
+	ErrTicketNotFound         = errors.New("vé không tồn tại")
+	ErrTicketWrongEvent       = errors.New("vé không thuộc sự kiện này")
+	ErrTicketCancelled        = errors.New("vé đã bị hủy")
+	ErrTicketRefunding        = errors.New("vé đang được hoàn tiền")
+	ErrTicketRefunded         = errors.New("vé đã được hoàn tiền")
+	ErrTicketAlreadyCheckedIn = errors.New("vé đã được check-in")
+	ErrCheckInNotOpen         = errors.New("chưa đến giờ check-in")
+	ErrCheckInClosed          = errors.New("đã hết thời gian check-in")
+	ErrTicketTokenInvalid     = errors.New("mã QR không hợp lệ hoặc đã bị thay đổi")
+	ErrTicketLegacyCode       = errors.New("mã QR cũ không còn được chấp nhận")
+	ErrCheckInEventRequired   = errors.New("mã QR cũ phải kèm event_id của sự kiện đang check-in")
+
+	ErrRefundNotFound       = errors.New("không tìm thấy yêu cầu hoàn tiền")
+	ErrRefundNotAllowed     = errors.New("sự kiện không cho phép hoàn tiền")
+	ErrRefundDeadlinePassed = errors.New("đã quá hạn yêu cầu hoàn tiền")
+	ErrRefundNotPaid        = errors.New("đơn đăng ký chưa thanh toán")
+	ErrRefundNoTickets      = errors.New("không có vé hợp lệ để hoàn tiền")
+	ErrRefundInProgress     = errors.New("vé đang có yêu cầu hoàn tiền khác")
+	ErrRefundInvalidState   = errors.New("trạng thái yêu cầu hoàn tiền không hợp lệ")
+	ErrRefundProvider       = errors.New("cổng thanh toán từ chối hoàn tiền")
+	ErrRefundTicketsChanged = errors.New("vé đã check-in hoặc thay đổi trạng thái, yêu cầu hoàn tiền bị hủy")
+
+	ErrPaymentOrderNotFound    = errors.New("không tìm thấy đơn thanh toán")
+	ErrPaymentAlreadyConfirmed = errors.New("đơn đã được xác nhận thanh toán")
+	ErrPaymentInvalidAmount    = errors.New("số tiền thanh toán không khớp")
+
+	ErrEInvoiceAlreadySubmitted = errors.New("hóa đơn đã được gửi phát hành hóa đơn điện tử")
+	ErrEInvoiceInProgress       = errors.New("hóa đơn đang được gửi phát hành")
+	ErrEInvoiceOriginalMissing  = errors.New("hóa đơn gốc chưa được phát hành hóa đơn điện tử")
+	ErrEInvoiceProvider         = errors.New("nhà cung cấp hóa đơn điện tử từ chối hóa đơn")
+
+	ErrPromoCodeInvalid       = errors.New("mã giảm giá không tồn tại hoặc đã ngừng áp dụng")
+	ErrPromoCodeNotStarted    = errors.New("mã giảm giá chưa đến thời gian áp dụng")
+	ErrPromoCodeExpired       = errors.New("mã giảm giá đã hết hạn")
+	ErrPromoCodeNotApplicable = errors.New("mã giảm giá không áp dụng cho các loại vé đã chọn")
+	ErrPromoCodeExhausted     = errors.New("mã giảm giá đã hết lượt sử dụng")
+	ErrPromoCodeUserLimit     = errors.New("bạn đã dùng hết số lượt cho phép của mã giảm giá này")
+
+	ErrWaitlistNotFound        = errors.New("không tìm thấy lượt đăng ký chờ")
+	ErrWaitlistUnlimited       = errors.New("loại vé không giới hạn số lượng, không cần đăng ký chờ")
+	ErrWaitlistTicketAvailable = errors.New("loại vé vẫn còn đủ số lượng, vui lòng đăng ký trực tiếp")
+	ErrWaitlistAlreadyJoined   = errors.New("bạn đã có trong danh sách chờ của loại vé này")
+	ErrWaitlistInvalidState    = errors.New("lượt đăng ký chờ không còn hiệu lực")
+
+	ErrInventorySoldOut = errors.New("không còn đủ vé")
+
+	ErrWaitingRoomDisabled    = errors.New("sự kiện không bật phòng chờ")
+	ErrWaitingRoomNotJoined   = errors.New("bạn chưa vào hàng chờ của sự kiện này")
+	ErrWaitingRoomExpired     = errors.New("lượt vào đã hết hạn, vui lòng xếp hàng lại")
+	ErrAdmissionTokenRequired = errors.New("sự kiện đang mở bán qua phòng chờ, vui lòng xếp hàng để nhận vé vào cửa")
+	ErrAdmissionTokenInvalid  = errors.New("vé vào cửa không hợp lệ hoặc đã hết hạn")
+
+	ErrLotteryNotEnabled         = errors.New("sự kiện không phân bổ vé bằng bốc thăm")
+	ErrLotteryClosed             = errors.New("ngoài thời gian đăng ký bốc thăm")
+	ErrLotteryPaidTicket         = errors.New("bốc thăm chỉ áp dụng cho vé miễn phí")
+	ErrLotteryQuantity           = errors.New("số vé đăng ký bốc thăm vượt quá giới hạn mỗi người của sự kiện")
+	ErrLotteryEntryNotFound      = errors.New("không tìm thấy lượt đăng ký bốc thăm")
+	ErrLotteryDrawNotFound       = errors.New("không tìm thấy lần bốc thăm")
+	ErrLotteryDirectRegistration = errors.New("sự kiện phân bổ vé bằng bốc thăm, vui lòng đăng ký tham gia bốc thăm")
 )
 
 type LockReason string
@@ -6,3 +6,12 @@ const (
 	EventStatusEnded     = "Đã kết thúc"
 	EventStatusCancelled = "Đã hủy"
 )
+
+// Loại câu hỏi trong form đăng ký của sự kiện
+type FormFieldType string
+
+const (
+	FormFieldText     FormFieldType = "text"     // Trả lời tự do
+	FormFieldChoice   FormFieldType = "choice"   // Chọn một trong các lựa chọn
+	FormFieldCheckbox FormFieldType = "checkbox" // Chọn nhiều trong các lựa chọn
+)
@@ -0,0 +1,28 @@
+package consts
+
+// Trạng thái bốc thăm của sự kiện
+type LotteryStatus string
+
+const (
+	LotteryOpen  LotteryStatus = "open"  // Đang nhận đăng ký bốc thăm / chờ bốc thăm
+	LotteryDrawn LotteryStatus = "drawn" // Đã bốc thăm xong mọi loại vé
+)
+
+type LotteryEntryStatus string
+
+const (
+	LotteryEntryPending   LotteryEntryStatus = "pending"   // Chờ bốc thăm
+	LotteryEntryWon       LotteryEntryStatus = "won"       // Trúng, đã tạo đơn đăng ký
+	LotteryEntryLost      LotteryEntryStatus = "lost"      // Không trúng
+	LotteryEntryCancelled LotteryEntryStatus = "cancelled" // Người dùng rút khỏi bốc thăm
+)
+
+type LotteryDrawStatus string
+
+const (
+	LotteryDrawRunning   LotteryDrawStatus = "running"   // Đã chốt seed, đang ghi kết quả
+	LotteryDrawCompleted LotteryDrawStatus = "completed" // Đã ghi kết quả cho mọi lượt tham gia
+)
+
+// Thuật toán bốc thăm, đổi cách xáo trộn thì phải đổi phiên bản để các lần bốc cũ vẫn kiểm chứng được
+const LotteryAlgorithmV1 = "chacha8-fisher-yates-v1"
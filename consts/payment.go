@@ -0,0 +1,48 @@
+package consts
+
+// Cổng thanh toán
+const (
+	PaymentMethodVNPAY   = "VNPAY"
+	PaymentMethodMoMo    = "MOMO"
+	PaymentMethodZaloPay = "ZALOPAY"
+	PaymentMethodFake    = "FAKE" // Cổng giả lập cho môi trường local
+	PaymentMethodFree    = "FREE" // Đơn 0đ, không qua cổng thanh toán
+)
+
+// Sai lệch thanh toán cần admin xử lý thủ công
+type PaymentDiscrepancyType string
+
+const (
+	DiscrepancyPaidCancelled  PaymentDiscrepancyType = "paid_cancelled"  // Cổng báo đã thanh toán nhưng đơn đã hủy
+	DiscrepancyAmountMismatch PaymentDiscrepancyType = "amount_mismatch" // Số tiền cổng báo khác tổng tiền đơn
+	DiscrepancyRefundPending  PaymentDiscrepancyType = "refund_pending"  // Cổng đã hoàn tiền nhưng vé/đơn/hóa đơn chưa cập nhật
+)
+
+type PaymentDiscrepancyStatus string
+
+const (
+	DiscrepancyStatusOpen     PaymentDiscrepancyStatus = "open"
+	DiscrepancyStatusResolved PaymentDiscrepancyStatus = "resolved"
+)
+
+// Kết quả xử lý một callback/IPN trong sổ payment_events
+type PaymentEventOutcome string
+
+const (
+	PaymentEventSettled          PaymentEventOutcome = "settled"           // Xác nhận thanh toán, đơn chuyển PAID
+	PaymentEventPaymentFailed    PaymentEventOutcome = "payment_failed"    // Cổng báo giao dịch thất bại
+	PaymentEventAlreadyConfirmed PaymentEventOutcome = "already_confirmed" // Đơn đã được xác nhận trước đó
+	PaymentEventOrderNotFound    PaymentEventOutcome = "order_not_found"
+	PaymentEventOrderCancelled   PaymentEventOutcome = "order_cancelled"
+	PaymentEventInvalidAmount    PaymentEventOutcome = "invalid_amount"
+	PaymentEventInvalidSignature PaymentEventOutcome = "invalid_signature"
+	PaymentEventDisplayed        PaymentEventOutcome = "displayed" // Trang trả về trình duyệt, không đổi trạng thái đơn
+	PaymentEventError            PaymentEventOutcome = "error"
+)
+
+// Nơi nhận callback
+const (
+	PaymentEndpointIPN      = "ipn"
+	PaymentEndpointReturn   = "return"
+	PaymentEndpointCallback = "callback"
+)
@@ -0,0 +1,17 @@
+package consts
+
+type PromoDiscountType string
+
+const (
+	// Giảm theo phần trăm giá vé
+	PromoDiscountPercent PromoDiscountType = "percent"
+	// Giảm một số tiền cố định trên đơn
+	PromoDiscountFixed PromoDiscountType = "fixed"
+)
+
+type PromoCodeStatus string
+
+const (
+	PromoCodeActive   PromoCodeStatus = "active"
+	PromoCodeInactive PromoCodeStatus = "inactive"
+)
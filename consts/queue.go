@@ -3,3 +3,17 @@ package consts
 const (
 	QueueNameEmail = "transactional_email_queue"
 )
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending   OutboxStatus = "pending"
+	OutboxStatusPublished OutboxStatus = "published"
+)
+
+// Loại message trong outbox, trùng với Type của job trong queue
+const (
+	OutboxTopicTicketEmail   = "ticket_email"
+	OutboxTopicWaitlistOffer = "waitlist_offer"
+	OutboxTopicLotteryResult = "lottery_result"
+)
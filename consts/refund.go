@@ -0,0 +1,24 @@
+package consts
+
+type RefundStatus string
+
+const (
+	RefundStatusRequested  RefundStatus = "requested"  // Người mua gửi yêu cầu, chờ ban tổ chức duyệt
+	RefundStatusProcessing RefundStatus = "processing" // Đã duyệt, đang gọi cổng thanh toán
+	RefundStatusCompleted  RefundStatus = "completed"
+	RefundStatusRejected   RefundStatus = "rejected"
+	RefundStatusFailed     RefundStatus = "failed" // Cổng thanh toán trả lỗi, có thể duyệt lại
+)
+
+// Loại chứng từ
+const (
+	InvoiceTypeInvoice    = "invoice"
+	InvoiceTypeCreditNote = "credit_note"
+)
+
+// Trạng thái hóa đơn
+const (
+	InvoiceStatusCompleted         = "Completed"
+	InvoiceStatusPartiallyRefunded = "PartiallyRefunded"
+	InvoiceStatusRefunded          = "Refunded"
+)
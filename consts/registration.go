@@ -6,4 +6,12 @@ const (
 	RegistrationPending   EventRegistrationStatus = "PENDING"
 	RegistrationPaid      EventRegistrationStatus = "PAID"
 	RegistrationCancelled EventRegistrationStatus = "CANCELLED"
+	RegistrationRefunded  EventRegistrationStatus = "REFUNDED"
+)
+
+// Lý do hủy đơn đăng ký
+const (
+	CancelReasonExpired       = "expired"
+	CancelReasonUserCancelled = "user_cancelled"
+	CancelReasonPaymentFailed = "payment_failed" // Không tạo được link thanh toán
 )
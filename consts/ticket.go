@@ -6,5 +6,14 @@ const (
 	TicketStatusConfirmed TicketStatus = "confirmed"
 	TicketStatusCheckedIn TicketStatus = "checked_in"
 	TicketStatusCancelled TicketStatus = "cancelled"
+	TicketStatusRefunding TicketStatus = "refunding"
 	TicketStatusRefunded  TicketStatus = "refunded"
 )
+
+type CheckInResult string
+
+const (
+	CheckInResultAccepted  CheckInResult = "accepted"
+	CheckInResultDuplicate CheckInResult = "duplicate"
+	CheckInResultRejected  CheckInResult = "rejected"
+)
@@ -7,3 +7,22 @@ const (
 	TicketTypeInactive TicketTypeStatus = "inactive"
 	TicketTypeCanceled TicketTypeStatus = "canceled"
 )
+
+type CheckInPolicy string
+
+const (
+	// Chỉ được check-in một lần duy nhất
+	CheckInPolicySingle CheckInPolicy = "single"
+	// Mỗi ngày diễn ra sự kiện được check-in một lần
+	CheckInPolicyDaily CheckInPolicy = "daily"
+	// Ra vào không giới hạn trong thời gian sự kiện
+	CheckInPolicyUnlimited CheckInPolicy = "unlimited"
+)
+
+type TicketSaleStatus string
+
+const (
+	TicketSaleUpcoming TicketSaleStatus = "upcoming" // Chưa đến giờ mở bán
+	TicketSaleOpen     TicketSaleStatus = "open"
+	TicketSaleEnded    TicketSaleStatus = "ended" // Đã hết thời gian bán
+)
@@ -0,0 +1,11 @@
+package consts
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting   WaitlistStatus = "waiting"   // Đang xếp hàng
+	WaitlistOffered   WaitlistStatus = "offered"   // Đang được giữ vé, chờ người dùng đăng ký
+	WaitlistClaimed   WaitlistStatus = "claimed"   // Đã dùng lượt giữ vé để đăng ký
+	WaitlistExpired   WaitlistStatus = "expired"   // Hết hạn giữ vé, chuyển cho người kế tiếp
+	WaitlistCancelled WaitlistStatus = "cancelled" // Người dùng rời danh sách chờ
+)
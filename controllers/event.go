@@ -72,6 +72,38 @@ func CreateEvent(c *gin.Context) {
 		UpdatedBy:            creatorID,
 	}
 
+	if req.RefundPolicy != nil {
+		newEvent.RefundPolicy = &collections.EventRefundPolicy{
+			Enabled:       req.RefundPolicy.Enabled,
+			DeadlineHours: req.RefundPolicy.DeadlineHours,
+			RefundPercent: req.RefundPolicy.RefundPercent,
+		}
+	}
+	if req.WaitingRoom != nil {
+		newEvent.WaitingRoom = &collections.EventWaitingRoom{
+			Enabled:        req.WaitingRoom.Enabled,
+			AdmitPerMinute: req.WaitingRoom.AdmitPerMinute,
+		}
+	}
+	if req.Lottery != nil && req.Lottery.Enabled {
+		newEvent.Lottery = &collections.EventLottery{
+			Enabled:      true,
+			ApplyStartAt: *req.Lottery.ApplyStartAt,
+			ApplyEndAt:   *req.Lottery.ApplyEndAt,
+			Status:       consts.LotteryOpen,
+		}
+	}
+	if req.RegistrationForm != nil {
+		// Sự kiện mới chưa có loại vé nên câu hỏi chỉ gán được cho loại vé khi cập nhật
+		for _, field := range req.RegistrationForm.Fields {
+			if len(field.TicketTypeIDs) > 0 {
+				utils.ResponseError(c, http.StatusBadRequest, "", "Chỉ gán câu hỏi cho loại vé sau khi đã tạo loại vé")
+				return
+			}
+		}
+		newEvent.RegistrationForm = buildRegistrationForm(req.RegistrationForm)
+	}
+
 	//Kiểm tra trường không bắt buộc
 	mediaIDs := []primitive.ObjectID{}
 	if req.ThumbnailId != nil {
@@ -294,6 +326,43 @@ func UpdateEvent(c *gin.Context) {
 	if req.TopicIDs != nil {
 		updateFields["topic_ids"] = *req.TopicIDs
 	}
+	if req.RefundPolicy != nil {
+		updateFields["refund_policy"] = collections.EventRefundPolicy{
+			Enabled:       req.RefundPolicy.Enabled,
+			DeadlineHours: req.RefundPolicy.DeadlineHours,
+			RefundPercent: req.RefundPolicy.RefundPercent,
+		}
+	}
+	if req.WaitingRoom != nil {
+		updateFields["waiting_room"] = collections.EventWaitingRoom{
+			Enabled:        req.WaitingRoom.Enabled,
+			AdmitPerMinute: req.WaitingRoom.AdmitPerMinute,
+		}
+	}
+	if req.Lottery != nil {
+		// Đã đóng đăng ký thì danh sách tham gia và kết quả bốc thăm không được thay đổi
+		if eventEntry.Lottery != nil && (eventEntry.Lottery.Status == consts.LotteryDrawn || eventEntry.LotteryEnabled() && !time.Now().Before(eventEntry.Lottery.ApplyEndAt)) {
+			utils.ResponseError(c, http.StatusConflict, "", "Sự kiện đã đóng đăng ký bốc thăm, không thể thay đổi cấu hình bốc thăm")
+			return
+		}
+		if req.Lottery.Enabled {
+			updateFields["lottery"] = collections.EventLottery{
+				Enabled:      true,
+				ApplyStartAt: *req.Lottery.ApplyStartAt,
+				ApplyEndAt:   *req.Lottery.ApplyEndAt,
+				Status:       consts.LotteryOpen,
+			}
+		} else {
+			updateFields["lottery.enabled"] = false
+		}
+	}
+	if req.RegistrationForm != nil {
+		if err = checkFormTicketTypes(c.Request.Context(), eventID, req.RegistrationForm); err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "", err.Error())
+			return
+		}
+		updateFields["registration_form"] = buildRegistrationForm(req.RegistrationForm)
+	}
 
 	if req.EventTime != nil {
 		if req.EventTime.StartDate != nil {
@@ -645,6 +714,59 @@ const (
 )
 
 // validate nghiệp vụ
+func buildRegistrationForm(req *dto.EventRegistrationFormReq) *collections.EventRegistrationForm {
+	form := &collections.EventRegistrationForm{
+		CollectAttendees: req.CollectAttendees,
+		Fields:           make([]collections.EventFormField, 0, len(req.Fields)),
+	}
+	for _, field := range req.Fields {
+		options := make([]string, 0, len(field.Options))
+		for _, option := range field.Options {
+			options = append(options, strings.TrimSpace(option))
+		}
+		form.Fields = append(form.Fields, collections.EventFormField{
+			ID:            strings.TrimSpace(field.ID),
+			Label:         strings.TrimSpace(field.Label),
+			Type:          consts.FormFieldType(field.Type),
+			Required:      field.Required,
+			Options:       options,
+			TicketTypeIDs: field.TicketTypeIDs,
+		})
+	}
+	return form
+}
+
+// Loại vé gán cho câu hỏi phải thuộc sự kiện
+func checkFormTicketTypes(ctx context.Context, eventID primitive.ObjectID, req *dto.EventRegistrationFormReq) error {
+	ticketTypeEntry := &collections.TicketType{}
+
+	idSet := make(map[primitive.ObjectID]bool)
+	for _, field := range req.Fields {
+		for _, id := range field.TicketTypeIDs {
+			idSet[id] = true
+		}
+	}
+	if len(idSet) == 0 {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+
+	ticketTypes, err := ticketTypeEntry.Find(ctx, utils.GetFilter(bson.M{
+		"_id":      bson.M{"$in": ids},
+		"event_id": eventID,
+	}))
+	if err != nil {
+		return err
+	}
+	if len(ticketTypes) != len(ids) {
+		return errors.New("Loại vé trong form đăng ký không tồn tại hoặc không thuộc sự kiện này")
+	}
+	return nil
+}
+
 func validateEventUpdateBusiness(req dto.EventUpdateReq, oldEvent *collections.Event) []string {
 	var errs []string
 
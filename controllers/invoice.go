@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Tải hóa đơn PDF: người mua, ban tổ chức sự kiện hoặc admin
+func DownloadInvoicePDF(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	invoiceEntry, ok := loadInvoiceForAccount(c, false)
+	if !ok {
+		return
+	}
+
+	pdfData, err := service.RenderInvoicePDF(ctx, invoiceEntry)
+	if err != nil {
+		log.Printf("ERROR: Tạo PDF hóa đơn %s thất bại: %v", invoiceEntry.InvoiceNumber, err)
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tạo hóa đơn PDF", err.Error())
+		return
+	}
+
+	disposition := "attachment"
+	if c.Query("inline") == "true" {
+		disposition = "inline"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, service.InvoicePDFFilename(invoiceEntry)))
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "application/pdf", pdfData)
+}
+
+// Xuất XML hóa đơn điện tử (ban tổ chức sự kiện hoặc admin)
+func ExportEInvoiceXML(c *gin.Context) {
+	invoiceEntry, ok := loadInvoiceForAccount(c, true)
+	if !ok {
+		return
+	}
+
+	xmlData, err := service.ExportEInvoiceXML(c.Request.Context(), invoiceEntry)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tạo XML hóa đơn điện tử", err.Error())
+		return
+	}
+
+	filename := strings.TrimSuffix(service.InvoicePDFFilename(invoiceEntry), ".pdf") + ".xml"
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", xmlData)
+}
+
+// Gửi phát hành hóa đơn điện tử qua nhà cung cấp đã cấu hình (ban tổ chức sự kiện hoặc admin)
+func SubmitEInvoice(c *gin.Context) {
+	invoiceEntry, ok := loadInvoiceForAccount(c, true)
+	if !ok {
+		return
+	}
+
+	if err := service.SubmitEInvoice(c.Request.Context(), invoiceEntry); err != nil {
+		utils.ResponseError(c, eInvoiceErrorStatus(err), "", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "Đã gửi phát hành hóa đơn điện tử!", invoiceEntry, nil)
+}
+
+// Lấy hóa đơn theo :id và kiểm tra quyền: ban tổ chức sự kiện/admin, người mua nếu staffOnly = false
+func loadInvoiceForAccount(c *gin.Context, staffOnly bool) (*collections.Invoice, bool) {
+	var (
+		invoiceEntry = &collections.Invoice{}
+		regisEntry   = &collections.Registration{}
+		eventEntry   = &collections.Event{}
+	)
+	ctx := c.Request.Context()
+
+	invoiceID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Invoice ID không hợp lệ", err.Error())
+		return nil, false
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return nil, false
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return nil, false
+	}
+
+	err = invoiceEntry.First(ctx, bson.M{"_id": invoiceID})
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy hóa đơn")
+		return nil, false
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm hóa đơn", err.Error())
+		return nil, false
+	}
+
+	// Người mua là chủ đơn đăng ký (hóa đơn điều chỉnh do người duyệt hoàn tiền tạo)
+	if !staffOnly {
+		err = regisEntry.First(ctx, bson.M{"_id": invoiceEntry.RegistrationID})
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm đơn đăng ký", err.Error())
+			return nil, false
+		}
+		if err == nil && regisEntry.CreatedBy == accountID {
+			return invoiceEntry, true
+		}
+	}
+
+	err = eventEntry.First(ctx, bson.M{"_id": invoiceEntry.EventDetails.EventID})
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return nil, false
+	}
+	if !utils.CanModifyResource(eventEntry.CreatedBy, accountID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền với hóa đơn này")
+		return nil, false
+	}
+	return invoiceEntry, true
+}
+
+func eInvoiceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, consts.ErrEInvoiceAlreadySubmitted),
+		errors.Is(err, consts.ErrEInvoiceInProgress):
+		return http.StatusConflict
+	case errors.Is(err, consts.ErrEInvoiceOriginalMissing):
+		return http.StatusBadRequest
+	case errors.Is(err, consts.ErrEInvoiceProvider):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/dto"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Đăng ký tham gia bốc thăm một loại vé (gửi lại để đổi số lượng)
+func EnterLottery(c *gin.Context) {
+	var (
+		req             dto.EnterLotteryRequest
+		eventEntry      = &collections.Event{}
+		ticketTypeEntry = &collections.TicketType{}
+	)
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ!", err.Error())
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu đầu vào không hợp lệ!", err.Error())
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	err = eventEntry.First(ctx, utils.GetFilter(bson.M{"_id": eventID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Sự kiện không tồn tại hoặc đã bị xóa.")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+
+	err = ticketTypeEntry.First(ctx, utils.GetFilter(bson.M{
+		"_id":      req.TicketTypeID,
+		"event_id": eventID,
+		"status":   consts.TicketTypeActive,
+	}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Loại vé không tồn tại hoặc không còn mở bán")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm loại vé", err.Error())
+		return
+	}
+
+	entry, err := service.EnterLottery(ctx, eventEntry, *ticketTypeEntry, accountID, req.Quantity, time.Now())
+	if err != nil {
+		utils.ResponseError(c, lotteryErrorStatus(err), "", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusCreated, "Đã đăng ký tham gia bốc thăm!", entry, nil)
+}
+
+// Các lượt bốc thăm của người dùng hiện tại
+func GetMyLotteryEntries(c *gin.Context) {
+	var (
+		lotteryEntry = &collections.LotteryEntry{}
+	)
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"account_id": accountID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	entries, err := lotteryEntry.Find(c.Request.Context(), filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", entries, nil)
+}
+
+// Rút khỏi bốc thăm trước khi hết thời gian nhận đăng ký
+func CancelMyLotteryEntry(c *gin.Context) {
+	entryID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	if err = service.CancelLotteryEntry(c.Request.Context(), entryID, accountID, time.Now()); err != nil {
+		utils.ResponseError(c, lotteryErrorStatus(err), "", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "Đã rút khỏi bốc thăm!", nil, nil)
+}
+
+// Danh sách lượt bốc thăm của sự kiện kèm thông tin người đăng ký (chỉ ban tổ chức)
+func GetEventLotteryEntries(c *gin.Context) {
+	var (
+		eventEntry   = &collections.Event{}
+		lotteryEntry = &collections.LotteryEntry{}
+		accountEntry = &collections.Account{}
+	)
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	err = eventEntry.First(ctx, utils.GetFilter(bson.M{"_id": eventID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy sự kiện")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+	if !utils.CanModifyResource(eventEntry.CreatedBy, accountID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền xem danh sách bốc thăm của sự kiện này")
+		return
+	}
+
+	filter := bson.M{"event_id": eventID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if ticketTypeIDStr := c.Query("ticket_type_id"); ticketTypeIDStr != "" {
+		ticketTypeID, err := primitive.ObjectIDFromHex(ticketTypeIDStr)
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "Ticket type ID không hợp lệ", err.Error())
+			return
+		}
+		filter["ticket_type_id"] = ticketTypeID
+	}
+
+	// Đã bốc thăm thì xếp theo thứ tự trúng, chưa bốc thì theo thời gian đăng ký
+	opts := options.Find().SetSort(bson.D{{Key: "draw_rank", Value: 1}, {Key: "_id", Value: 1}})
+	entries, err := lotteryEntry.Find(ctx, filter, opts)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	accountIDs := make([]primitive.ObjectID, 0, len(entries))
+	for _, entry := range entries {
+		accountIDs = append(accountIDs, entry.AccountID)
+	}
+	accountMap := make(map[primitive.ObjectID]collections.Account)
+	if len(accountIDs) > 0 {
+		accounts, err := accountEntry.Find(bson.M{"_id": bson.M{"$in": accountIDs}})
+		if err != nil {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+			return
+		}
+		for _, account := range accounts {
+			accountMap[account.ID] = account
+		}
+	}
+
+	results := make([]bson.M, 0, len(entries))
+	for _, entry := range entries {
+		results = append(results, bson.M{
+			"entry": entry,
+			"account": bson.M{
+				"id":    entry.AccountID,
+				"name":  accountMap[entry.AccountID].Name,
+				"email": accountMap[entry.AccountID].Email,
+			},
+		})
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", results, nil)
+}
+
+// Seed và mã băm của các lần bốc thăm trong sự kiện (công khai)
+func GetEventLotteryDraws(c *gin.Context) {
+	var (
+		drawEntry = &collections.LotteryDraw{}
+	)
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+		return
+	}
+
+	draws, err := drawEntry.Find(c.Request.Context(), bson.M{"event_id": eventID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", draws, nil)
+}
+
+// Chạy lại lần bốc thăm từ seed đã lưu để đối chiếu kết quả (công khai, không trả thông tin tài khoản)
+func VerifyLotteryDraw(c *gin.Context) {
+	drawID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "ID không hợp lệ", err.Error())
+		return
+	}
+
+	verification, err := service.VerifyLotteryDraw(c.Request.Context(), drawID)
+	if err != nil {
+		utils.ResponseError(c, lotteryErrorStatus(err), "", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", verification, nil)
+}
+
+func lotteryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, consts.ErrLotteryEntryNotFound),
+		errors.Is(err, consts.ErrLotteryDrawNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, consts.ErrLotteryNotEnabled),
+		errors.Is(err, consts.ErrLotteryPaidTicket),
+		errors.Is(err, consts.ErrLotteryQuantity):
+		return http.StatusBadRequest
+	case errors.Is(err, consts.ErrLotteryClosed):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
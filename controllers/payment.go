@@ -0,0 +1,334 @@
+package controllers
+
+import (
+	"EventHunting/collections"
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/dto"
+	"EventHunting/jobs"
+	"EventHunting/payment"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Trang thanh toán giả lập của cổng FAKE (chỉ bật ở môi trường local).
+// ?outcome=success|failure|delay ghi đè payment.fake.outcome, ?delay=<giây> ghi đè payment.fake.delay_seconds
+func FakeCheckout(c *gin.Context) {
+	provider, err := payment.Get(consts.PaymentMethodFake)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, "", err.Error())
+		return
+	}
+
+	orderID, amount, err := payment.VerifyFakeCheckout(c.Request.URL.Query())
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Link thanh toán không hợp lệ!", err.Error())
+		return
+	}
+
+	outcome := strings.ToLower(c.DefaultQuery("outcome", configs.GetFakePaymentOutcome()))
+	switch outcome {
+	case payment.FakeOutcomeSuccess, payment.FakeOutcomeFailure:
+		// Giống cổng thật: IPN cập nhật đơn phía server, trình duyệt chỉ quay về trang kết quả
+		params := payment.SimulateFakeCallback(orderID, amount, outcome == payment.FakeOutcomeSuccess)
+		startedAt := time.Now()
+		result, regisEntry, err := processPaymentNotification(c.Request.Context(), provider, params)
+		recordPaymentEvent(c, provider, consts.PaymentEndpointIPN, params, startedAt, result, regisEntry, err)
+		c.Redirect(http.StatusFound, payment.ReturnUrl(provider.Name())+"?"+params.Encode())
+	case payment.FakeOutcomeDelay:
+		delaySeconds := configs.GetFakePaymentDelaySeconds()
+		if delayStr := c.Query("delay"); delayStr != "" {
+			if parsed, err := strconv.Atoi(delayStr); err == nil && parsed >= 0 {
+				delaySeconds = parsed
+			}
+		}
+
+		// IPN thành công đến muộn, đơn vẫn PENDING trong lúc chờ
+		go func() {
+			time.Sleep(time.Duration(delaySeconds) * time.Second)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			params := payment.SimulateFakeCallback(orderID, amount, true)
+			result, err := provider.VerifyCallback(ctx, params)
+			if err != nil {
+				log.Printf("ERROR: [FAKE] IPN trễ cho đơn %s lỗi chữ ký: %v", orderID, err)
+				return
+			}
+			if _, err := service.SettlePayment(ctx, provider.Name(), result); err != nil {
+				log.Printf("ERROR: [FAKE] IPN trễ cho đơn %s thất bại: %v", orderID, err)
+				return
+			}
+			log.Printf("INFO: [FAKE] IPN trễ %ds: Xử lý thành công đơn hàng %s", delaySeconds, orderID)
+		}()
+
+		utils.ResponseSuccess(c, http.StatusAccepted, "Giao dịch đang xử lý", gin.H{
+			"order_id":      orderID,
+			"amount":        amount,
+			"delay_seconds": delaySeconds,
+		}, nil)
+	default:
+		utils.ResponseError(c, http.StatusBadRequest, "", "outcome phải là success, failure hoặc delay")
+	}
+}
+
+// Danh sách sai lệch thanh toán (chỉ admin), lọc theo ?status=open|resolved, ?type=, ?provider=
+func GetPaymentDiscrepancies(c *gin.Context) {
+	var (
+		discrepancyEntry = &collections.PaymentDiscrepancy{}
+	)
+	ctx := c.Request.Context()
+
+	if !requireAdmin(c) {
+		return
+	}
+
+	filter := bson.M{}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if discrepancyType := c.Query("type"); discrepancyType != "" {
+		filter["type"] = discrepancyType
+	}
+	if provider := c.Query("provider"); provider != "" {
+		filter["provider"] = strings.ToUpper(provider)
+	}
+
+	pagination := dto.GetPagination(c, "primary")
+	skip := (pagination.Page - 1) * pagination.Length
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	opts.SetSkip(int64(skip))
+	opts.SetLimit(int64(pagination.Length))
+
+	totalDocs, err := discrepancyEntry.CountDocuments(ctx, filter)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	pagination.TotalDocs = int(totalDocs)
+	pagination.BuildPagination()
+
+	discrepancies, err := discrepancyEntry.Find(ctx, filter, opts)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", discrepancies, &pagination)
+}
+
+// Đánh dấu sai lệch thanh toán đã được xử lý (chỉ admin)
+func ResolvePaymentDiscrepancy(c *gin.Context) {
+	var (
+		discrepancyEntry = &collections.PaymentDiscrepancy{}
+		req              dto.ResolvePaymentDiscrepancyRequest
+	)
+	ctx := c.Request.Context()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Lỗi do bind dữ liệu", err.Error())
+		return
+	}
+	if validateErrs := utils.ValidateResolvePaymentDiscrepancy(req); len(validateErrs) > 0 {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu không hợp lệ", strings.Join(validateErrs, ", "))
+		return
+	}
+
+	discrepancyID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "ID không hợp lệ", err.Error())
+		return
+	}
+	if !requireAdmin(c) {
+		return
+	}
+	adminID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	err = discrepancyEntry.Update(ctx,
+		bson.M{"_id": discrepancyID, "status": consts.DiscrepancyStatusOpen},
+		bson.M{"$set": bson.M{
+			"status":      consts.DiscrepancyStatusResolved,
+			"note":        strings.TrimSpace(req.Note),
+			"resolved_by": adminID,
+			"resolved_at": now,
+			"updated_at":  now,
+		}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy sai lệch đang mở")
+		return
+	}
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	if err = discrepancyEntry.First(ctx, bson.M{"_id": discrepancyID}); err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "Đã xử lý sai lệch thanh toán!", discrepancyEntry, nil)
+}
+
+// Độ trễ của outbox sau thanh toán (chỉ admin): tồn đọng, message đang lỗi, độ trễ đẩy sang queue
+func GetOutboxMetrics(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+
+	metrics, err := jobs.GetOutboxMetrics(c.Request.Context())
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", metrics, nil)
+}
+
+// Tra cứu sổ callback thanh toán (chỉ admin). Lọc theo provider, endpoint, outcome, order_id, registration_id,
+// transaction_no, signature_valid và khoảng thời gian nhận from/to (RFC3339)
+func GetPaymentEvents(c *gin.Context) {
+	var (
+		eventEntry = &collections.PaymentEvent{}
+	)
+	ctx := c.Request.Context()
+
+	if !requireAdmin(c) {
+		return
+	}
+
+	filter := bson.M{}
+	if provider := c.Query("provider"); provider != "" {
+		filter["provider"] = strings.ToUpper(provider)
+	}
+	for _, key := range []string{"endpoint", "outcome", "order_id", "transaction_no"} {
+		if value := c.Query(key); value != "" {
+			filter[key] = value
+		}
+	}
+	if regisIDStr := c.Query("registration_id"); regisIDStr != "" {
+		regisID, err := primitive.ObjectIDFromHex(regisIDStr)
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "registration_id không hợp lệ", err.Error())
+			return
+		}
+		filter["registration_id"] = regisID
+	}
+	if signatureValid := c.Query("signature_valid"); signatureValid != "" {
+		valid, err := strconv.ParseBool(signatureValid)
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "signature_valid phải là true hoặc false", err.Error())
+			return
+		}
+		filter["signature_valid"] = valid
+	}
+
+	receivedAt := bson.M{}
+	for key, operator := range map[string]string{"from": "$gte", "to": "$lte"} {
+		value := c.Query(key)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, key+" phải theo định dạng RFC3339", err.Error())
+			return
+		}
+		receivedAt[operator] = parsed
+	}
+	if len(receivedAt) > 0 {
+		filter["received_at"] = receivedAt
+	}
+
+	pagination := dto.GetPagination(c, "secondary")
+	opts := options.Find().
+		SetSort(bson.D{{Key: "received_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((pagination.Page - 1) * pagination.Length)).
+		SetLimit(int64(pagination.Length))
+
+	totalDocs, err := eventEntry.CountDocuments(ctx, filter)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	pagination.TotalDocs = int(totalDocs)
+	pagination.BuildPagination()
+
+	events, err := eventEntry.Find(ctx, filter, opts)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", events, &pagination)
+}
+
+// Toàn bộ lịch sử callback và sai lệch thanh toán của một đơn, theo thứ tự thời gian (chỉ admin, dùng khi xử lý khiếu nại)
+func GetRegistrationPaymentEvents(c *gin.Context) {
+	var (
+		eventEntry       = &collections.PaymentEvent{}
+		discrepancyEntry = &collections.PaymentDiscrepancy{}
+	)
+	ctx := c.Request.Context()
+
+	regisID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Registration ID không hợp lệ", err.Error())
+		return
+	}
+	if !requireAdmin(c) {
+		return
+	}
+
+	// Callback sai chữ ký không khớp được đơn nên tìm thêm theo mã đơn thô
+	events, err := eventEntry.Find(ctx,
+		bson.M{"$or": []bson.M{{"registration_id": regisID}, {"order_id": regisID.Hex()}}},
+		options.Find().SetSort(bson.D{{Key: "received_at", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	discrepancies, err := discrepancyEntry.Find(ctx, bson.M{"registration_id": regisID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, http.StatusOK, "", gin.H{
+		"registration_id": regisID,
+		"events":          events,
+		"discrepancies":   discrepancies,
+	}, nil)
+}
+
+func requireAdmin(c *gin.Context) bool {
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return false
+	}
+	if !slices.Contains(roles, "Admin") {
+		utils.ResponseError(c, http.StatusForbidden, "", "Chỉ admin mới có quyền truy cập")
+		return false
+	}
+	return true
+}
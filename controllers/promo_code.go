@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/dto"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func CreatePromoCode(c *gin.Context) {
+	var (
+		req             dto.CreatePromoCode
+		eventEntry      = &collections.Event{}
+		ticketTypeEntry = &collections.TicketType{}
+		err             error
+	)
+	ctx := c.Request.Context()
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Lỗi do bind dữ liệu", err.Error())
+		return
+	}
+
+	if validateErrs := utils.ValidateCreatePromoCode(req); len(validateErrs) > 0 {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu không hợp lệ", strings.Join(validateErrs, ", "))
+		return
+	}
+
+	creatorID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	eventObjectID, _ := primitive.ObjectIDFromHex(req.EventID)
+	err = eventEntry.First(ctx, utils.GetFilter(bson.M{"_id": eventObjectID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusBadRequest, "", "Sự kiện (Event ID) không tồn tại")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+	if !utils.CanModifyResource(eventEntry.CreatedBy, creatorID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền tạo mã giảm giá cho sự kiện này")
+		return
+	}
+
+	// Loại vé áp dụng phải thuộc sự kiện
+	var ticketTypeIDs []primitive.ObjectID
+	for _, id := range req.TicketTypeIDs {
+		objectID, _ := primitive.ObjectIDFromHex(id)
+		ticketTypeIDs = append(ticketTypeIDs, objectID)
+	}
+	if len(ticketTypeIDs) > 0 {
+		ticketTypes, err := ticketTypeEntry.Find(ctx, bson.M{
+			"_id":      bson.M{"$in": ticketTypeIDs},
+			"event_id": eventObjectID,
+		})
+		if err != nil {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm loại vé", err.Error())
+			return
+		}
+		if len(ticketTypes) != len(ticketTypeIDs) {
+			utils.ResponseError(c, http.StatusBadRequest, "", "Một hoặc nhiều loại vé không thuộc sự kiện này")
+			return
+		}
+	}
+
+	now := time.Now()
+	newPromoCode := &collections.PromoCode{
+		ID:             primitive.NewObjectID(),
+		EventID:        eventObjectID,
+		Code:           service.NormalizePromoCode(req.Code),
+		DiscountType:   req.DiscountType,
+		DiscountValue:  req.DiscountValue,
+		MaxDiscount:    req.MaxDiscount,
+		TicketTypeIDs:  ticketTypeIDs,
+		MaxUses:        req.MaxUses,
+		MaxUsesPerUser: req.MaxUsesPerUser,
+		StartAt:        req.StartAt,
+		EndAt:          req.EndAt,
+		Status:         consts.PromoCodeActive,
+		CreatedAt:      now,
+		CreatedBy:      creatorID,
+		UpdatedAt:      now,
+		UpdatedBy:      creatorID,
+	}
+	if req.Description != nil {
+		newPromoCode.Description = *req.Description
+	}
+	if req.Status != nil {
+		newPromoCode.Status = *req.Status
+	}
+
+	err = newPromoCode.Create(ctx)
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		utils.ResponseError(c, http.StatusConflict, "", "Mã giảm giá này đã tồn tại trong sự kiện")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống", err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, http.StatusCreated, "", newPromoCode, nil)
+}
+
+func UpdatePromoCode(c *gin.Context) {
+	var (
+		req        dto.UpdatePromoCode
+		promoEntry = &collections.PromoCode{}
+		eventEntry = &collections.Event{}
+		err        error
+	)
+	ctx := c.Request.Context()
+
+	promoID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "ID mã giảm giá không hợp lệ", err.Error())
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Lỗi do bind dữ liệu", err.Error())
+		return
+	}
+
+	if validateErrs := utils.ValidateUpdatePromoCode(req); len(validateErrs) > 0 {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu không hợp lệ", strings.Join(validateErrs, ", "))
+		return
+	}
+
+	updaterID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	filter := bson.M{"_id": promoID}
+	err = promoEntry.First(ctx, filter)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy mã giảm giá")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm mã giảm giá", err.Error())
+		return
+	}
+
+	err = eventEntry.First(ctx, bson.M{"_id": promoEntry.EventID})
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy sự kiện")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+	if !utils.CanModifyResource(eventEntry.CreatedBy, updaterID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền cập nhật mã giảm giá này")
+		return
+	}
+
+	// Kiểm tra lại khoảng thời gian khi chỉ đổi một đầu
+	startAt, endAt := promoEntry.StartAt, promoEntry.EndAt
+	if req.StartAt != nil {
+		startAt = req.StartAt
+	}
+	if req.EndAt != nil {
+		endAt = req.EndAt
+	}
+	if startAt != nil && endAt != nil && !endAt.After(*startAt) {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu không hợp lệ", "Thời gian kết thúc phải sau thời gian bắt đầu")
+		return
+	}
+
+	updateFields := bson.M{
+		"updated_at": time.Now(),
+		"updated_by": updaterID,
+	}
+	unsetFields := bson.M{}
+
+	if req.Description != nil {
+		updateFields["description"] = *req.Description
+	}
+	if req.MaxUsesPerUser != nil {
+		updateFields["max_uses_per_user"] = *req.MaxUsesPerUser
+	}
+	if req.StartAt != nil {
+		updateFields["start_at"] = *req.StartAt
+	}
+	if req.EndAt != nil {
+		updateFields["end_at"] = *req.EndAt
+	}
+	if req.Status != nil {
+		updateFields["status"] = *req.Status
+	}
+
+	if req.SetUnlimited != nil && *req.SetUnlimited {
+		unsetFields["max_uses"] = ""
+	} else if req.MaxUses != nil {
+		updateFields["max_uses"] = *req.MaxUses
+	}
+
+	finalUpdateDoc := bson.M{"$set": updateFields}
+	if len(unsetFields) > 0 {
+		finalUpdateDoc["$unset"] = unsetFields
+	}
+
+	err = promoEntry.Update(ctx, filter, finalUpdateDoc)
+	switch {
+	case err == nil:
+		utils.ResponseSuccess(c, http.StatusOK, "", nil, nil)
+	default:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống!", err.Error())
+	}
+}
+
+// Danh sách mã giảm giá của sự kiện (chỉ ban tổ chức)
+func GetEventPromoCodes(c *gin.Context) {
+	var (
+		eventEntry = &collections.Event{}
+		promoEntry = &collections.PromoCode{}
+	)
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	err = eventEntry.First(ctx, utils.GetFilter(bson.M{"_id": eventID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy sự kiện")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+	if !utils.CanModifyResource(eventEntry.CreatedBy, accountID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền xem mã giảm giá của sự kiện này")
+		return
+	}
+
+	filter := bson.M{"event_id": eventID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+
+	promoCodes, err := promoEntry.Find(ctx, filter, opts)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", promoCodes, nil)
+}
+
+func promoCodeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, consts.ErrPromoCodeInvalid):
+		return http.StatusNotFound
+	case errors.Is(err, consts.ErrPromoCodeNotStarted),
+		errors.Is(err, consts.ErrPromoCodeExpired),
+		errors.Is(err, consts.ErrPromoCodeNotApplicable):
+		return http.StatusBadRequest
+	case errors.Is(err, consts.ErrPromoCodeExhausted),
+		errors.Is(err, consts.ErrPromoCodeUserLimit):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/dto"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Yêu cầu hoàn tiền cho đơn đăng ký.
+// Người mua gửi yêu cầu theo chính sách hoàn tiền của sự kiện; ban tổ chức/admin hoàn tiền trực tiếp.
+func CreateRefund(c *gin.Context) {
+	var (
+		req        dto.CreateRefundRequest
+		regisEntry = &collections.Registration{}
+		eventEntry = &collections.Event{}
+	)
+	ctx := c.Request.Context()
+
+	regisID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Registration ID không hợp lệ", err.Error())
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Lỗi do bind dữ liệu", err.Error())
+		return
+	}
+	if validateErrs := utils.ValidateCreateRefund(req); len(validateErrs) > 0 {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu không hợp lệ", strings.Join(validateErrs, ", "))
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	err = regisEntry.First(ctx, utils.GetFilter(bson.M{"_id": regisID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy đơn đăng ký")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm đơn đăng ký", err.Error())
+		return
+	}
+
+	err = eventEntry.First(ctx, bson.M{"_id": regisEntry.EventID})
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+
+	byStaff := utils.CanModifyResource(eventEntry.CreatedBy, accountID, roles)
+	if !byStaff && regisEntry.CreatedBy != accountID {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền hoàn tiền đơn đăng ký này")
+		return
+	}
+	// Người mua đồng thời là ban tổ chức vẫn đi theo luồng của người mua
+	if regisEntry.CreatedBy == accountID {
+		byStaff = false
+	}
+
+	refund, err := service.RequestRefund(ctx, regisEntry, eventEntry, req.TicketIDs, strings.TrimSpace(req.Reason), accountID, byStaff)
+	if err != nil {
+		utils.ResponseError(c, refundErrorStatus(err), "", err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, http.StatusCreated, "Tạo yêu cầu hoàn tiền thành công!", refund, nil)
+}
+
+// Ban tổ chức/admin duyệt yêu cầu hoàn tiền
+func ApproveRefund(c *gin.Context) {
+	refundEntry, reviewerID, ok := loadRefundForReview(c)
+	if !ok {
+		return
+	}
+
+	if err := service.ApproveRefund(c.Request.Context(), refundEntry, reviewerID); err != nil {
+		utils.ResponseError(c, refundErrorStatus(err), "", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "Hoàn tiền thành công!", refundEntry, nil)
+}
+
+// Ban tổ chức/admin từ chối yêu cầu hoàn tiền
+func RejectRefund(c *gin.Context) {
+	var req dto.RejectRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Lỗi do bind dữ liệu", err.Error())
+		return
+	}
+	if validateErrs := utils.ValidateRejectRefund(req); len(validateErrs) > 0 {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu không hợp lệ", strings.Join(validateErrs, ", "))
+		return
+	}
+
+	refundEntry, reviewerID, ok := loadRefundForReview(c)
+	if !ok {
+		return
+	}
+
+	if err := service.RejectRefund(c.Request.Context(), refundEntry, reviewerID, strings.TrimSpace(req.Reason)); err != nil {
+		utils.ResponseError(c, refundErrorStatus(err), "", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "Đã từ chối yêu cầu hoàn tiền!", refundEntry, nil)
+}
+
+// Danh sách yêu cầu hoàn tiền của người dùng hiện tại
+func GetMyRefunds(c *gin.Context) {
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"owner_id": accountID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	listRefunds(c, filter)
+}
+
+// Danh sách yêu cầu hoàn tiền của một sự kiện (ban tổ chức/admin)
+func GetEventRefunds(c *gin.Context) {
+	var (
+		eventEntry = &collections.Event{}
+	)
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	err = eventEntry.First(ctx, utils.GetFilter(bson.M{"_id": eventID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy sự kiện")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+	if !utils.CanModifyResource(eventEntry.CreatedBy, accountID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền xem yêu cầu hoàn tiền của sự kiện này")
+		return
+	}
+
+	filter := bson.M{"event_id": eventID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	listRefunds(c, filter)
+}
+
+func listRefunds(c *gin.Context, filter bson.M) {
+	var (
+		refundEntry = &collections.Refund{}
+	)
+	ctx := c.Request.Context()
+
+	pagination := dto.GetPagination(c, "primary")
+	skip := (pagination.Page - 1) * pagination.Length
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	opts.SetSkip(int64(skip))
+	opts.SetLimit(int64(pagination.Length))
+
+	totalDocs, err := refundEntry.CountDocuments(ctx, filter)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	pagination.TotalDocs = int(totalDocs)
+	pagination.BuildPagination()
+
+	refunds, err := refundEntry.Find(ctx, filter, opts)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", refunds, &pagination)
+}
+
+// Tìm yêu cầu hoàn tiền và kiểm tra người duyệt là ban tổ chức (hoặc admin) của sự kiện
+func loadRefundForReview(c *gin.Context) (*collections.Refund, primitive.ObjectID, bool) {
+	var (
+		refundEntry = &collections.Refund{}
+		eventEntry  = &collections.Event{}
+	)
+	ctx := c.Request.Context()
+
+	refundID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Refund ID không hợp lệ", err.Error())
+		return nil, primitive.NilObjectID, false
+	}
+
+	reviewerID, ok := utils.GetAccountID(c)
+	if !ok {
+		return nil, primitive.NilObjectID, false
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return nil, primitive.NilObjectID, false
+	}
+
+	err = refundEntry.First(ctx, bson.M{"_id": refundID})
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", consts.ErrRefundNotFound.Error())
+		return nil, primitive.NilObjectID, false
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm yêu cầu hoàn tiền", err.Error())
+		return nil, primitive.NilObjectID, false
+	}
+
+	err = eventEntry.First(ctx, bson.M{"_id": refundEntry.EventID})
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return nil, primitive.NilObjectID, false
+	}
+	if !utils.CanModifyResource(eventEntry.CreatedBy, reviewerID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền duyệt yêu cầu hoàn tiền này")
+		return nil, primitive.NilObjectID, false
+	}
+
+	return refundEntry, reviewerID, true
+}
+
+func refundErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, consts.ErrRefundNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, consts.ErrRefundNotAllowed),
+		errors.Is(err, consts.ErrRefundDeadlinePassed),
+		errors.Is(err, consts.ErrRefundNotPaid),
+		errors.Is(err, consts.ErrRefundNoTickets):
+		return http.StatusBadRequest
+	case errors.Is(err, consts.ErrRefundInProgress),
+		errors.Is(err, consts.ErrRefundInvalidState),
+		errors.Is(err, consts.ErrRefundTicketsChanged):
+		return http.StatusConflict
+	case errors.Is(err, consts.ErrRefundProvider):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
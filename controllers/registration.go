@@ -2,20 +2,27 @@ package controllers
 
 import (
 	"EventHunting/collections"
+	"EventHunting/configs"
 	"EventHunting/consts"
 	"EventHunting/database"
 	"EventHunting/dto"
-	"EventHunting/jobs"
+	"EventHunting/payment"
 	"EventHunting/service"
 	"EventHunting/utils"
+	"EventHunting/view"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/mail"
 	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"net/http"
 
@@ -23,151 +30,319 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type VNPAYIPNResponse struct {
-	RspCode string `json:"RspCode"`
-	Message string `json:"Message"`
-}
+// IPN server-to-server của VNPAY (khai báo IPN URL trên cổng merchant), phản hồi đúng bảng RspCode
+func HandleIPNVNPAY(c *gin.Context) {
+	provider, err := payment.Get(consts.PaymentMethodVNPAY)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, "", err.Error())
+		return
+	}
 
-// Xử lý call back url thanh toán
-func HandleCallbackVNPAY(c *gin.Context) {
-	vnpParams := c.Request.URL.Query()
+	startedAt := time.Now()
+	params := c.Request.URL.Query()
+	result, regisEntry, err := processPaymentNotification(c.Request.Context(), provider, params)
+	recordPaymentEvent(c, provider, consts.PaymentEndpointIPN, params, startedAt, result, regisEntry, err)
 
-	// Verify Checksum
-	if err := utils.VerifyIPNChecksum(vnpParams); err != nil {
-		log.Printf("ERROR: VNPAY IPN Checksum thất bại: %v", err)
-		utils.ResponseError(c, http.StatusBadRequest, "Lỗi sai chữ ký!", err.Error())
+	// Cổng không có định dạng phản hồi IPN riêng thì trả JSON chung
+	responder, ok := provider.(payment.IPNResponder)
+	if !ok {
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "", err.Error())
+			return
+		}
+		utils.ResponseSuccess(c, http.StatusOK, "Confirm", nil, nil)
 		return
 	}
+	status, body := responder.IPNResponse(err)
+	c.JSON(status, body)
+}
 
-	vnp_ResponseCode := vnpParams.Get("vnp_ResponseCode")
-	vnp_TxnRef := vnpParams.Get("vnp_TxnRef")
-	vnp_Amount_str := vnpParams.Get("vnp_Amount")
-	vnp_TxnRefObjectID, _ := primitive.ObjectIDFromHex(vnp_TxnRef)
-	vnp_TransactionNo := vnpParams.Get("vnp_TransactionNo")
-
-	// Kiểm tra mã lỗi từ VNPAY
-	if vnp_ResponseCode != "00" {
-		log.Printf("WARN: VNPAY IPN: Giao dịch %s thất bại. Code: %s", vnp_TxnRef, vnp_ResponseCode)
-		utils.ResponseSuccess(c, http.StatusOK, "Payment Failed confirmed", VNPAYIPNResponse{
-			RspCode: "00",
-			Message: "Confirm",
-		}, nil)
+// Trình duyệt quay về sau khi thanh toán VNPAY: chỉ hiển thị kết quả, trạng thái đơn do IPN cập nhật
+func HandleReturnVNPAY(c *gin.Context) {
+	provider, err := payment.Get(consts.PaymentMethodVNPAY)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, "", err.Error())
 		return
 	}
 
-	// Tìm Registration trong DB
-	regisEntry := &collections.Registration{}
-	// Nếu đã PAID rồi thì trả về thành công luôn
-	checkPaidErr := regisEntry.First(nil, bson.M{"_id": vnp_TxnRefObjectID, "status": consts.RegistrationPaid})
-	if checkPaidErr == nil {
-		utils.ResponseSuccess(c, http.StatusOK, "Order already confirmed", VNPAYIPNResponse{
-			RspCode: "00",
-			Message: "Confirm",
-		}, nil)
+	data := view.PaymentResultData{
+		OrderID:      c.Query("vnp_TxnRef"),
+		ResponseCode: c.Query("vnp_ResponseCode"),
+		Message:      "Dữ liệu thanh toán không hợp lệ.",
+	}
+	startedAt := time.Now()
+	params := c.Request.URL.Query()
+	result, err := provider.VerifyCallback(c.Request.Context(), params)
+	if err != nil {
+		log.Printf("WARN: VNPAY return: Sai chữ ký cho đơn %s: %v", data.OrderID, err)
+	} else {
+		data.Success = result.Success
+		data.Amount = result.Amount
+		data.Message = payment.ResponsePaymentMessage(result.ResponseCode)
+	}
+	recordPaymentEvent(c, provider, consts.PaymentEndpointReturn, params, startedAt, result, nil, err)
+	renderPaymentResult(c, data)
+}
+
+// Callback/IPN chung cho các cổng thanh toán: /payments/:provider/callback
+func HandlePaymentCallback(c *gin.Context) {
+	// Link cũ còn trỏ trình duyệt về callback: chỉ hiển thị kết quả, không cập nhật đơn
+	if c.Request.Method == http.MethodGet {
+		handlePaymentReturn(c, c.Param("provider"))
 		return
 	}
+	handlePaymentCallback(c, c.Param("provider"))
+}
 
-	// Tìm đơn đang PENDING
-	regisFilter := bson.M{
-		"_id":    vnp_TxnRefObjectID,
-		"status": consts.RegistrationPending,
+// Trình duyệt quay về sau khi thanh toán: /payments/:provider/return, trạng thái đơn do IPN/callback cập nhật
+func HandlePaymentReturn(c *gin.Context) {
+	handlePaymentReturn(c, c.Param("provider"))
+}
+
+func handlePaymentReturn(c *gin.Context, providerName string) {
+	provider, err := payment.Get(providerName)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, "", err.Error())
+		return
 	}
-	err := regisEntry.First(nil, utils.GetFilter(regisFilter))
 
+	startedAt := time.Now()
+	params := c.Request.URL.Query()
+	data := view.PaymentResultData{
+		OrderID: rawOrderID(params),
+		Message: "Dữ liệu thanh toán không hợp lệ.",
+	}
+	result, err := provider.VerifyCallback(c.Request.Context(), params)
 	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			log.Printf("ERROR: VNPAY IPN: Không tìm thấy TxnRef %s (Pending) trong DB", vnp_TxnRef)
-			utils.ResponseError(c, http.StatusBadRequest, "Order not found", err.Error())
-			return
+		log.Printf("WARN: %s return: Sai chữ ký cho đơn %s: %v", provider.Name(), data.OrderID, err)
+	} else {
+		data.OrderID = result.OrderID
+		data.Success = result.Success
+		data.Amount = result.Amount
+		data.ResponseCode = result.ResponseCode
+		data.Message = result.Message
+		if data.Message == "" {
+			data.Message = payment.ResponsePaymentMessage(result.ResponseCode)
 		}
-		log.Printf("ERROR: VNPAY IPN: Lỗi DB khi tìm TxnRef %s: %v", vnp_TxnRef, err)
-		utils.ResponseError(c, http.StatusInternalServerError, "System Error", err.Error())
-		return
 	}
+	recordPaymentEvent(c, provider, consts.PaymentEndpointReturn, params, startedAt, result, nil, err)
+	renderPaymentResult(c, data)
+}
 
-	// Kiểm tra số tiền
-	vnp_Amount, _ := strconv.ParseInt(vnp_Amount_str, 10, 64)
-	if vnp_Amount != int64(regisEntry.TotalPrice*100) {
-		log.Printf("ERROR: VNPAY IPN: Sai số tiền. TxnRef %s. VNPAY: %d, DB: %d", vnp_TxnRef, vnp_Amount, (regisEntry.TotalPrice * 100))
-		utils.ResponseError(c, http.StatusBadRequest, "Invalid Amount", nil)
+func handlePaymentCallback(c *gin.Context, providerName string) {
+	provider, err := payment.Get(providerName)
+	if err != nil {
+		utils.ResponseError(c, http.StatusNotFound, "", err.Error())
+		return
+	}
+	// VNPAY chỉ nhận IPN qua /vnpay_ipn, trình duyệt quay về /vnpay_return
+	if provider.Name() == consts.PaymentMethodVNPAY {
+		utils.ResponseError(c, http.StatusNotFound, "", "VNPAY dùng /vnpay_ipn và /vnpay_return")
 		return
 	}
 
-	//Tạo hóa đơn
-	newInvoice, err := service.CreateInvoiceForRegistration(vnp_TxnRefObjectID, vnp_TransactionNo, time.Now())
+	params, err := callbackParams(c)
 	if err != nil {
-		log.Printf("ERROR: Lỗi prepare invoice data: %v", err)
-		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi dữ liệu", err.Error())
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu callback không hợp lệ!", err.Error())
 		return
 	}
 
-	client := database.GetDB().Client()
-	session, err := client.StartSession()
-	if err != nil {
-		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi kết nối DB Session", err.Error())
+	startedAt := time.Now()
+	result, regisEntry, err := processPaymentNotification(c.Request.Context(), provider, params)
+	recordPaymentEvent(c, provider, consts.PaymentEndpointCallback, params, startedAt, result, regisEntry, err)
+	if respondIPN(c, provider, err) {
 		return
 	}
-	defer session.EndSession(context.Background())
 
-	callback := func(sessCtx mongo.SessionContext) (interface{}, error) {
-		if err := newInvoice.Create(sessCtx); err != nil {
-			return nil, err
-		}
+	switch {
+	case result == nil:
+		utils.ResponseError(c, http.StatusBadRequest, "Lỗi sai chữ ký!", err.Error())
+	case errors.Is(err, consts.ErrPaymentAlreadyConfirmed):
+		// Nếu đã PAID rồi thì trả về thành công luôn
+		utils.ResponseSuccess(c, http.StatusOK, "Order already confirmed", payment.VnpayIPNResponse{
+			RspCode: "00",
+			Message: "Confirm",
+		}, nil)
+	case errors.Is(err, consts.ErrRegistrationNotPending):
+		utils.ResponseError(c, http.StatusBadRequest, "Order cancelled", err.Error())
+	case errors.Is(err, consts.ErrPaymentOrderNotFound):
+		utils.ResponseError(c, http.StatusBadRequest, "Order not found", err.Error())
+	case errors.Is(err, consts.ErrPaymentInvalidAmount):
+		utils.ResponseError(c, http.StatusBadRequest, "Invalid Amount", nil)
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Giao dịch thất bại", err.Error())
+	case !result.Success:
+		utils.ResponseSuccess(c, http.StatusOK, "Payment Failed confirmed", payment.VnpayIPNResponse{
+			RspCode: "00",
+			Message: "Confirm",
+		}, nil)
+	default:
+		//Phản hồi thành công cho cổng thanh toán
+		utils.ResponseSuccess(c, http.StatusOK, "", payment.VnpayIPNResponse{
+			RspCode: "00",
+			Message: "Confirm",
+		}, nil)
+	}
+}
 
-		regisCollection := database.GetDB().Collection("registrations")
-		update := bson.M{
-			"$set": bson.M{
-				"status":                   consts.RegistrationPaid,
-				"invoice_id":               newInvoice.ID,
-				"updated_at":               time.Now(),
-				"payment_transaction_code": vnp_TransactionNo,
-			},
-		}
-		_, err := regisCollection.UpdateOne(sessCtx, bson.M{"_id": vnp_TxnRefObjectID}, update)
-		if err != nil {
-			return nil, err
-		}
+// Xác thực và xử lý thông báo thanh toán, trả lỗi để từng endpoint phản hồi theo định dạng của cổng.
+// result = nil nghĩa là sai chữ ký, regisEntry = nil nghĩa là không khớp được đơn.
+func processPaymentNotification(ctx context.Context, provider payment.Provider, params url.Values) (*payment.CallbackResult, *collections.Registration, error) {
+	// Verify Checksum
+	result, err := provider.VerifyCallback(ctx, params)
+	if err != nil {
+		log.Printf("ERROR: %s IPN Checksum thất bại: %v", provider.Name(), err)
+		return nil, nil, err
+	}
+
+	// Giao dịch thất bại: chỉ kiểm tra đơn, số tiền và trạng thái, đơn vẫn PENDING đến khi hết hạn
+	if !result.Success {
+		log.Printf("WARN: %s IPN: Giao dịch %s thất bại. Code: %s", provider.Name(), result.OrderID, result.ResponseCode)
+		regisEntry, err := service.VerifyPaymentOrder(ctx, provider.Name(), result)
+		return result, matchedRegistration(regisEntry, err), err
+	}
 
-		return nil, nil
+	regisEntry, err := service.SettlePayment(ctx, provider.Name(), result)
+	switch {
+	case err == nil:
+		log.Printf("INFO: %s IPN: Xử lý thành công đơn hàng %s", provider.Name(), result.OrderID)
+	case errors.Is(err, consts.ErrPaymentOrderNotFound):
+		log.Printf("ERROR: %s IPN: Không tìm thấy TxnRef %s (Pending) trong DB", provider.Name(), result.OrderID)
+	case errors.Is(err, consts.ErrPaymentAlreadyConfirmed),
+		errors.Is(err, consts.ErrRegistrationNotPending),
+		errors.Is(err, consts.ErrPaymentInvalidAmount):
+		// SettlePayment đã ghi log
+	default:
+		log.Printf("CRITICAL: Transaction thất bại cho đơn %s: %v", result.OrderID, err)
 	}
+	return result, matchedRegistration(regisEntry, err), err
+}
+
+// Đơn khớp cổng sai (ErrPaymentOrderNotFound) không tính là khớp
+func matchedRegistration(regisEntry *collections.Registration, err error) *collections.Registration {
+	if errors.Is(err, consts.ErrPaymentOrderNotFound) {
+		return nil
+	}
+	return regisEntry
+}
 
-	_, err = session.WithTransaction(context.Background(), callback)
+// Lưu callback vào sổ payment_events (chạy nền để không làm chậm phản hồi cho cổng)
+func recordPaymentEvent(c *gin.Context, provider payment.Provider, endpoint string, params url.Values, startedAt time.Time, result *payment.CallbackResult, regisEntry *collections.Registration, err error) {
+	event := &collections.PaymentEvent{
+		ID:             primitive.NewObjectID(),
+		Provider:       provider.Name(),
+		Endpoint:       endpoint,
+		HTTPMethod:     c.Request.Method,
+		ClientIP:       c.ClientIP(),
+		RawParams:      params,
+		SignatureValid: result != nil,
+		Outcome:        service.PaymentEventOutcomeOf(result, err),
+		LatencyMs:      time.Since(startedAt).Milliseconds(),
+		ReceivedAt:     startedAt,
+	}
+	if endpoint == consts.PaymentEndpointReturn && result != nil {
+		event.Outcome = consts.PaymentEventDisplayed
+	}
 	if err != nil {
-		log.Printf("CRITICAL: Transaction thất bại cho đơn %s: %v", vnp_TxnRef, err)
-		utils.ResponseError(c, http.StatusInternalServerError, "Giao dịch thất bại", err.Error())
-		return
+		event.Error = err.Error()
+	}
+	if result != nil {
+		event.OrderID = result.OrderID
+		event.TransactionNo = result.TransactionNo
+		event.Amount = result.Amount
+		event.ResponseCode = result.ResponseCode
+	} else {
+		// Chữ ký sai: vẫn lưu mã đơn thô để tra cứu khi có khiếu nại
+		event.OrderID = rawOrderID(params)
+	}
+	if regisEntry != nil {
+		event.RegistrationID = regisEntry.ID
 	}
 
-	// ĐẨY JOB VÀO REDIS
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+	go service.RecordPaymentEvent(event)
+}
 
-		jobData := jobs.EmailJob{
-			Type:       "ticket_email",
-			Data:       bson.M{"registration_id": vnp_TxnRef}, // Lưu ID dưới dạng string cho an toàn JSON
-			RetryCount: 0,
+// Mã đơn thô trong tham số của cổng (chưa xác thực chữ ký)
+func rawOrderID(params url.Values) string {
+	for _, key := range []string{"vnp_TxnRef", "orderId", "order_id", "apptransid"} {
+		if orderID := params.Get(key); orderID != "" {
+			return orderID
 		}
+	}
+	return ""
+}
+
+// Chuyển hướng tới trang kết quả của frontend (payment.result_page_url) hoặc tự render nếu chưa cấu hình
+func renderPaymentResult(c *gin.Context, data view.PaymentResultData) {
+	if resultPageUrl := strings.TrimSpace(configs.GetPaymentResultPageUrl()); resultPageUrl != "" {
+		params := url.Values{}
+		params.Set("order_id", data.OrderID)
+		params.Set("success", strconv.FormatBool(data.Success))
+		params.Set("code", data.ResponseCode)
+		params.Set("message", data.Message)
+		c.Redirect(http.StatusFound, resultPageUrl+"?"+params.Encode())
+		return
+	}
+
+	page, err := view.BuildPaymentResultPage(data)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hiển thị kết quả thanh toán", err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
 
-		jobPayload, _ := json.Marshal(jobData)
-		rdb := database.GetRedisClient().Client
+// Gộp tham số query (redirect) và body JSON (IPN dạng POST của MoMo, ZaloPay) thành url.Values
+func callbackParams(c *gin.Context) (url.Values, error) {
+	params := c.Request.URL.Query()
+	if c.Request.Method != http.MethodPost || c.Request.ContentLength == 0 {
+		return params, nil
+	}
 
-		//Đẩy vào cuối hàng đợi
-		if err := rdb.RPush(ctx, consts.QueueNameEmail, jobPayload).Err(); err != nil {
-			log.Printf("CRITICAL: Đơn %s đã PAID nhưng đẩy Redis thất bại: %v", vnp_TxnRef, err)
-		} else {
-			log.Printf("INFO: Đã đẩy job sinh vé cho đơn %s vào queue.", vnp_TxnRef)
+	var body map[string]interface{}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber() // Giữ nguyên số như cổng gửi để tính chữ ký
+	if err := decoder.Decode(&body); err != nil {
+		if errors.Is(err, io.EOF) {
+			return params, nil
+		}
+		return nil, err
+	}
+	for k, v := range body {
+		switch value := v.(type) {
+		case nil:
+			params.Set(k, "")
+		case string:
+			params.Set(k, value)
+		case json.Number:
+			params.Set(k, value.String())
+		case bool:
+			params.Set(k, fmt.Sprintf("%t", value))
+		default:
+			raw, _ := json.Marshal(value)
+			params.Set(k, string(raw))
 		}
-	}()
+	}
+	return params, nil
+}
 
-	//Phản hồi thành công cho VNPAY
-	log.Printf("INFO: VNPAY IPN: Xử lý thành công đơn hàng %s", vnp_TxnRef)
-	utils.ResponseSuccess(c, http.StatusOK, "", VNPAYIPNResponse{
-		RspCode: "00",
-		Message: "Confirm",
-	}, nil)
+// IPN dạng POST của cổng có định dạng phản hồi riêng thì trả theo định dạng đó
+func respondIPN(c *gin.Context, provider payment.Provider, err error) bool {
+	responder, ok := provider.(payment.IPNResponder)
+	if !ok || c.Request.Method != http.MethodPost {
+		return false
+	}
+
+	status, body := responder.IPNResponse(err)
+	if body == nil {
+		c.Status(status)
+		return true
+	}
+	c.JSON(status, body)
+	return true
 }
 
 func RegistrationEvent(c *gin.Context) {
@@ -184,6 +359,18 @@ func RegistrationEvent(c *gin.Context) {
 		return
 	}
 
+	//Chọn cổng thanh toán
+	var provider payment.Provider
+	if req.PaymentMethod != "" {
+		provider, err = payment.Get(req.PaymentMethod)
+	} else {
+		provider, err = payment.Default()
+	}
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Cổng thanh toán không hợp lệ!", err.Error())
+		return
+	}
+
 	//Lấy id người tạo đăng ký
 	creatorID, ok := utils.GetAccountID(c)
 	if !ok {
@@ -191,11 +378,28 @@ func RegistrationEvent(c *gin.Context) {
 		return
 	}
 
+	// Vé đang được giữ cho người dùng từ danh sách chờ (đã tính trong registered_count)
+	waitlistOffers, err := service.FindWaitlistOffers(c.Request.Context(), eventID, creatorID, time.Now())
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi kiểm tra danh sách chờ!", err.Error())
+		return
+	}
+	requestedTypes := make(map[primitive.ObjectID]bool, len(req.Tickets))
+	for _, ticket := range req.Tickets {
+		requestedTypes[ticket.TicketTypeID] = true
+	}
+	for ticketTypeID := range waitlistOffers {
+		if !requestedTypes[ticketTypeID] {
+			delete(waitlistOffers, ticketTypeID)
+		}
+	}
+
 	//Validate đầu vào
-	validationErrors, lstTicketTypes, requestedTicketsMap, totalPrice, totalNewTickets := validateRegistrationRules(
+	validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets := validateRegistrationRules(
 		req,
 		eventID,
 		creatorID,
+		waitlistOffers,
 	)
 
 	if len(validationErrors) > 0 {
@@ -203,27 +407,94 @@ func RegistrationEvent(c *gin.Context) {
 		return
 	}
 
+	// Chốt giá từng dòng vé theo bậc giá tại thời điểm đăng ký
+	registrationTickets := make([]collections.RegistrationTicket, 0, len(req.Tickets))
+	for _, ticket := range req.Tickets {
+		price := priceMap[ticket.TicketTypeID]
+		unitPrice := price.Price
+		line := collections.RegistrationTicket{
+			TicketTypeID: ticket.TicketTypeID,
+			Quantity:     ticket.Quantity,
+			UnitPrice:    &unitPrice,
+			Attendees:    attendeesMap[ticket.TicketTypeID],
+		}
+		if price.Tier != nil {
+			line.PriceTier = price.Tier.Name
+		}
+		registrationTickets = append(registrationTickets, line)
+	}
+
+	// Áp mã giảm giá: kiểm tra trước, giữ lượt trong transaction bên dưới
+	var (
+		promoEntry *collections.PromoCode
+		discount   *collections.RegistrationDiscount
+	)
+	subtotalPrice := totalPrice
+	if strings.TrimSpace(req.PromoCode) != "" {
+		promoEntry, discount, err = service.PreparePromoCode(c.Request.Context(), eventID, req.PromoCode, creatorID, registrationTickets, time.Now())
+		if err != nil {
+			utils.ResponseError(c, promoCodeErrorStatus(err), "Mã giảm giá không hợp lệ!", err.Error())
+			return
+		}
+		totalPrice -= discount.Amount
+	}
+
+	// Đơn 0đ xác nhận ngay, không qua cổng thanh toán
+	isFree := totalPrice == 0
+	if isFree {
+		provider, _ = payment.Get(consts.PaymentMethodFree)
+	} else if provider.Name() == consts.PaymentMethodFree {
+		utils.ResponseError(c, http.StatusBadRequest, "Cổng thanh toán không hợp lệ!", payment.ErrUnknownProvider.Error())
+		return
+	}
+
+	// Loại vé bán số lượng lớn: giữ vé trên Redis trước để phần lớn yêu cầu khi hết vé không phải mở transaction
+	var inventoryItems []service.InventoryItem
+	for _, tickType := range lstTicketTypes {
+		requestedQty := requestedTicketsMap[tickType.ID] - waitlistOffers[tickType.ID].OfferedQuantity
+		if tickType.UsesInventoryCounter() && requestedQty > 0 {
+			inventoryItems = append(inventoryItems, service.InventoryItem{TicketType: tickType, Quantity: requestedQty})
+		}
+	}
+	if len(inventoryItems) > 0 {
+		err = service.ReserveInventory(c.Request.Context(), inventoryItems)
+		switch {
+		case errors.Is(err, consts.ErrInventorySoldOut):
+			utils.ResponseError(c, http.StatusConflict, "Vé đã bán hết!", err.Error())
+			return
+		case err != nil:
+			utils.ResponseError(c, http.StatusServiceUnavailable, "Hệ thống đang quá tải, vui lòng thử lại!", err.Error())
+			return
+		}
+	}
+
 	client := database.GetDB().Client()
 	session, err := client.StartSession()
 	if err != nil {
+		service.ReleaseInventory(c.Request.Context(), inventoryItems)
 		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống (session)!", err.Error())
 		return
 	}
 	defer session.EndSession(c.Request.Context())
 
+	// Vé giữ từ danh sách chờ nhưng không mua hết, còn trống sau khi chuyển cho người kế tiếp
+	var released map[primitive.ObjectID]int
+
 	result, err := session.WithTransaction(c.Request.Context(),
 		func(sessionContext mongo.SessionContext) (interface{}, error) {
 
 			var newRegistration collections.Registration
 			var err error
 			var ticketTypeEntry = &collections.TicketType{}
+			released = make(map[primitive.ObjectID]int)
 
 			for _, tickType := range lstTicketTypes {
-				requestedQty := requestedTicketsMap[tickType.ID]
+				// Phần vé đã giữ từ danh sách chờ không cần trừ kho lần nữa
+				requestedQty := requestedTicketsMap[tickType.ID] - waitlistOffers[tickType.ID].OfferedQuantity
 
 				var ticketTypeFilter bson.M
 				//Logic xử lý nếu số lượng có giới hạn/ ko giới hạn
-				if tickType.Quantity == nil {
+				if tickType.Quantity == nil || requestedQty <= 0 {
 					ticketTypeFilter = bson.M{
 						"_id": tickType.ID,
 					}
@@ -233,6 +504,12 @@ func RegistrationEvent(c *gin.Context) {
 						"registered_count": bson.M{"$lte": *tickType.Quantity - requestedQty},
 					}
 				}
+				// Bậc giá theo số lượng: chỉ giữ giá đã chốt khi bậc còn đủ vé
+				if tier := priceMap[tickType.ID].Tier; tier != nil && tier.MaxSold != nil {
+					ticketTypeFilter["$and"] = bson.A{
+						bson.M{"registered_count": bson.M{"$lte": *tier.MaxSold - requestedQty}},
+					}
+				}
 				ticketTypeFilter = utils.GetFilter(ticketTypeFilter)
 				ticketTypeUpdate := bson.M{
 					"$inc": bson.M{"registered_count": requestedQty},
@@ -241,7 +518,7 @@ func RegistrationEvent(c *gin.Context) {
 				err = ticketTypeEntry.Update(sessionContext, ticketTypeFilter, ticketTypeUpdate)
 				if err != nil {
 					if errors.Is(err, mongo.ErrNoDocuments) {
-						return nil, fmt.Errorf("Vé '%s' đã hết trong lúc bạn thao tác, vui lòng thử lại.", tickType.Name)
+						return nil, fmt.Errorf("Vé '%s' đã hết hoặc đã đổi giá trong lúc bạn thao tác, vui lòng thử lại.", tickType.Name)
 					}
 					return nil, err
 				}
@@ -258,30 +535,75 @@ func RegistrationEvent(c *gin.Context) {
 
 			now := time.Now()
 
+			if promoEntry != nil {
+				if err = service.RedeemPromoCode(sessionContext, promoEntry, creatorID, now); err != nil {
+					return nil, err
+				}
+			}
+
 			newRegistration = collections.Registration{
 				ID:            primitive.NewObjectID(),
 				EventID:       eventID,
-				Tickets:       req.Tickets,
+				Tickets:       registrationTickets,
 				TotalQuantity: totalNewTickets,
+				SubtotalPrice: subtotalPrice,
 				TotalPrice:    totalPrice,
+				Discount:      discount,
 				Status:        consts.RegistrationPending,
+				PaymentMethod: provider.Name(),
 				CreatedBy:     creatorID,
 				UpdatedBy:     creatorID,
 				CreatedAt:     now,
 				UpdatedAt:     now,
 			}
+
+			if isFree {
+				newInvoice, err := service.BuildInvoiceForRegistration(sessionContext, &newRegistration, provider.Name(), "", now)
+				if err != nil {
+					return nil, errors.New("Lỗi hệ thống khi tạo hóa đơn!")
+				}
+				if err = service.CreateInvoice(sessionContext, newInvoice); err != nil {
+					return nil, errors.New("Lỗi hệ thống khi tạo hóa đơn!")
+				}
+				newRegistration.Status = consts.RegistrationPaid
+				newRegistration.InvoiceID = newInvoice.ID
+				newRegistration.PaidAt = &now
+			}
+
 			err = newRegistration.Create(sessionContext)
 			if err != nil {
 				return nil, errors.New("Lỗi hệ thống khi tạo đăng ký!")
 			}
 
+			// Dùng lượt giữ vé; mua ít hơn số vé được giữ thì phần thừa chuyển cho người kế tiếp
+			for ticketTypeID, offer := range waitlistOffers {
+				if err = service.ClaimWaitlistOffer(sessionContext, offer.ID, newRegistration.ID, now); err != nil {
+					return nil, err
+				}
+				if surplus := offer.OfferedQuantity - requestedTicketsMap[ticketTypeID]; surplus > 0 {
+					held, err := service.OfferWaitlist(sessionContext, ticketTypeID, now)
+					if err != nil {
+						return nil, errors.New("Lỗi hệ thống khi cập nhật danh sách chờ!")
+					}
+					released[ticketTypeID] = surplus - held
+				}
+			}
+
+			if isFree {
+				if err = service.CreateTicketEmailOutbox(sessionContext, newRegistration.ID); err != nil {
+					return nil, errors.New("Lỗi hệ thống khi tạo đăng ký!")
+				}
+			}
+
 			return newRegistration, nil
 		})
 
 	if err != nil {
+		service.ReleaseInventory(c.Request.Context(), inventoryItems)
 		utils.ResponseError(c, http.StatusBadRequest, "", err.Error())
 		return
 	}
+	service.AdjustInventory(c.Request.Context(), released)
 
 	newRegistration, ok := result.(collections.Registration)
 	if !ok {
@@ -289,13 +611,39 @@ func RegistrationEvent(c *gin.Context) {
 		return
 	}
 
+	if isFree {
+		utils.ResponseSuccess(c, http.StatusCreated, "Đăng ký thành công!", bson.M{
+			"registration": newRegistration,
+			"url":          "",
+		}, nil)
+		return
+	}
+
 	//Tạo url thanh toán
 	ipAddr := utils.GetClientIpAdrr(c)
 	orderInfo := url.QueryEscape(newRegistration.ID.Hex())
-	urlRe, _ := utils.CreatePaymentURL(newRegistration.ID.Hex(), int64(newRegistration.TotalPrice), orderInfo, ipAddr)
+	paymentRes, err := provider.CreatePayment(c.Request.Context(), payment.CreatePaymentRequest{
+		OrderID:   newRegistration.ID.Hex(),
+		Amount:    int64(newRegistration.TotalPrice),
+		OrderInfo: orderInfo,
+		IPAddr:    ipAddr,
+		CreatedAt: newRegistration.CreatedAt,
+	})
+	if err != nil {
+		log.Printf("ERROR: Tạo link thanh toán %s cho đơn %s thất bại: %v", provider.Name(), newRegistration.ID.Hex(), err)
+		// Không có link thì người mua không thể thanh toán: hủy đơn để trả vé, mã giảm giá và lượt giữ vé
+		cancelCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		cancelErr := service.CancelPendingRegistration(cancelCtx, newRegistration, creatorID, consts.CancelReasonPaymentFailed)
+		cancel()
+		if cancelErr != nil {
+			log.Printf("ERROR: Không hủy được đơn %s sau khi tạo link thanh toán thất bại: %v", newRegistration.ID.Hex(), cancelErr)
+		}
+		utils.ResponseError(c, http.StatusBadGateway, "Không tạo được link thanh toán, vui lòng thử lại!", err.Error())
+		return
+	}
 	utils.ResponseSuccess(c, http.StatusCreated, "Đăng ký thành công!", bson.M{
 		"registration": newRegistration,
-		"url":          urlRe,
+		"url":          paymentRes.PaymentURL,
 	}, nil)
 }
 
@@ -303,10 +651,13 @@ func validateRegistrationRules(
 	req dto.CreateRegistrationEventRequest,
 	eventID primitive.ObjectID,
 	creatorID primitive.ObjectID,
+	waitlistOffers map[primitive.ObjectID]collections.WaitlistEntry,
 ) (
 	[]string,
 	[]collections.TicketType,
 	map[primitive.ObjectID]int,
+	map[primitive.ObjectID]collections.TicketPrice,
+	map[primitive.ObjectID][]collections.TicketAttendee,
 	int,
 	int,
 ) {
@@ -314,12 +665,14 @@ func validateRegistrationRules(
 	var validationErrors []string
 	var lstTicketTypes []collections.TicketType
 	var requestedTicketsMap = make(map[primitive.ObjectID]int)
+	var priceMap = make(map[primitive.ObjectID]collections.TicketPrice)
+	var attendeesMap = make(map[primitive.ObjectID][]collections.TicketAttendee)
 	var totalPrice = 0
 	var totalNewTickets = 0
 
 	if len(req.Tickets) <= 0 {
 		validationErrors = append(validationErrors, "Bạn phải chọn ít nhất 1 vé!")
-		return validationErrors, lstTicketTypes, requestedTicketsMap, totalPrice, totalNewTickets
+		return validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets
 	}
 
 	var eventEntry collections.Event
@@ -330,7 +683,11 @@ func validateRegistrationRules(
 	err := eventEntry.First(nil, utils.GetFilter(eventFilter))
 	if err != nil {
 		validationErrors = append(validationErrors, "Sự kiện không tồn tại hoặc đã bị xóa.")
-		return validationErrors, lstTicketTypes, requestedTicketsMap, totalPrice, totalNewTickets
+		return validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets
+	}
+	if eventEntry.LotteryEnabled() {
+		validationErrors = append(validationErrors, consts.ErrLotteryDirectRegistration.Error())
+		return validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets
 	}
 
 	//Kiểm tra thời hạn đăng ký
@@ -354,11 +711,12 @@ func validateRegistrationRules(
 			timeStr := deadline.Format("15:04 02/01/2006")
 			msg := fmt.Sprintf("Đã hết hạn đăng ký. Sự kiện ngày cuối cùng đã bắt đầu lúc %s.", timeStr)
 			validationErrors = append(validationErrors, msg)
-			return validationErrors, lstTicketTypes, requestedTicketsMap, totalPrice, totalNewTickets
+			return validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets
 		}
 	}
 
 	var ticketTypeIDs []primitive.ObjectID
+	requestedAttendees := make(map[primitive.ObjectID][]dto.AttendeeReq)
 	hasInvalidQuantity := false
 
 	for _, ticket := range req.Tickets {
@@ -368,10 +726,11 @@ func validateRegistrationRules(
 		}
 		ticketTypeIDs = append(ticketTypeIDs, ticket.TicketTypeID)
 		requestedTicketsMap[ticket.TicketTypeID] = ticket.Quantity
+		requestedAttendees[ticket.TicketTypeID] = ticket.Attendees
 	}
 
 	if hasInvalidQuantity {
-		return validationErrors, lstTicketTypes, requestedTicketsMap, totalPrice, totalNewTickets
+		return validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets
 	}
 
 	ticketTypeEntry := collections.TicketType{}
@@ -383,12 +742,12 @@ func validateRegistrationRules(
 
 	if err != nil {
 		validationErrors = append(validationErrors, "Lỗi khi tìm thông tin vé!")
-		return validationErrors, lstTicketTypes, requestedTicketsMap, totalPrice, totalNewTickets
+		return validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets
 	}
 
 	if len(lstTicketTypes) != len(requestedTicketsMap) {
 		validationErrors = append(validationErrors, "Một hoặc nhiều loại vé không hợp lệ hoặc không thuộc sự kiện này!")
-		return validationErrors, lstTicketTypes, requestedTicketsMap, totalPrice, totalNewTickets
+		return validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets
 	}
 
 	ownedTicketTypeMap := make(map[primitive.ObjectID]int)
@@ -408,7 +767,7 @@ func validateRegistrationRules(
 
 	if err != nil {
 		validationErrors = append(validationErrors, "Lỗi khi kiểm tra vé đã đăng ký!")
-		return validationErrors, lstTicketTypes, requestedTicketsMap, totalPrice, totalNewTickets
+		return validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets
 	}
 
 	for _, reg := range existingRegs {
@@ -423,7 +782,23 @@ func validateRegistrationRules(
 
 	for _, tickType := range lstTicketTypes {
 		requestedQty := requestedTicketsMap[tickType.ID]
-		isFreeTicket := (tickType.Price == 0)
+
+		switch tickType.SaleStatus(now) {
+		case consts.TicketSaleUpcoming:
+			validationErrors = append(validationErrors, fmt.Sprintf("Vé '%s' chưa mở bán, mở bán lúc %s.", tickType.Name, tickType.SaleStartAt.In(loc).Format("15:04 02/01/2006")))
+			continue
+		case consts.TicketSaleEnded:
+			validationErrors = append(validationErrors, fmt.Sprintf("Vé '%s' đã ngừng bán.", tickType.Name))
+			continue
+		}
+
+		attendees, attendeeErrs := buildTicketAttendees(eventEntry.RegistrationForm, tickType, requestedQty, requestedAttendees[tickType.ID])
+		validationErrors = append(validationErrors, attendeeErrs...)
+		attendeesMap[tickType.ID] = attendees
+
+		price := tickType.EffectivePrice(now)
+		priceMap[tickType.ID] = price
+		isFreeTicket := (price.Price == 0)
 
 		if isFreeTicket {
 			if requestedQty > 1 {
@@ -435,18 +810,268 @@ func validateRegistrationRules(
 		}
 
 		if tickType.Quantity != nil {
-			if stockLeft := *tickType.Quantity - tickType.RegisteredCount; stockLeft < 0 || stockLeft < requestedQty {
-				validationErrors = append(validationErrors, fmt.Sprintf("Vé '%s' chỉ còn %d vé (bạn yêu cầu %d)", tickType.Name, stockLeft, requestedQty))
+			heldQty := waitlistOffers[tickType.ID].OfferedQuantity
+			if stockLeft := *tickType.Quantity - tickType.RegisteredCount + heldQty; stockLeft < 0 || stockLeft < requestedQty {
+				validationErrors = append(validationErrors, fmt.Sprintf("Vé '%s' chỉ còn %d vé (bạn yêu cầu %d), bạn có thể đăng ký vào danh sách chờ", tickType.Name, stockLeft, requestedQty))
+			}
+		}
+
+		if price.Tier != nil && price.Tier.MaxSold != nil {
+			soldOthers := tickType.RegisteredCount - waitlistOffers[tickType.ID].OfferedQuantity
+			if soldOthers+requestedQty > *price.Tier.MaxSold {
+				validationErrors = append(validationErrors, fmt.Sprintf("Vé '%s' giá %s chỉ còn %d vé (bạn yêu cầu %d)", tickType.Name, price.Tier.Name, *price.Tier.MaxSold-soldOthers, requestedQty))
 			}
 		}
 
 		totalNewTickets += requestedQty
-		totalPrice += tickType.Price * requestedQty
+		totalPrice += price.Price * requestedQty
 	}
 
 	if totalAlreadyRegistered+totalNewTickets > eventEntry.MaxTicketPerUser {
 		validationErrors = append(validationErrors, fmt.Sprintf("Bạn đang giữ %d vé. Bạn chỉ được đăng ký tổng cộng %d vé cho sự kiện này.", totalAlreadyRegistered, eventEntry.MaxTicketPerUser))
 	}
 
-	return validationErrors, lstTicketTypes, requestedTicketsMap, totalPrice, totalNewTickets
+	return validationErrors, lstTicketTypes, requestedTicketsMap, priceMap, attendeesMap, totalPrice, totalNewTickets
+}
+
+// Kiểm tra thông tin người tham dự của một dòng vé theo form đăng ký của sự kiện.
+// Form không yêu cầu thì được bỏ trống, đã gửi thì phải đủ một người cho mỗi vé.
+func buildTicketAttendees(form *collections.EventRegistrationForm, ticketType collections.TicketType, quantity int, reqAttendees []dto.AttendeeReq) ([]collections.TicketAttendee, []string) {
+	var errs []string
+	if len(reqAttendees) == 0 {
+		if form.RequiresAttendees(ticketType.ID) {
+			errs = append(errs, fmt.Sprintf("Vui lòng điền thông tin người tham dự cho từng vé '%s'.", ticketType.Name))
+		}
+		return nil, errs
+	}
+	if len(reqAttendees) != quantity {
+		errs = append(errs, fmt.Sprintf("Vé '%s' cần thông tin của đúng %d người tham dự (đã gửi %d).", ticketType.Name, quantity, len(reqAttendees)))
+		return nil, errs
+	}
+
+	fields := form.FieldsFor(ticketType.ID)
+	attendees := make([]collections.TicketAttendee, 0, len(reqAttendees))
+	for i, reqAttendee := range reqAttendees {
+		prefix := fmt.Sprintf("Vé '%s' người thứ %d", ticketType.Name, i+1)
+		attendee := collections.TicketAttendee{
+			Name:  strings.TrimSpace(reqAttendee.Name),
+			Email: strings.TrimSpace(reqAttendee.Email),
+		}
+		if attendee.Name == "" {
+			errs = append(errs, prefix+": thiếu tên người tham dự.")
+		}
+		if attendee.Email == "" {
+			errs = append(errs, prefix+": thiếu email người tham dự.")
+		} else if _, err := mail.ParseAddress(attendee.Email); err != nil {
+			errs = append(errs, prefix+": email người tham dự không hợp lệ.")
+		}
+
+		answerMap := make(map[string]dto.AttendeeAnswerReq, len(reqAttendee.Answers))
+		for _, answer := range reqAttendee.Answers {
+			answerMap[answer.FieldID] = answer
+		}
+		for _, field := range fields {
+			values := normalizeAnswer(field, answerMap[field.ID])
+			delete(answerMap, field.ID)
+			if len(values) == 0 {
+				if field.Required {
+					errs = append(errs, fmt.Sprintf("%s: câu hỏi '%s' là bắt buộc.", prefix, field.Label))
+				}
+				continue
+			}
+			if field.Type == consts.FormFieldText && utf8.RuneCountInString(values[0]) > 1000 {
+				errs = append(errs, fmt.Sprintf("%s: câu trả lời '%s' dài quá 1000 ký tự.", prefix, field.Label))
+			}
+			if field.Type != consts.FormFieldText {
+				for _, value := range values {
+					if !slices.Contains(field.Options, value) {
+						errs = append(errs, fmt.Sprintf("%s: '%s' không phải lựa chọn của câu hỏi '%s'.", prefix, value, field.Label))
+					}
+				}
+			}
+			attendee.Answers = append(attendee.Answers, collections.TicketAnswer{
+				FieldID: field.ID,
+				Label:   field.Label,
+				Values:  values,
+			})
+		}
+		for fieldID := range answerMap {
+			errs = append(errs, fmt.Sprintf("%s: câu hỏi '%s' không thuộc form đăng ký của vé này.", prefix, fieldID))
+		}
+		attendees = append(attendees, attendee)
+	}
+	return attendees, errs
+}
+
+// Câu trả lời đã bỏ khoảng trắng; câu hỏi text / choice chỉ nhận một giá trị
+func normalizeAnswer(field collections.EventFormField, answer dto.AttendeeAnswerReq) []string {
+	if field.Type != consts.FormFieldCheckbox {
+		value := strings.TrimSpace(answer.Value)
+		if value == "" && len(answer.Values) == 1 {
+			value = strings.TrimSpace(answer.Values[0])
+		}
+		if value == "" {
+			return nil
+		}
+		return []string{value}
+	}
+
+	var values []string
+	for _, value := range answer.Values {
+		value = strings.TrimSpace(value)
+		if value != "" && !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Danh sách đơn đăng ký của người dùng hiện tại
+func GetMyRegistrations(c *gin.Context) {
+	var (
+		regisEntry = &collections.Registration{}
+	)
+	ctx := c.Request.Context()
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"created_by": accountID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = strings.ToUpper(status)
+	}
+	if eventIDStr := c.Query("event_id"); eventIDStr != "" {
+		eventID, err := primitive.ObjectIDFromHex(eventIDStr)
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+			return
+		}
+		filter["event_id"] = eventID
+	}
+
+	pagination := dto.GetPagination(c, "primary")
+	skip := (pagination.Page - 1) * pagination.Length
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	opts.SetSkip(int64(skip))
+	opts.SetLimit(int64(pagination.Length))
+
+	totalDocs, err := regisEntry.CountDocuments(ctx, filter)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	pagination.TotalDocs = int(totalDocs)
+	pagination.BuildPagination()
+
+	registrations, err := regisEntry.Find(ctx, filter, opts)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	results, err := service.BuildRegistrationSummaries(ctx, registrations)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", results, &pagination)
+}
+
+// Chi tiết một đơn đăng ký (người mua hoặc admin)
+func GetMyRegistration(c *gin.Context) {
+	var (
+		regisEntry = &collections.Registration{}
+	)
+	ctx := c.Request.Context()
+
+	regisID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Registration ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	err = regisEntry.First(ctx, utils.GetFilter(bson.M{"_id": regisID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy đơn đăng ký")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm đơn đăng ký", err.Error())
+		return
+	}
+
+	if !utils.CanModifyResource(regisEntry.CreatedBy, accountID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền xem đơn đăng ký này")
+		return
+	}
+
+	results, err := service.BuildRegistrationSummaries(ctx, collections.Registrations{*regisEntry})
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", results[0], nil)
+}
+
+// Người dùng tự hủy đơn đăng ký đang chờ thanh toán
+func CancelMyRegistration(c *gin.Context) {
+	var (
+		regisEntry = &collections.Registration{}
+	)
+	ctx := c.Request.Context()
+
+	regisID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Registration ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	err = regisEntry.First(ctx, utils.GetFilter(bson.M{"_id": regisID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy đơn đăng ký")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm đơn đăng ký", err.Error())
+		return
+	}
+
+	if regisEntry.CreatedBy != accountID {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền hủy đơn đăng ký này")
+		return
+	}
+	if regisEntry.Status != consts.RegistrationPending {
+		utils.ResponseError(c, http.StatusConflict, "", consts.ErrRegistrationNotPending.Error())
+		return
+	}
+
+	err = service.CancelPendingRegistration(ctx, *regisEntry, accountID, consts.CancelReasonUserCancelled)
+	switch {
+	case errors.Is(err, consts.ErrRegistrationNotPending):
+		utils.ResponseError(c, http.StatusConflict, "", err.Error())
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	utils.ResponseSuccess(c, http.StatusOK, "Hủy đơn đăng ký thành công!", nil, nil)
 }
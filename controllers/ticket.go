@@ -0,0 +1,628 @@
+package controllers
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/dto"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"EventHunting/view"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Check-in vé bằng mã QR (link trong email: <domain>/ticket/checkin/token?<QRCodeData>&event_id=<id>, mã QR đã ký có thể bỏ event_id)
+func CheckInTicket(c *gin.Context) {
+	var (
+		req             dto.CheckInTicketRequest
+		eventEntry      = &collections.Event{}
+		ticketTypeEntry = &collections.TicketType{}
+	)
+	ctx := c.Request.Context()
+
+	if c.Request.Method == http.MethodPost && c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "Lỗi do bind dữ liệu", err.Error())
+			return
+		}
+	}
+	if req.QRCodeData == "" {
+		req.QRCodeData = extractQRCodeData(c)
+	}
+	if req.EventID == "" {
+		req.EventID = c.Query("event_id")
+	}
+
+	if strings.TrimSpace(req.QRCodeData) == "" {
+		utils.ResponseError(c, http.StatusBadRequest, "", "Thiếu mã QR của vé")
+		return
+	}
+
+	// Link không có event_id: lấy sự kiện từ mã QR đã ký, quyền của ban tổ chức vẫn được kiểm tra bên dưới
+	var (
+		eventID primitive.ObjectID
+		err     error
+	)
+	if req.EventID == "" {
+		eventID, err = service.ResolveTicketEventID(req.QRCodeData)
+		if err != nil {
+			utils.ResponseError(c, checkInErrorStatus(err), "Check-in thất bại!", err.Error())
+			return
+		}
+	} else {
+		eventID, err = primitive.ObjectIDFromHex(req.EventID)
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+			return
+		}
+	}
+
+	staffID, ok := loadCheckInEvent(c, eventID, eventEntry)
+	if !ok {
+		return
+	}
+
+	ticketEntry, err := service.CheckInTicket(ctx, eventEntry, req.QRCodeData, staffID, time.Now())
+	if err != nil {
+		utils.ResponseError(c, checkInErrorStatus(err), "Check-in thất bại!", err.Error())
+		return
+	}
+
+	_ = ticketTypeEntry.First(ctx, bson.M{"_id": ticketEntry.TicketTypeID})
+	utils.ResponseSuccess(c, http.StatusOK, "Check-in thành công!", bson.M{
+		"ticket_id":        ticketEntry.ID,
+		"event_id":         eventEntry.ID,
+		"event_name":       eventEntry.Name,
+		"ticket_type_id":   ticketEntry.TicketTypeID,
+		"ticket_type_name": ticketTypeEntry.Name,
+		"status":           ticketEntry.Status,
+		"checked_in_at":    ticketEntry.CheckedInAt,
+	}, nil)
+}
+
+// Xuất danh sách vé hợp lệ cho thiết bị check-in offline
+func GetCheckInManifest(c *gin.Context) {
+	eventEntry := &collections.Event{}
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+		return
+	}
+	if _, ok := loadCheckInEvent(c, eventID, eventEntry); !ok {
+		return
+	}
+
+	manifest, err := service.BuildCheckInManifest(ctx, eventEntry)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	keys, err := utils.GetTicketTokenPublicKeys()
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi cấu hình key QR", err.Error())
+		return
+	}
+
+	days := []bson.M{}
+	for _, day := range service.EventDays(eventEntry) {
+		days = append(days, bson.M{
+			"date":     day.Date,
+			"open_at":  day.OpenAt,
+			"close_at": day.CloseAt,
+		})
+	}
+
+	utils.ResponseSuccess(c, http.StatusOK, "", bson.M{
+		"event_id":      eventEntry.ID,
+		"event_name":    eventEntry.Name,
+		"generated_at":  time.Now(),
+		"event_days":    days,
+		"token_version": utils.TicketTokenVersion,
+		"keys":          keys,
+		"tickets":       manifest,
+	}, nil)
+}
+
+// Đồng bộ các lượt quét offline từ thiết bị check-in
+func SyncOfflineCheckIns(c *gin.Context) {
+	var (
+		req        dto.SyncOfflineCheckInRequest
+		eventEntry = &collections.Event{}
+	)
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Lỗi do bind dữ liệu", err.Error())
+		return
+	}
+	if validateErrs := utils.ValidateSyncOfflineCheckIn(req); len(validateErrs) > 0 {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu không hợp lệ", strings.Join(validateErrs, ", "))
+		return
+	}
+
+	staffID, ok := loadCheckInEvent(c, eventID, eventEntry)
+	if !ok {
+		return
+	}
+
+	scans := make([]service.OfflineScan, 0, len(req.Scans))
+	for _, scan := range req.Scans {
+		gate := scan.Gate
+		if gate == "" {
+			gate = req.Gate
+		}
+		ticketID, _ := primitive.ObjectIDFromHex(scan.TicketID)
+		scans = append(scans, service.OfflineScan{
+			QRCodeData: strings.TrimSpace(scan.QRCodeData),
+			TicketID:   ticketID,
+			DeviceID:   req.DeviceID,
+			Gate:       gate,
+			ScannedAt:  scan.ScannedAt,
+		})
+	}
+
+	results, err := service.SyncOfflineScans(ctx, eventEntry, staffID, scans)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	summary := map[consts.CheckInResult]int{
+		consts.CheckInResultAccepted:  0,
+		consts.CheckInResultDuplicate: 0,
+		consts.CheckInResultRejected:  0,
+	}
+	for _, result := range results {
+		summary[result.Result]++
+	}
+
+	utils.ResponseSuccess(c, http.StatusOK, "Đồng bộ check-in thành công!", bson.M{
+		"summary": summary,
+		"results": results,
+	}, nil)
+}
+
+// Danh sách lượt quét trùng/bị từ chối khi đồng bộ offline
+func GetCheckInConflicts(c *gin.Context) {
+	var (
+		eventEntry = &collections.Event{}
+		logEntry   = &collections.CheckInLog{}
+	)
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+		return
+	}
+	if _, ok := loadCheckInEvent(c, eventID, eventEntry); !ok {
+		return
+	}
+
+	filter := bson.M{
+		"event_id": eventID,
+		"result":   bson.M{"$in": []consts.CheckInResult{consts.CheckInResultDuplicate, consts.CheckInResultRejected}},
+	}
+	if result := c.Query("result"); result != "" {
+		filter["result"] = result
+	}
+	if deviceID := c.Query("device_id"); deviceID != "" {
+		filter["device_id"] = deviceID
+	}
+
+	pagination := dto.GetPagination(c, "secondary")
+	opts := options.Find().
+		SetSort(bson.D{{Key: "synced_at", Value: -1}, {Key: "_id", Value: 1}}).
+		SetSkip(int64((pagination.Page - 1) * pagination.Length)).
+		SetLimit(int64(pagination.Length))
+
+	totalDocs, err := logEntry.CountDocuments(ctx, filter)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	pagination.TotalDocs = int(totalDocs)
+	pagination.BuildPagination()
+
+	logs, err := logEntry.Find(ctx, filter, opts)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", logs, &pagination)
+}
+
+// Xuất CSV danh sách người tham dự kèm câu trả lời form đăng ký (chỉ ban tổ chức)
+func ExportEventAttendees(c *gin.Context) {
+	eventEntry := &collections.Event{}
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	err = eventEntry.First(ctx, utils.GetFilter(bson.M{"_id": eventID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy sự kiện")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+	if !utils.CanModifyResource(eventEntry.CreatedBy, accountID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền xuất danh sách người tham dự của sự kiện này")
+		return
+	}
+
+	csvData, err := service.ExportEventAttendeesCSV(ctx, eventEntry)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi xuất danh sách người tham dự", err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "attendees-"+eventID.Hex()+".csv"))
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", csvData)
+}
+
+// Tìm sự kiện và kiểm tra người dùng là ban tổ chức (hoặc admin) của sự kiện
+func loadCheckInEvent(c *gin.Context, eventID primitive.ObjectID, eventEntry *collections.Event) (primitive.ObjectID, bool) {
+	staffID, ok := utils.GetAccountID(c)
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return primitive.NilObjectID, false
+	}
+
+	err = eventEntry.First(c.Request.Context(), utils.GetFilter(bson.M{"_id": eventID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy sự kiện")
+		return primitive.NilObjectID, false
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return primitive.NilObjectID, false
+	}
+
+	if !utils.CanModifyResource(eventEntry.CreatedBy, staffID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền check-in cho sự kiện này")
+		return primitive.NilObjectID, false
+	}
+	return staffID, true
+}
+
+// Public key để thiết bị check-in verify chữ ký mã QR khi offline
+func GetCheckInPublicKeys(c *gin.Context) {
+	keys, err := utils.GetTicketTokenPublicKeys()
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi cấu hình key QR", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", bson.M{
+		"token_version": utils.TicketTokenVersion,
+		"keys":          keys,
+	}, nil)
+}
+
+// Mã QR nằm ở phần query không có key: /ticket/checkin/token?TICKET-xxx&event_id=...
+func extractQRCodeData(c *gin.Context) string {
+	if code := c.Query("code"); code != "" {
+		return code
+	}
+	for _, part := range strings.Split(c.Request.URL.RawQuery, "&") {
+		if part == "" || strings.Contains(part, "=") {
+			continue
+		}
+		code, err := url.QueryUnescape(part)
+		if err != nil {
+			return part
+		}
+		return code
+	}
+	return ""
+}
+
+func checkInErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, consts.ErrTicketNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, consts.ErrTicketWrongEvent),
+		errors.Is(err, consts.ErrTicketTokenInvalid),
+		errors.Is(err, consts.ErrTicketLegacyCode),
+		errors.Is(err, consts.ErrCheckInEventRequired),
+		errors.Is(err, consts.ErrTicketCancelled),
+		errors.Is(err, consts.ErrTicketRefunding),
+		errors.Is(err, consts.ErrTicketRefunded),
+		errors.Is(err, consts.ErrCheckInNotOpen),
+		errors.Is(err, consts.ErrCheckInClosed):
+		return http.StatusBadRequest
+	case errors.Is(err, consts.ErrTicketAlreadyCheckedIn):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Chi tiết vé kèm điểm danh theo từng ngày sự kiện
+func GetTicket(c *gin.Context) {
+	var (
+		ticketEntry     = &collections.Ticket{}
+		ticketTypeEntry = &collections.TicketType{}
+		eventEntry      = &collections.Event{}
+		err             error
+	)
+	ctx := c.Request.Context()
+
+	ticketID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Ticket ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	err = ticketEntry.First(ctx, bson.M{"_id": ticketID})
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy vé")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm vé", err.Error())
+		return
+	}
+
+	err = eventEntry.First(ctx, bson.M{"_id": ticketEntry.EventID})
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+
+	// Người mua, ban tổ chức hoặc admin mới được xem vé
+	if ticketEntry.CreatedBy != accountID && !utils.CanModifyResource(eventEntry.CreatedBy, accountID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền xem vé này")
+		return
+	}
+
+	_ = ticketTypeEntry.First(ctx, bson.M{"_id": ticketEntry.TicketTypeID})
+
+	result := ticketEntry.ParseEntry()
+	result["ticket_type"] = bson.M{
+		"name":            ticketTypeEntry.Name,
+		"price":           ticketTypeEntry.Price,
+		"check_in_policy": ticketTypeEntry.GetCheckInPolicy(),
+	}
+	result["event"] = bson.M{
+		"name":       eventEntry.Name,
+		"event_time": eventEntry.EventTime,
+	}
+	result["attendance"] = service.BuildTicketAttendance(ticketEntry, eventEntry)
+
+	utils.ResponseSuccess(c, http.StatusOK, "", result, nil)
+}
+
+// Danh sách vé của người dùng hiện tại
+func GetMyTickets(c *gin.Context) {
+	var (
+		ticketEntry     = &collections.Ticket{}
+		ticketTypeEntry = &collections.TicketType{}
+		eventEntry      = &collections.Event{}
+		regisEntry      = &collections.Registration{}
+	)
+	ctx := c.Request.Context()
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"created_by": accountID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if eventIDStr := c.Query("event_id"); eventIDStr != "" {
+		eventID, err := primitive.ObjectIDFromHex(eventIDStr)
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+			return
+		}
+		filter["event_id"] = eventID
+	}
+
+	pagination := dto.GetPagination(c, "primary")
+	skip := (pagination.Page - 1) * pagination.Length
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}})
+	opts.SetSkip(int64(skip))
+	opts.SetLimit(int64(pagination.Length))
+
+	totalDocs, err := ticketEntry.CountDocuments(ctx, filter)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	pagination.TotalDocs = int(totalDocs)
+	pagination.BuildPagination()
+
+	tickets, err := ticketEntry.Find(ctx, filter, opts)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	var ticketTypeIDs, eventIDs, regisIDs []primitive.ObjectID
+	for _, ticket := range tickets {
+		ticketTypeIDs = append(ticketTypeIDs, ticket.TicketTypeID)
+		eventIDs = append(eventIDs, ticket.EventID)
+		regisIDs = append(regisIDs, ticket.RegisID)
+	}
+
+	ticketTypeMap := make(map[primitive.ObjectID]collections.TicketType)
+	eventMap := make(map[primitive.ObjectID]collections.Event)
+	regisMap := make(map[primitive.ObjectID]collections.Registration)
+	if len(tickets) > 0 {
+		ticketTypes, err := ticketTypeEntry.Find(ctx, bson.M{"_id": bson.M{"$in": ticketTypeIDs}})
+		if err != nil {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+			return
+		}
+		for _, ticketType := range ticketTypes {
+			ticketTypeMap[ticketType.ID] = ticketType
+		}
+
+		events, err := eventEntry.Find(ctx, bson.M{"_id": bson.M{"$in": eventIDs}})
+		if err != nil {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+			return
+		}
+		for _, event := range events {
+			eventMap[event.ID] = event
+		}
+
+		// Giá vé lấy theo giá đã chốt trên đơn
+		registrations, err := regisEntry.Find(ctx, bson.M{"_id": bson.M{"$in": regisIDs}})
+		if err != nil {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+			return
+		}
+		for _, regis := range registrations {
+			regisMap[regis.ID] = regis
+		}
+	}
+
+	results := make([]bson.M, 0, len(tickets))
+	for _, ticket := range tickets {
+		item := service.BuildTicketSummary(ticket, ticketTypeMap, regisMap)
+		if event, ok := eventMap[ticket.EventID]; ok {
+			item["event"] = bson.M{
+				"id":             event.ID,
+				"name":           event.Name,
+				"thumbnail_url":  event.ThumbnailUrl,
+				"event_time":     event.EventTime,
+				"event_location": event.EventLocation,
+			}
+		}
+		results = append(results, item)
+	}
+
+	utils.ResponseSuccess(c, http.StatusOK, "", results, &pagination)
+}
+
+// Ảnh QR của vé (tạo lại theo yêu cầu, giống ảnh trong email vé)
+func GetTicketQRCode(c *gin.Context) {
+	var (
+		ticketEntry = &collections.Ticket{}
+		eventEntry  = &collections.Event{}
+	)
+	ctx := c.Request.Context()
+
+	ticketID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Ticket ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	err = ticketEntry.First(ctx, bson.M{"_id": ticketID})
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy vé")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm vé", err.Error())
+		return
+	}
+
+	if ticketEntry.CreatedBy != accountID {
+		err = eventEntry.First(ctx, bson.M{"_id": ticketEntry.EventID})
+		if err != nil {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+			return
+		}
+		if !utils.CanModifyResource(eventEntry.CreatedBy, accountID, roles) {
+			utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền xem vé này")
+			return
+		}
+	}
+
+	switch ticketEntry.Status {
+	case consts.TicketStatusCancelled:
+		utils.ResponseError(c, http.StatusBadRequest, "", consts.ErrTicketCancelled.Error())
+		return
+	case consts.TicketStatusRefunding:
+		utils.ResponseError(c, http.StatusBadRequest, "", consts.ErrTicketRefunding.Error())
+		return
+	case consts.TicketStatusRefunded:
+		utils.ResponseError(c, http.StatusBadRequest, "", consts.ErrTicketRefunded.Error())
+		return
+	}
+
+	size := 256
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		size, err = strconv.Atoi(sizeStr)
+		if err != nil || size < 128 || size > 1024 {
+			utils.ResponseError(c, http.StatusBadRequest, "", "Kích thước ảnh QR phải từ 128 đến 1024")
+			return
+		}
+	}
+
+	qrCodePng, err := view.BuildTicketQRCode(ticketEntry.QRCodeData, size)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "image/png", qrCodePng)
+}
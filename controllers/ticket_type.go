@@ -2,9 +2,12 @@ package controllers
 
 import (
 	"EventHunting/collections"
+	"EventHunting/consts"
 	"EventHunting/dto"
+	"EventHunting/service"
 	"EventHunting/utils"
 	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -89,12 +92,28 @@ func CreateTicketType(c *gin.Context) {
 	if req.Description != nil {
 		newTicketType.Description = *req.Description
 	}
+	if req.CheckInPolicy != nil {
+		newTicketType.CheckInPolicy = *req.CheckInPolicy
+	} else {
+		newTicketType.CheckInPolicy = consts.CheckInPolicySingle
+	}
+	newTicketType.SaleStartAt = req.SaleStartAt
+	newTicketType.SaleEndAt = req.SaleEndAt
+	newTicketType.PriceTiers = buildPriceTiers(req.PriceTiers)
+	newTicketType.HighDemand = req.HighDemand
 	// Lưu vào DB
 	if err := newTicketType.Create(ctx); err != nil {
 		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống", err.Error())
 		return
 	}
 
+	// Nạp sẵn bộ đếm tồn kho trước giờ mở bán (lỗi thì lượt giữ vé đầu tiên sẽ tự nạp lại)
+	if newTicketType.UsesInventoryCounter() {
+		if err := service.SyncInventory(ctx, newTicketType.ID); err != nil {
+			log.Printf("WARN: Nạp bộ đếm tồn kho loại vé %s thất bại: %v", newTicketType.ID.Hex(), err)
+		}
+	}
+
 	//Trả về thành công
 	utils.ResponseSuccess(c, http.StatusCreated, "", newTicketType, nil)
 }
@@ -186,6 +205,9 @@ func UpdateTicketType(c *gin.Context) {
 	if req.Status != nil {
 		updateFields["status"] = *req.Status
 	}
+	if req.CheckInPolicy != nil {
+		updateFields["check_in_policy"] = *req.CheckInPolicy
+	}
 
 	// Logic xử lý Quantity
 	if req.SetUnlimited != nil && *req.SetUnlimited {
@@ -194,6 +216,51 @@ func UpdateTicketType(c *gin.Context) {
 		updateFields["quantity"] = *req.Quantity
 	}
 
+	// Thời gian mở bán, kiểm tra lại khi chỉ đổi một đầu
+	if req.ClearSaleWindow != nil && *req.ClearSaleWindow {
+		unsetFields["sale_start_at"] = ""
+		unsetFields["sale_end_at"] = ""
+	} else {
+		saleStartAt, saleEndAt := ticketTypeEntry.SaleStartAt, ticketTypeEntry.SaleEndAt
+		if req.SaleStartAt != nil {
+			saleStartAt = req.SaleStartAt
+			updateFields["sale_start_at"] = *req.SaleStartAt
+		}
+		if req.SaleEndAt != nil {
+			saleEndAt = req.SaleEndAt
+			updateFields["sale_end_at"] = *req.SaleEndAt
+		}
+		if saleStartAt != nil && saleEndAt != nil && !saleEndAt.After(*saleStartAt) {
+			utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu không hợp lệ", "Thời gian kết thúc bán phải sau thời gian mở bán")
+			return
+		}
+	}
+
+	// Bậc giá chỉ ảnh hưởng đơn mới, đơn đã đăng ký giữ giá đã chốt
+	if req.PriceTiers != nil {
+		if len(*req.PriceTiers) == 0 {
+			unsetFields["price_tiers"] = ""
+		} else {
+			updateFields["price_tiers"] = buildPriceTiers(*req.PriceTiers)
+		}
+	}
+
+	// Chế độ bán số lượng lớn và số lượng sau cập nhật
+	updated := *ticketTypeEntry
+	if req.HighDemand != nil {
+		updateFields["high_demand"] = *req.HighDemand
+		updated.HighDemand = *req.HighDemand
+	}
+	if req.SetUnlimited != nil && *req.SetUnlimited {
+		updated.Quantity = nil
+	} else if req.Quantity != nil {
+		updated.Quantity = req.Quantity
+	}
+	if updated.HighDemand && updated.Quantity == nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu không hợp lệ", "Chế độ bán số lượng lớn (high_demand) chỉ áp dụng cho vé có giới hạn số lượng")
+		return
+	}
+
 	finalUpdateDoc := bson.M{"$set": updateFields}
 	if len(unsetFields) > 0 {
 		finalUpdateDoc["$unset"] = unsetFields
@@ -201,12 +268,38 @@ func UpdateTicketType(c *gin.Context) {
 
 	// Thực hiện cập nhật
 	err = ticketTypeEntry.Update(ctx, filter, finalUpdateDoc)
-	switch {
-	case err == nil:
-		utils.ResponseSuccess(c, http.StatusOK, "", nil, nil)
-	default:
+	if err != nil {
 		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống!", err.Error())
+		return
+	}
+
+	// Đổi số lượng khi đang bán qua Redis: cộng chênh lệch để không đè các lượt giữ vé đang diễn ra.
+	// Bật/tắt chế độ thì nạp lại hoặc xóa bộ đếm.
+	switch {
+	case ticketTypeEntry.UsesInventoryCounter() && updated.UsesInventoryCounter():
+		if diff := *updated.Quantity - *ticketTypeEntry.Quantity; diff != 0 {
+			service.AdjustInventory(ctx, map[primitive.ObjectID]int{ticketID: diff})
+		}
+	case ticketTypeEntry.UsesInventoryCounter() != updated.UsesInventoryCounter():
+		if err = service.SyncInventory(ctx, ticketID); err != nil {
+			log.Printf("WARN: Đồng bộ bộ đếm tồn kho loại vé %s thất bại: %v", ticketID.Hex(), err)
+		}
+	}
+
+	utils.ResponseSuccess(c, http.StatusOK, "", nil, nil)
+}
+
+func buildPriceTiers(tiers []dto.TicketPriceTier) []collections.TicketPriceTier {
+	var result []collections.TicketPriceTier
+	for _, tier := range tiers {
+		result = append(result, collections.TicketPriceTier{
+			Name:    strings.TrimSpace(tier.Name),
+			Price:   tier.Price,
+			EndAt:   tier.EndAt,
+			MaxSold: tier.MaxSold,
+		})
 	}
+	return result
 }
 
 func GetListTicketTypes(c *gin.Context) {
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Vào hàng chờ của sự kiện, gọi lại nhiều lần vẫn giữ nguyên vị trí
+func JoinWaitingRoom(c *gin.Context) {
+	handleWaitingRoom(c, service.JoinWaitingRoom)
+}
+
+// Vị trí / thời gian chờ ước tính, tới lượt thì trả về vé vào cửa để gửi kèm yêu cầu đăng ký
+func GetWaitingRoomStatus(c *gin.Context) {
+	handleWaitingRoom(c, service.GetWaitingRoomStatus)
+}
+
+type waitingRoomHandler func(ctx context.Context, event *collections.Event, accountID primitive.ObjectID, now time.Time) (*service.WaitingRoomStatus, error)
+
+func handleWaitingRoom(c *gin.Context, handler waitingRoomHandler) {
+	var (
+		eventEntry = &collections.Event{}
+	)
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ!", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	err = eventEntry.First(ctx, utils.GetFilter(bson.M{"_id": eventID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Sự kiện không tồn tại hoặc đã bị xóa.")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+
+	status, err := handler(ctx, eventEntry, accountID, time.Now())
+	if err != nil {
+		utils.ResponseError(c, waitingRoomErrorStatus(err), "", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", status, nil)
+}
+
+func waitingRoomErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, consts.ErrWaitingRoomDisabled):
+		return http.StatusBadRequest
+	case errors.Is(err, consts.ErrWaitingRoomNotJoined):
+		return http.StatusNotFound
+	case errors.Is(err, consts.ErrWaitingRoomExpired):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
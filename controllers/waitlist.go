@@ -0,0 +1,273 @@
+package controllers
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/dto"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Đăng ký vào danh sách chờ của loại vé đã hết
+func JoinWaitlist(c *gin.Context) {
+	var (
+		req             dto.JoinWaitlistRequest
+		eventEntry      = &collections.Event{}
+		ticketTypeEntry = &collections.TicketType{}
+	)
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ!", err.Error())
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Dữ liệu đầu vào không hợp lệ!", err.Error())
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	err = eventEntry.First(ctx, utils.GetFilter(bson.M{"_id": eventID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Sự kiện không tồn tại hoặc đã bị xóa.")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+
+	if eventEntry.LotteryEnabled() {
+		utils.ResponseError(c, http.StatusBadRequest, "", consts.ErrLotteryDirectRegistration.Error())
+		return
+	}
+	if req.Quantity < 0 || req.Quantity > eventEntry.MaxTicketPerUser {
+		utils.ResponseError(c, http.StatusBadRequest, "", fmt.Sprintf("Số vé chờ phải từ 1 đến %d", eventEntry.MaxTicketPerUser))
+		return
+	}
+
+	err = ticketTypeEntry.First(ctx, utils.GetFilter(bson.M{
+		"_id":      req.TicketTypeID,
+		"event_id": eventID,
+		"status":   consts.TicketTypeActive,
+	}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Loại vé không tồn tại hoặc không còn mở bán")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm loại vé", err.Error())
+		return
+	}
+	if ticketTypeEntry.SaleStatus(time.Now()) == consts.TicketSaleEnded {
+		utils.ResponseError(c, http.StatusBadRequest, "", fmt.Sprintf("Vé '%s' đã ngừng bán.", ticketTypeEntry.Name))
+		return
+	}
+
+	newEntry, err := service.JoinWaitlist(ctx, *ticketTypeEntry, accountID, req.Quantity, time.Now())
+	if err != nil {
+		utils.ResponseError(c, waitlistErrorStatus(err), "", err.Error())
+		return
+	}
+
+	position, err := service.WaitlistPosition(ctx, *newEntry)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusCreated, "Đã vào danh sách chờ!", bson.M{
+		"entry":    newEntry,
+		"position": position,
+	}, nil)
+}
+
+// Danh sách chờ của người dùng hiện tại
+func GetMyWaitlist(c *gin.Context) {
+	var (
+		waitlistEntry = &collections.WaitlistEntry{}
+	)
+	ctx := c.Request.Context()
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"account_id": accountID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	entries, err := waitlistEntry.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	results := make([]bson.M, 0, len(entries))
+	for _, entry := range entries {
+		position, err := service.WaitlistPosition(ctx, entry)
+		if err != nil {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+			return
+		}
+		results = append(results, bson.M{
+			"entry":    entry,
+			"position": position,
+		})
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", results, nil)
+}
+
+// Rời danh sách chờ (kể cả khi đang được giữ vé)
+func CancelMyWaitlist(c *gin.Context) {
+	entryID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+
+	if err = service.CancelWaitlistEntry(c.Request.Context(), entryID, accountID); err != nil {
+		utils.ResponseError(c, waitlistErrorStatus(err), "", err.Error())
+		return
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "Đã rời danh sách chờ!", nil, nil)
+}
+
+// Hàng chờ của sự kiện theo thứ tự (chỉ ban tổ chức)
+func GetEventWaitlist(c *gin.Context) {
+	var (
+		eventEntry    = &collections.Event{}
+		waitlistEntry = &collections.WaitlistEntry{}
+		accountEntry  = &collections.Account{}
+	)
+	ctx := c.Request.Context()
+
+	eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		utils.ResponseError(c, http.StatusBadRequest, "Event ID không hợp lệ", err.Error())
+		return
+	}
+
+	accountID, ok := utils.GetAccountID(c)
+	if !ok {
+		return
+	}
+	roles, err := utils.GetRoles(c)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi khi lấy quyền", err.Error())
+		return
+	}
+
+	err = eventEntry.First(ctx, utils.GetFilter(bson.M{"_id": eventID}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.ResponseError(c, http.StatusNotFound, "", "Không tìm thấy sự kiện")
+		return
+	case err != nil:
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi tìm sự kiện", err.Error())
+		return
+	}
+	if !utils.CanModifyResource(eventEntry.CreatedBy, accountID, roles) {
+		utils.ResponseError(c, http.StatusForbidden, "", "Bạn không có quyền xem danh sách chờ của sự kiện này")
+		return
+	}
+
+	// Mặc định chỉ lấy các lượt còn hiệu lực (đang chờ / đang giữ vé)
+	filter := bson.M{
+		"event_id": eventID,
+		"status":   bson.M{"$in": []consts.WaitlistStatus{consts.WaitlistWaiting, consts.WaitlistOffered}},
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if ticketTypeIDStr := c.Query("ticket_type_id"); ticketTypeIDStr != "" {
+		ticketTypeID, err := primitive.ObjectIDFromHex(ticketTypeIDStr)
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "Ticket type ID không hợp lệ", err.Error())
+			return
+		}
+		filter["ticket_type_id"] = ticketTypeID
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	entries, err := waitlistEntry.Find(ctx, filter, opts)
+	if err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+		return
+	}
+
+	accountIDs := make([]primitive.ObjectID, 0, len(entries))
+	for _, entry := range entries {
+		accountIDs = append(accountIDs, entry.AccountID)
+	}
+	accountMap := make(map[primitive.ObjectID]collections.Account)
+	if len(accountIDs) > 0 {
+		accounts, err := accountEntry.Find(bson.M{"_id": bson.M{"$in": accountIDs}})
+		if err != nil {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống!", err.Error())
+			return
+		}
+		for _, account := range accounts {
+			accountMap[account.ID] = account
+		}
+	}
+
+	// Vị trí tính riêng cho từng loại vé, chỉ với lượt đang chờ
+	positions := make(map[primitive.ObjectID]int)
+	results := make([]bson.M, 0, len(entries))
+	for _, entry := range entries {
+		item := bson.M{
+			"entry": entry,
+			"account": bson.M{
+				"id":    entry.AccountID,
+				"name":  accountMap[entry.AccountID].Name,
+				"email": accountMap[entry.AccountID].Email,
+			},
+		}
+		if entry.Status == consts.WaitlistWaiting {
+			positions[entry.TicketTypeID]++
+			item["position"] = positions[entry.TicketTypeID]
+		}
+		results = append(results, item)
+	}
+	utils.ResponseSuccess(c, http.StatusOK, "", results, nil)
+}
+
+func waitlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, consts.ErrWaitlistNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, consts.ErrWaitlistUnlimited),
+		errors.Is(err, consts.ErrWaitlistTicketAvailable):
+		return http.StatusBadRequest
+	case errors.Is(err, consts.ErrWaitlistAlreadyJoined),
+		errors.Is(err, consts.ErrWaitlistInvalidState):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
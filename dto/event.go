@@ -27,7 +27,43 @@ type EventCreateReq struct {
 		Address string `json:"address"`
 		MapURL  string `json:"map_url"`
 	} `bson:"event_location" json:"event_location"`
-	TopicIDs *[]primitive.ObjectID `bson:"topic_ids" json:"topic_ids"`
+	TopicIDs     *[]primitive.ObjectID `bson:"topic_ids" json:"topic_ids"`
+	RefundPolicy *EventRefundPolicyReq `json:"refund_policy"`
+	WaitingRoom  *EventWaitingRoomReq  `json:"waiting_room"`
+	Lottery      *EventLotteryReq      `json:"lottery"`
+
+	RegistrationForm *EventRegistrationFormReq `json:"registration_form"`
+}
+
+type EventRefundPolicyReq struct {
+	Enabled       bool `json:"enabled"`
+	DeadlineHours int  `json:"deadline_hours"`
+	RefundPercent int  `json:"refund_percent"`
+}
+
+type EventWaitingRoomReq struct {
+	Enabled        bool `json:"enabled"`
+	AdmitPerMinute int  `json:"admit_per_minute"`
+}
+
+type EventLotteryReq struct {
+	Enabled      bool       `json:"enabled"`
+	ApplyStartAt *time.Time `json:"apply_start_at"`
+	ApplyEndAt   *time.Time `json:"apply_end_at"`
+}
+
+type EventRegistrationFormReq struct {
+	CollectAttendees bool                `json:"collect_attendees"`
+	Fields           []EventFormFieldReq `json:"fields"`
+}
+
+type EventFormFieldReq struct {
+	ID            string               `json:"id"`
+	Label         string               `json:"label"`
+	Type          string               `json:"type"` // text / choice / checkbox
+	Required      bool                 `json:"required"`
+	Options       []string             `json:"options"`
+	TicketTypeIDs []primitive.ObjectID `json:"ticket_type_ids"`
 }
 
 type EventUpdateReq struct {
@@ -42,6 +78,9 @@ type EventUpdateReq struct {
 	MediaIDs         *[]primitive.ObjectID `json:"media_ids"`
 	TopicIDs         *[]primitive.ObjectID `json:"topic_ids"`
 	ProvinceID       *primitive.ObjectID   `json:"province_id"`
+	RefundPolicy     *EventRefundPolicyReq `json:"refund_policy"`
+	WaitingRoom      *EventWaitingRoomReq  `json:"waiting_room"`
+	Lottery          *EventLotteryReq      `json:"lottery"`
 	EventTime        *struct {
 		StartDate *time.Time `json:"start_date"`
 		EndDate   *time.Time `json:"end_date"`
@@ -54,4 +93,6 @@ type EventUpdateReq struct {
 		Address *string `json:"address"`
 		MapURL  *string `json:"map_url"`
 	} `json:"event_location"`
+
+	RegistrationForm *EventRegistrationFormReq `json:"registration_form"`
 }
@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"EventHunting/consts"
+	"time"
+)
+
+type CreatePromoCode struct {
+	EventID       string                   `json:"event_id"`
+	Code          string                   `json:"code"`
+	Description   *string                  `json:"description,omitempty"`
+	DiscountType  consts.PromoDiscountType `json:"discount_type"`  // percent / fixed
+	DiscountValue int                      `json:"discount_value"` // % (1-100) hoặc số tiền VNĐ
+	MaxDiscount   *int                     `json:"max_discount,omitempty"`
+	TicketTypeIDs []string                 `json:"ticket_type_ids,omitempty"` // Bỏ trống = mọi loại vé
+
+	MaxUses        *int `json:"max_uses,omitempty"`          // nil là không giới hạn
+	MaxUsesPerUser *int `json:"max_uses_per_user,omitempty"` // nil là không giới hạn
+
+	StartAt *time.Time              `json:"start_at,omitempty"`
+	EndAt   *time.Time              `json:"end_at,omitempty"`
+	Status  *consts.PromoCodeStatus `json:"status,omitempty"` // mặc định: active
+}
+
+// Mức giảm và phạm vi loại vé không đổi sau khi tạo để đơn đã áp mã giữ nguyên điều kiện
+type UpdatePromoCode struct {
+	Description    *string                 `json:"description,omitempty"`
+	MaxUses        *int                    `json:"max_uses,omitempty"`
+	MaxUsesPerUser *int                    `json:"max_uses_per_user,omitempty"`
+	SetUnlimited   *bool                   `json:"set_unlimited,omitempty"` // true để bỏ giới hạn tổng số lượt
+	StartAt        *time.Time              `json:"start_at,omitempty"`
+	EndAt          *time.Time              `json:"end_at,omitempty"`
+	Status         *consts.PromoCodeStatus `json:"status,omitempty"`
+}
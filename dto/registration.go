@@ -6,5 +6,43 @@ type CreateRegistrationEventRequest struct {
 	Tickets []struct {
 		TicketTypeID primitive.ObjectID `json:"ticket_type_id"`
 		Quantity     int                `json:"quantity"`
+		Attendees    []AttendeeReq      `json:"attendees"` // Mỗi vé một người tham dự, theo form đăng ký của sự kiện
 	} `json:"tickets"`
+	PaymentMethod string `json:"payment_method"` // VNPAY, MOMO, ZALOPAY, FAKE... bỏ trống = cổng mặc định
+	PromoCode     string `json:"promo_code"`     // Mã giảm giá (không phân biệt hoa thường)
+}
+
+type AttendeeReq struct {
+	Name    string              `json:"name"`
+	Email   string              `json:"email"`
+	Answers []AttendeeAnswerReq `json:"answers"`
+}
+
+type AttendeeAnswerReq struct {
+	FieldID string   `json:"field_id"`
+	Value   string   `json:"value"`  // Câu hỏi text / choice
+	Values  []string `json:"values"` // Câu hỏi checkbox
+}
+
+type CreateRefundRequest struct {
+	TicketIDs []primitive.ObjectID `json:"ticket_ids"` // Bỏ trống = hoàn tất cả vé chưa check-in
+	Reason    string               `json:"reason"`
+}
+
+type RejectRefundRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ResolvePaymentDiscrepancyRequest struct {
+	Note string `json:"note"` // Cách xử lý (đã hoàn tiền thủ công, khôi phục đơn...)
+}
+
+type JoinWaitlistRequest struct {
+	TicketTypeID primitive.ObjectID `json:"ticket_type_id"`
+	Quantity     int                `json:"quantity"` // Mặc định 1
+}
+
+type EnterLotteryRequest struct {
+	TicketTypeID primitive.ObjectID `json:"ticket_type_id"`
+	Quantity     int                `json:"quantity"` // Mặc định 1
 }
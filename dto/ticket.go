@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+type CheckInTicketRequest struct {
+	QRCodeData string `json:"qr_code_data"`
+	EventID    string `json:"event_id"`
+}
+
+type OfflineScanRequest struct {
+	QRCodeData string    `json:"qr_code_data"`
+	TicketID   string    `json:"ticket_id"`
+	Gate       string    `json:"gate"`
+	ScannedAt  time.Time `json:"scanned_at"` // Giờ trên thiết bị (RFC3339)
+}
+
+type SyncOfflineCheckInRequest struct {
+	DeviceID string               `json:"device_id"`
+	Gate     string               `json:"gate"`
+	Scans    []OfflineScanRequest `json:"scans"`
+}
@@ -2,8 +2,17 @@ package dto
 
 import (
 	"EventHunting/consts"
+	"time"
 )
 
+// Bậc giá: hết hiệu lực tại end_at và/hoặc khi số vé đã bán đạt max_sold (cần ít nhất một điều kiện)
+type TicketPriceTier struct {
+	Name    string     `json:"name"`
+	Price   int        `json:"price"`
+	EndAt   *time.Time `json:"end_at,omitempty"`
+	MaxSold *int       `json:"max_sold,omitempty"`
+}
+
 type CreateTicketType struct {
 	EventID     string                  `json:"event_id"`
 	Name        string                  `json:"name"`
@@ -11,6 +20,14 @@ type CreateTicketType struct {
 	Price       int                     `json:"price"`
 	Quantity    *int                    `json:"quantity,omitempty"` // nil là unlimited
 	Status      consts.TicketTypeStatus `json:"status"`
+
+	CheckInPolicy *consts.CheckInPolicy `json:"check_in_policy,omitempty"` // mặc định: single
+
+	SaleStartAt *time.Time        `json:"sale_start_at,omitempty"`
+	SaleEndAt   *time.Time        `json:"sale_end_at,omitempty"`
+	PriceTiers  []TicketPriceTier `json:"price_tiers,omitempty"` // Xét theo thứ tự, hết bậc thì dùng price
+
+	HighDemand bool `json:"high_demand,omitempty"` // Bật chế độ giữ vé qua Redis cho đợt mở bán lớn
 }
 
 // UpdateTicketTypePayload chứa dữ liệu để cập nhật loại vé
@@ -21,4 +38,13 @@ type UpdateTicketType struct {
 	Quantity     *int                     `json:"quantity,omitempty"`
 	SetUnlimited *bool                    `json:"set_unlimited,omitempty"` // Set thành true để làm cho quantity = nil
 	Status       *consts.TicketTypeStatus `json:"status,omitempty"`
+
+	CheckInPolicy *consts.CheckInPolicy `json:"check_in_policy,omitempty"`
+
+	SaleStartAt     *time.Time         `json:"sale_start_at,omitempty"`
+	SaleEndAt       *time.Time         `json:"sale_end_at,omitempty"`
+	ClearSaleWindow *bool              `json:"clear_sale_window,omitempty"` // true để bỏ giới hạn thời gian bán
+	PriceTiers      *[]TicketPriceTier `json:"price_tiers,omitempty"`       // Mảng rỗng để xóa toàn bộ bậc giá
+
+	HighDemand *bool `json:"high_demand,omitempty"`
 }
@@ -0,0 +1,72 @@
+package einvoice
+
+import (
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultFileDir = "./data/einvoices"
+
+// Thay cho nhà cung cấp thật khi phát triển: ghi XML vào thư mục local và coi như đã phát hành.
+// Mã tra cứu là 12 ký tự đầu của SHA-256 nội dung XML.
+type fileSubmitter struct{}
+
+func (fileSubmitter) Name() string {
+	return consts.EInvoiceProviderFile
+}
+
+func (fileSubmitter) Submit(ctx context.Context, req SubmitRequest) (*SubmitResult, error) {
+	dir := fileDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("không tạo được thư mục hóa đơn điện tử: %w", err)
+	}
+
+	filename := req.InvoiceID.Hex() + ".xml"
+	tmpPath := filepath.Join(dir, filename+".tmp")
+	if err := os.WriteFile(tmpPath, req.XML, 0o644); err != nil {
+		return nil, fmt.Errorf("không ghi được file hóa đơn điện tử: %w", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(dir, filename)); err != nil {
+		return nil, fmt.Errorf("không ghi được file hóa đơn điện tử: %w", err)
+	}
+
+	sum := sha256.Sum256(req.XML)
+	return &SubmitResult{
+		ProviderInvoiceID: filename,
+		LookupCode:        strings.ToUpper(hex.EncodeToString(sum[:])[:12]),
+		Status:            consts.EInvoiceStatusIssued,
+		Message:           "Đã lưu XML tại " + filepath.Join(dir, filename),
+	}, nil
+}
+
+func (fileSubmitter) QueryStatus(ctx context.Context, providerInvoiceID string) (*SubmitResult, error) {
+	data, err := os.ReadFile(filepath.Join(fileDir(), filepath.Base(providerInvoiceID)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("không tìm thấy hóa đơn điện tử %s", providerInvoiceID)
+		}
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	return &SubmitResult{
+		ProviderInvoiceID: providerInvoiceID,
+		LookupCode:        strings.ToUpper(hex.EncodeToString(sum[:])[:12]),
+		Status:            consts.EInvoiceStatusIssued,
+	}, nil
+}
+
+func fileDir() string {
+	if dir := strings.TrimSpace(configs.GetEInvoiceFileDir()); dir != "" {
+		return dir
+	}
+	return defaultFileDir
+}
@@ -0,0 +1,49 @@
+package einvoice
+
+import (
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrUnknownSubmitter = errors.New("nhà cung cấp hóa đơn điện tử không được hỗ trợ")
+
+type SubmitRequest struct {
+	InvoiceID     primitive.ObjectID
+	InvoiceNumber string
+	XML           []byte
+}
+
+type SubmitResult struct {
+	ProviderInvoiceID string
+	LookupCode        string
+	Status            consts.EInvoiceStatus // submitted hoặc issued
+	Message           string
+}
+
+// Nhà cung cấp hóa đơn điện tử: nhận XML, ký số, gửi cơ quan thuế và trả mã tra cứu
+type Submitter interface {
+	Name() string
+	Submit(ctx context.Context, req SubmitRequest) (*SubmitResult, error)
+	QueryStatus(ctx context.Context, providerInvoiceID string) (*SubmitResult, error)
+}
+
+// Lấy nhà cung cấp theo tên (không phân biệt hoa thường). Tên rỗng = FILE.
+func Get(name string) (Submitter, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "", consts.EInvoiceProviderFile:
+		return fileSubmitter{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSubmitter, name)
+	}
+}
+
+// Nhà cung cấp mặc định theo cấu hình einvoice.provider
+func Default() (Submitter, error) {
+	return Get(configs.GetEInvoiceProvider())
+}
@@ -0,0 +1,317 @@
+package einvoice
+
+import (
+	"EventHunting/collections"
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/utils"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dữ liệu hóa đơn điện tử theo định dạng XML của Tổng cục Thuế (QĐ 1450/QĐ-TCT, phiên bản 2.0.0).
+// Phần chữ ký số (DSCKS) do nhà cung cấp ký khi phát hành.
+type HDon struct {
+	XMLName xml.Name `xml:"HDon"`
+	DLHDon  DLHDon   `xml:"DLHDon"`
+	DSCKS   DSCKS    `xml:"DSCKS"`
+}
+
+type DLHDon struct {
+	ID      string  `xml:"Id,attr"`
+	TTChung TTChung `xml:"TTChung"`
+	NDHDon  NDHDon  `xml:"NDHDon"`
+}
+
+// Thông tin chung
+type TTChung struct {
+	PBan      string     `xml:"PBan"`
+	THDon     string     `xml:"THDon"`
+	KHMSHDon  string     `xml:"KHMSHDon"`
+	KHHDon    string     `xml:"KHHDon"`
+	SHDon     int64      `xml:"SHDon"`
+	NLap      string     `xml:"NLap"`
+	DVTTe     string     `xml:"DVTTe"`
+	TGia      int        `xml:"TGia"`
+	HTTToan   string     `xml:"HTTToan"`
+	MSTTCGP   string     `xml:"MSTTCGP,omitempty"`
+	TTHDLQuan *TTHDLQuan `xml:"TTHDLQuan,omitempty"`
+}
+
+// Thông tin hóa đơn liên quan (hóa đơn điều chỉnh trỏ về hóa đơn gốc)
+type TTHDLQuan struct {
+	TCHDon       int    `xml:"TCHDon"`    // 1: thay thế, 2: điều chỉnh
+	LHDCLQuan    int    `xml:"LHDCLQuan"` // 1: hóa đơn điện tử theo NĐ 123/2020
+	KHMSHDCLQuan string `xml:"KHMSHDCLQuan"`
+	KHHDCLQuan   string `xml:"KHHDCLQuan"`
+	SHDCLQuan    int64  `xml:"SHDCLQuan"`
+	NLHDCLQuan   string `xml:"NLHDCLQuan"`
+	GChu         string `xml:"GChu,omitempty"`
+}
+
+// Nội dung hóa đơn
+type NDHDon struct {
+	NBan    NBan    `xml:"NBan"`
+	NMua    NMua    `xml:"NMua"`
+	DSHHDVu DSHHDVu `xml:"DSHHDVu"`
+	TToan   TToan   `xml:"TToan"`
+}
+
+// Người bán
+type NBan struct {
+	Ten  string `xml:"Ten"`
+	MST  string `xml:"MST"`
+	DChi string `xml:"DChi"`
+}
+
+// Người mua (cá nhân không có MST)
+type NMua struct {
+	Ten       string `xml:"Ten,omitempty"`
+	MST       string `xml:"MST,omitempty"`
+	DChi      string `xml:"DChi,omitempty"`
+	HVTNMHang string `xml:"HVTNMHang,omitempty"`
+	DCTDTu    string `xml:"DCTDTu,omitempty"`
+	SDThoai   string `xml:"SDThoai,omitempty"`
+}
+
+type DSHHDVu struct {
+	HHDVu []HHDVu `xml:"HHDVu"`
+}
+
+// Một dòng hàng hóa, dịch vụ. Tiền thuế của dòng đặt trong TTKhac.
+type HHDVu struct {
+	TChat  int     `xml:"TChat"` // 1: hàng hóa, dịch vụ; 3: chiết khấu thương mại
+	STT    int     `xml:"STT"`
+	MHHDVu string  `xml:"MHHDVu,omitempty"`
+	THHDVu string  `xml:"THHDVu"`
+	DVTinh string  `xml:"DVTinh"`
+	SLuong int     `xml:"SLuong"`
+	DGia   int64   `xml:"DGia"`
+	ThTien int64   `xml:"ThTien"`
+	TSuat  string  `xml:"TSuat"`
+	TTKhac *TTKhac `xml:"TTKhac,omitempty"`
+}
+
+type TTKhac struct {
+	TTin []TTin `xml:"TTin"`
+}
+
+type TTin struct {
+	TTruong string `xml:"TTruong"`
+	KDLieu  string `xml:"KDLieu"`
+	DLieu   string `xml:"DLieu"`
+}
+
+// Tổng hợp thanh toán
+type TToan struct {
+	THTTLTSuat THTTLTSuat `xml:"THTTLTSuat"`
+	TgTCThue   int64      `xml:"TgTCThue"`
+	TgTThue    int64      `xml:"TgTThue"`
+	TgTTTBSo   int64      `xml:"TgTTTBSo"`
+	TgTTTBChu  string     `xml:"TgTTTBChu"`
+}
+
+// Tổng hợp theo từng loại thuế suất
+type THTTLTSuat struct {
+	LTSuat []LTSuat `xml:"LTSuat"`
+}
+
+type LTSuat struct {
+	TSuat  string `xml:"TSuat"`
+	ThTien int64  `xml:"ThTien"`
+	TThue  int64  `xml:"TThue"`
+}
+
+type DSCKS struct {
+	NBan string `xml:"NBan"`
+}
+
+var trailingDigits = regexp.MustCompile(`(\d+)\D*$`)
+
+// Chuyển hóa đơn sang XML hóa đơn điện tử. Giá vé đã gồm thuế nên tiền trước thuế được tách ngược theo thuế suất từng dòng.
+// Hóa đơn điều chỉnh giảm ghi số tiền âm và trỏ về hóa đơn gốc (originalInvoice).
+func BuildXML(invoice *collections.Invoice, originalInvoice *collections.Invoice) ([]byte, error) {
+	loc := time.FixedZone("Asia/Ho_Chi_Minh", 7*60*60)
+	isCreditNote := invoice.Type == consts.InvoiceTypeCreditNote
+	if isCreditNote && originalInvoice == nil {
+		return nil, fmt.Errorf("hóa đơn điều chỉnh %s thiếu hóa đơn gốc", invoice.InvoiceNumber)
+	}
+
+	seq, err := invoiceSeq(invoice)
+	if err != nil {
+		return nil, err
+	}
+	issuedAt := invoice.CreatedAt.In(loc)
+
+	title := "HÓA ĐƠN GIÁ TRỊ GIA TĂNG"
+	sign := int64(1)
+	if isCreditNote {
+		sign = -1
+	}
+
+	doc := HDon{
+		DLHDon: DLHDon{
+			ID: "data",
+			TTChung: TTChung{
+				PBan:     "2.0.0",
+				THDon:    title,
+				KHMSHDon: formNumber(),
+				KHHDon:   invoiceSymbol(issuedAt),
+				SHDon:    seq,
+				NLap:     issuedAt.Format("2006-01-02"),
+				DVTTe:    "VND",
+				TGia:     1,
+				HTTToan:  paymentMethodName(invoice.PaymentDetails.Method),
+				MSTTCGP:  strings.TrimSpace(configs.GetEInvoiceSolutionTaxCode()),
+			},
+			NDHDon: NDHDon{
+				NBan: NBan{
+					Ten:  strings.TrimSpace(configs.GetInvoiceSellerName()),
+					MST:  strings.TrimSpace(configs.GetInvoiceSellerTaxCode()),
+					DChi: strings.TrimSpace(configs.GetInvoiceSellerAddress()),
+				},
+				NMua: NMua{
+					HVTNMHang: invoice.CustomerDetails.Name,
+					DCTDTu:    invoice.CustomerDetails.Email,
+					SDThoai:   invoice.CustomerDetails.Phone,
+				},
+			},
+		},
+	}
+
+	if isCreditNote {
+		originalSeq, err := invoiceSeq(originalInvoice)
+		if err != nil {
+			return nil, err
+		}
+		originalIssuedAt := originalInvoice.CreatedAt.In(loc)
+		doc.DLHDon.TTChung.TTHDLQuan = &TTHDLQuan{
+			TCHDon:       2,
+			LHDCLQuan:    1,
+			KHMSHDCLQuan: formNumber(),
+			KHHDCLQuan:   invoiceSymbol(originalIssuedAt),
+			SHDCLQuan:    originalSeq,
+			NLHDCLQuan:   originalIssuedAt.Format("2006-01-02"),
+			GChu:         fmt.Sprintf("Điều chỉnh giảm cho hóa đơn số %s", originalInvoice.InvoiceNumber),
+		}
+	}
+
+	var (
+		summaryByRate = map[string]*LTSuat{}
+		rateOrder     []string
+		totalPreTax   int64
+		totalTax      int64
+		totalGross    int64
+	)
+	for i, item := range invoice.LineItems {
+		vatRate := configs.GetEInvoiceVATRate()
+		if item.VATRate != nil {
+			vatRate = *item.VATRate
+		}
+		rateLabel := fmt.Sprintf("%d%%", vatRate)
+
+		// Dòng âm là chiết khấu thương mại (mã giảm giá): ghi số dương với TChat 3 và trừ vào tổng
+		tChat, unit, direction := 1, "Vé", int64(1)
+		gross := int64(item.TotalAmount)
+		unitPrice := int64(item.UnitPrice)
+		if gross < 0 {
+			tChat, unit, direction = 3, "", -1
+			gross, unitPrice = -gross, -unitPrice
+		}
+		preTax := excludeVAT(gross, vatRate)
+		tax := gross - preTax
+
+		doc.DLHDon.NDHDon.DSHHDVu.HHDVu = append(doc.DLHDon.NDHDon.DSHHDVu.HHDVu, HHDVu{
+			TChat:  tChat,
+			STT:    i + 1,
+			MHHDVu: item.ItemID.Hex(),
+			THHDVu: item.Description,
+			DVTinh: unit,
+			SLuong: item.Quantity,
+			DGia:   sign * excludeVAT(unitPrice, vatRate),
+			ThTien: sign * preTax,
+			TSuat:  rateLabel,
+			TTKhac: &TTKhac{TTin: []TTin{
+				{TTruong: "Tiền thuế GTGT", KDLieu: "numeric", DLieu: strconv.FormatInt(sign*tax, 10)},
+				{TTruong: "Thành tiền sau thuế", KDLieu: "numeric", DLieu: strconv.FormatInt(sign*gross, 10)},
+			}},
+		})
+
+		summary, ok := summaryByRate[rateLabel]
+		if !ok {
+			summary = &LTSuat{TSuat: rateLabel}
+			summaryByRate[rateLabel] = summary
+			rateOrder = append(rateOrder, rateLabel)
+		}
+		summary.ThTien += sign * direction * preTax
+		summary.TThue += sign * direction * tax
+
+		totalPreTax += direction * preTax
+		totalTax += direction * tax
+		totalGross += direction * gross
+	}
+
+	for _, rateLabel := range rateOrder {
+		doc.DLHDon.NDHDon.TToan.THTTLTSuat.LTSuat = append(doc.DLHDon.NDHDon.TToan.THTTLTSuat.LTSuat, *summaryByRate[rateLabel])
+	}
+	doc.DLHDon.NDHDon.TToan.TgTCThue = sign * totalPreTax
+	doc.DLHDon.NDHDon.TToan.TgTThue = sign * totalTax
+	doc.DLHDon.NDHDon.TToan.TgTTTBSo = sign * totalGross
+	doc.DLHDon.NDHDon.TToan.TgTTTBChu = utils.VietnameseAmountInWords(sign * totalGross)
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("lỗi tạo XML hóa đơn điện tử: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// Tách tiền trước thuế từ số tiền đã gồm thuế, làm tròn đến đồng
+func excludeVAT(gross int64, vatRate int) int64 {
+	if vatRate <= 0 {
+		return gross
+	}
+	divisor := int64(100 + vatRate)
+	return (gross*100*2 + divisor) / (2 * divisor)
+}
+
+// Số hóa đơn (SHDon) là số thứ tự thuần theo năm; hóa đơn lập trước khi lưu einvoice_seq/invoice_seq thì lấy dãy số cuối của số hóa đơn
+func invoiceSeq(invoice *collections.Invoice) (int64, error) {
+	if invoice.EInvoiceSeq > 0 {
+		return invoice.EInvoiceSeq, nil
+	}
+	if invoice.InvoiceSeq > 0 {
+		return invoice.InvoiceSeq, nil
+	}
+	match := trailingDigits.FindStringSubmatch(invoice.InvoiceNumber)
+	if match == nil {
+		return 0, fmt.Errorf("không xác định được số thứ tự của hóa đơn %s", invoice.InvoiceNumber)
+	}
+	return strconv.ParseInt(match[1], 10, 64)
+}
+
+func formNumber() string {
+	if form := strings.TrimSpace(configs.GetEInvoiceFormNumber()); form != "" {
+		return form
+	}
+	return "1"
+}
+
+func invoiceSymbol(issuedAt time.Time) string {
+	return strings.ReplaceAll(strings.TrimSpace(configs.GetEInvoiceSymbol()), "{YY}", issuedAt.Format("06"))
+}
+
+func paymentMethodName(method string) string {
+	switch strings.ToUpper(method) {
+	case consts.PaymentMethodFree:
+		return "Không thu tiền"
+	case "":
+		return "TM/CK"
+	default:
+		return "Chuyển khoản"
+	}
+}
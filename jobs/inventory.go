@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"EventHunting/service"
+	"context"
+	"log"
+	"time"
+)
+
+// Dựng lại bộ đếm tồn kho trên Redis từ Mongo khi khởi động (Redis có thể đã mất dữ liệu hoặc lệch khi server dừng)
+func RebuildInventory() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	count, err := service.RebuildInventory(ctx)
+	if err != nil {
+		log.Println("Lỗi khi dựng lại bộ đếm tồn kho vé:", err)
+		return
+	}
+	log.Printf("Đã dựng lại bộ đếm tồn kho cho %d loại vé", count)
+}
@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"EventHunting/service"
+	"context"
+	"log"
+	"time"
+)
+
+// Bốc thăm các sự kiện đã hết thời gian nhận đăng ký bốc thăm
+func DrawLotteries() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	drawnCount, err := service.DrawLotteries(ctx)
+	if err != nil {
+		log.Println("CRON JOB:(lottery) Lỗi do hệ thống!", err)
+		return
+	}
+	if drawnCount > 0 {
+		log.Printf("CRON JOB:(lottery) Đã bốc thăm xong %d sự kiện", drawnCount)
+	}
+}
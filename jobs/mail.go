@@ -51,7 +51,7 @@ func processSingleJob(job EmailJob) {
 	var err error
 
 	switch job.Type {
-	case "ticket_email":
+	case consts.OutboxTopicTicketEmail:
 		regIDStr, ok := job.Data["registration_id"].(string)
 		if !ok {
 			log.Printf("ERROR: Dữ liệu job thiếu 'registration_id' -> BỎ QUA")
@@ -66,6 +66,36 @@ func processSingleJob(job EmailJob) {
 
 		err = service.ProcessTicketAndEmail(regID)
 
+	case consts.OutboxTopicWaitlistOffer:
+		entryIDStr, ok := job.Data["waitlist_entry_id"].(string)
+		if !ok {
+			log.Printf("ERROR: Dữ liệu job thiếu 'waitlist_entry_id' -> BỎ QUA")
+			return
+		}
+
+		entryID, parseErr := primitive.ObjectIDFromHex(entryIDStr)
+		if parseErr != nil {
+			log.Printf("ERROR: ID không hợp lệ: %s -> BỎ QUA", entryIDStr)
+			return
+		}
+
+		err = service.SendWaitlistOfferEmail(entryID)
+
+	case consts.OutboxTopicLotteryResult:
+		entryIDStr, ok := job.Data["lottery_entry_id"].(string)
+		if !ok {
+			log.Printf("ERROR: Dữ liệu job thiếu 'lottery_entry_id' -> BỎ QUA")
+			return
+		}
+
+		entryID, parseErr := primitive.ObjectIDFromHex(entryIDStr)
+		if parseErr != nil {
+			log.Printf("ERROR: ID không hợp lệ: %s -> BỎ QUA", entryIDStr)
+			return
+		}
+
+		err = service.SendLotteryResultEmail(entryID)
+
 	default:
 		log.Printf("WARN: Không biết loại job '%s' -> BỎ QUA", job.Type)
 		return
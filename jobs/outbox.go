@@ -0,0 +1,216 @@
+package jobs
+
+import (
+	"EventHunting/collections"
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Số liệu của relay trong tiến trình hiện tại (reset khi khởi động lại)
+var (
+	outboxPublishedTotal atomic.Int64
+	outboxFailedTotal    atomic.Int64
+	outboxLastRunAt      atomic.Int64 // Unix nano
+)
+
+type OutboxRelayStats struct {
+	PublishedTotal int64      `json:"published_total"`
+	FailedTotal    int64      `json:"failed_total"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+}
+
+type OutboxMetrics struct {
+	PendingCount            int64            `json:"pending_count"`
+	FailingCount            int64            `json:"failing_count"` // Pending và đã từng đẩy lỗi
+	OldestPendingAgeSeconds float64          `json:"oldest_pending_age_seconds"`
+	PublishedLastHour       int64            `json:"published_last_hour"`
+	AvgPublishLagMsLastHour float64          `json:"avg_publish_lag_ms_last_hour"` // published_at - created_at
+	MaxPublishLagMsLastHour float64          `json:"max_publish_lag_ms_last_hour"`
+	Relay                   OutboxRelayStats `json:"relay"`
+}
+
+// Relay đẩy message từ outbox sang Redis queue (at-least-once).
+// Message chỉ được đánh dấu published sau khi RPush thành công, nên có thể bị đẩy trùng; worker tự bỏ qua đơn đã gửi vé.
+func StartOutboxRelay() {
+	log.Printf("WORKER STARTED: Outbox relay, chu kỳ %ds", configs.GetOutboxPollIntervalSeconds())
+
+	for {
+		relayOutboxBatch()
+		time.Sleep(time.Duration(configs.GetOutboxPollIntervalSeconds()) * time.Second)
+	}
+}
+
+func relayOutboxBatch() {
+	lease := time.Duration(configs.GetOutboxLeaseSeconds()) * time.Second
+	outboxLastRunAt.Store(time.Now().UnixNano())
+
+	for i := 0; i < configs.GetOutboxBatchSize(); i++ {
+		outboxEntry := &collections.OutboxMessage{}
+		err := outboxEntry.Claim(nil, time.Now(), lease)
+		if err != nil {
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				log.Printf("ERROR: Outbox relay không đọc được outbox: %v", err)
+			}
+			return
+		}
+
+		if err := publishOutboxMessage(outboxEntry); err != nil {
+			outboxFailedTotal.Add(1)
+			retryAt := time.Now().Add(outboxBackoff(outboxEntry.Attempts))
+			log.Printf("ERROR: Outbox relay đẩy message %s (%s) lần %d thất bại, thử lại lúc %v: %v", outboxEntry.ID.Hex(), outboxEntry.Topic, outboxEntry.Attempts, retryAt.Format(time.RFC3339), err)
+
+			if err := outboxEntry.Update(nil,
+				bson.M{"_id": outboxEntry.ID, "status": consts.OutboxStatusPending},
+				bson.M{"$set": bson.M{"last_error": err.Error(), "available_at": retryAt}},
+			); err != nil {
+				log.Printf("ERROR: Outbox relay không cập nhật được message %s: %v", outboxEntry.ID.Hex(), err)
+			}
+			continue
+		}
+
+		// Đánh dấu lỗi thì message được đẩy lại khi hết lease
+		now := time.Now()
+		if err := outboxEntry.Update(nil,
+			bson.M{"_id": outboxEntry.ID, "status": consts.OutboxStatusPending},
+			bson.M{"$set": bson.M{"status": consts.OutboxStatusPublished, "published_at": now}},
+		); err != nil {
+			log.Printf("WARN: Message %s đã vào queue nhưng chưa đánh dấu published, có thể bị đẩy lại: %v", outboxEntry.ID.Hex(), err)
+		}
+		outboxPublishedTotal.Add(1)
+	}
+}
+
+func publishOutboxMessage(outboxEntry *collections.OutboxMessage) error {
+	var queueName string
+	switch outboxEntry.Topic {
+	case consts.OutboxTopicTicketEmail, consts.OutboxTopicWaitlistOffer, consts.OutboxTopicLotteryResult:
+		queueName = consts.QueueNameEmail
+	default:
+		return fmt.Errorf("không biết topic '%s'", outboxEntry.Topic)
+	}
+
+	jobPayload, err := json.Marshal(EmailJob{
+		Type:       outboxEntry.Topic,
+		Data:       outboxEntry.Data,
+		RetryCount: 0,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return database.GetRedisClient().Client.RPush(ctx, queueName, jobPayload).Err()
+}
+
+// 2^attempts giây, tối đa max_backoff_seconds
+func outboxBackoff(attempts int) time.Duration {
+	maxBackoff := time.Duration(configs.GetOutboxMaxBackoffSeconds()) * time.Second
+	if attempts > 16 {
+		return maxBackoff
+	}
+	backoff := time.Duration(1<<attempts) * time.Second
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// Thống kê độ trễ của outbox: tồn đọng hiện tại, độ trễ đẩy trong 1 giờ qua và số liệu relay
+func GetOutboxMetrics(ctx context.Context) (*OutboxMetrics, error) {
+	var (
+		outboxEntry = &collections.OutboxMessage{}
+		metrics     = &OutboxMetrics{}
+		now         = time.Now()
+		err         error
+	)
+
+	pendingFilter := bson.M{"status": consts.OutboxStatusPending}
+	if metrics.PendingCount, err = outboxEntry.CountDocuments(ctx, pendingFilter); err != nil {
+		return nil, err
+	}
+	if metrics.FailingCount, err = outboxEntry.CountDocuments(ctx, bson.M{
+		"status":     consts.OutboxStatusPending,
+		"last_error": bson.M{"$exists": true},
+	}); err != nil {
+		return nil, err
+	}
+
+	oldest, err := outboxEntry.Find(ctx, pendingFilter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(oldest) > 0 {
+		metrics.OldestPendingAgeSeconds = now.Sub(oldest[0].CreatedAt).Seconds()
+	}
+
+	lagStats, err := outboxEntry.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status":       consts.OutboxStatusPublished,
+			"published_at": bson.M{"$gte": now.Add(-time.Hour)},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"lag": bson.M{"$subtract": bson.A{"$published_at", "$created_at"}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   nil,
+			"count": bson.M{"$sum": 1},
+			"avg":   bson.M{"$avg": "$lag"},
+			"max":   bson.M{"$max": "$lag"},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(lagStats) > 0 {
+		metrics.PublishedLastHour = toInt64(lagStats[0]["count"])
+		metrics.AvgPublishLagMsLastHour = toFloat64(lagStats[0]["avg"])
+		metrics.MaxPublishLagMsLastHour = toFloat64(lagStats[0]["max"])
+	}
+
+	metrics.Relay = OutboxRelayStats{
+		PublishedTotal: outboxPublishedTotal.Load(),
+		FailedTotal:    outboxFailedTotal.Load(),
+	}
+	if lastRun := outboxLastRunAt.Load(); lastRun > 0 {
+		lastRunAt := time.Unix(0, lastRun)
+		metrics.Relay.LastRunAt = &lastRunAt
+	}
+	return metrics, nil
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
+
+func toFloat64(value interface{}) float64 {
+	switch v := value.(type) {
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
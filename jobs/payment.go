@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"EventHunting/collections"
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Đối soát đơn PENDING với cổng thanh toán trước khi hết hạn (phòng IPN bị mất hoặc lỗi giữa chừng),
+// và kiểm tra lại một lần các đơn vừa bị hủy để phát hiện trường hợp đã thu tiền.
+func ReconcilePendingPayments() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	regisEntry := collections.Registration{}
+	now := time.Now()
+	minAge := time.Duration(configs.GetReconcileMinAgeMinutes()) * time.Minute
+
+	pendingRegs, err := regisEntry.Find(ctx, utils.GetFilter(bson.M{
+		"status":         consts.RegistrationPending,
+		"payment_method": bson.M{"$ne": consts.PaymentMethodFree},
+		"created_at":     bson.M{"$lt": now.Add(-minAge)},
+	}))
+	if err != nil {
+		log.Println("CRON JOB:(reconcile) Lỗi do hệ thống!", err)
+		return
+	}
+
+	settledCount := 0
+	for _, reg := range pendingRegs {
+		outcome, err := service.ReconcileRegistration(ctx, reg)
+		if err != nil {
+			log.Printf("CRON JOB:(reconcile) Đối soát đơn %s thất bại: %v", reg.ID.Hex(), err)
+			continue
+		}
+		if outcome == service.ReconcileSettled {
+			log.Printf("CRON JOB:(reconcile) Đơn %s đã được thanh toán nhưng thiếu IPN, đã xác nhận", reg.ID.Hex())
+			settledCount++
+		}
+	}
+
+	// Đơn đã hủy chỉ cần đối soát một lần, sau khi đã qua thời gian chờ IPN đến muộn
+	cancelledRegs, err := regisEntry.Find(ctx, utils.GetFilter(bson.M{
+		"status":         consts.RegistrationCancelled,
+		"payment_method": bson.M{"$ne": consts.PaymentMethodFree},
+		"reconciled_at":  bson.M{"$exists": false},
+		"cancelled_at": bson.M{
+			"$gte": now.Add(-time.Duration(configs.GetReconcileLookbackHours()) * time.Hour),
+			"$lt":  now.Add(-minAge),
+		},
+	}))
+	if err != nil {
+		log.Println("CRON JOB:(reconcile) Lỗi do hệ thống!", err)
+		return
+	}
+
+	discrepancyCount := 0
+	for _, reg := range cancelledRegs {
+		outcome, err := service.ReconcileRegistration(ctx, reg)
+		if err != nil {
+			log.Printf("CRON JOB:(reconcile) Đối soát đơn đã hủy %s thất bại: %v", reg.ID.Hex(), err)
+			continue
+		}
+		if outcome == service.ReconcileDiscrepancy {
+			discrepancyCount++
+		}
+
+		err = reg.Update(ctx, bson.M{"_id": reg.ID}, bson.M{"$set": bson.M{"reconciled_at": time.Now()}})
+		if err != nil {
+			log.Printf("CRON JOB:(reconcile) Không cập nhật được reconciled_at cho đơn %s: %v", reg.ID.Hex(), err)
+		}
+	}
+
+	if len(pendingRegs) > 0 || len(cancelledRegs) > 0 {
+		log.Printf("CRON JOB:(reconcile) Đối soát %d đơn chờ (%d đã thu tiền), %d đơn đã hủy (%d sai lệch)",
+			len(pendingRegs), settledCount, len(cancelledRegs), discrepancyCount)
+	}
+}
@@ -4,15 +4,15 @@ import (
 	"EventHunting/collections"
 	"EventHunting/configs"
 	"EventHunting/consts"
-	"EventHunting/database"
+	"EventHunting/service"
 	"EventHunting/utils"
 	"context"
 	"errors"
-	"fmt"
 	"log"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -47,7 +47,16 @@ func HandleExpiredRegistrations() {
 	failCount := 0
 
 	for _, reg := range expiredRegs {
-		err := runPedingRegistrationTransaction(ctx, reg)
+		// Tra cứu lần cuối trước khi hủy, tránh hủy đơn khách đã trả tiền nhưng IPN chưa đến
+		outcome, err := service.ReconcileRegistration(ctx, reg)
+		if err != nil {
+			log.Printf("CRON JOB: Đối soát đơn %s trước khi hủy thất bại: %v", reg.ID.Hex(), err)
+		}
+		if outcome == service.ReconcileSettled {
+			continue
+		}
+
+		err = service.CancelPendingRegistration(ctx, reg, primitive.NilObjectID, consts.CancelReasonExpired)
 
 		if err != nil {
 			log.Printf("CRON JOB thất bại (RegID: %s): %v", reg.ID.Hex(), err)
@@ -58,56 +67,3 @@ func HandleExpiredRegistrations() {
 		}
 	}
 }
-
-func runPedingRegistrationTransaction(ctx context.Context, reg collections.Registration) error {
-	var (
-		db              = database.GetDB()
-		err             error
-		regisEntry      = collections.Registration{}
-		ticketTypeEntry = collections.TicketType{}
-		eventEntry      = collections.Event{}
-	)
-	session, err := db.Client().StartSession()
-	if err != nil {
-		return err
-	}
-	defer session.EndSession(ctx)
-
-	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
-
-		regFilter := bson.M{"_id": reg.ID, "status": consts.RegistrationPending}
-		regUpdate := bson.M{"$set": bson.M{
-			"status":       consts.RegistrationCancelled,
-			"cancelled_at": time.Now(),
-		}}
-
-		err = regisEntry.Update(sessionContext, regFilter, regUpdate)
-		if err != nil {
-			return nil, err
-		}
-
-		// Trả vé về kho
-		for _, ticket := range reg.Tickets {
-			err = ticketTypeEntry.Update(sessionContext,
-				bson.M{"_id": ticket.TicketTypeID},
-				bson.M{"$inc": bson.M{"registered_count": -ticket.Quantity}},
-			)
-			if err != nil {
-				return nil, fmt.Errorf("failed to return TicketType stock: %v", err)
-			}
-		}
-
-		//Giảm số người tham gia
-		err = eventEntry.Update(sessionContext,
-			bson.M{"_id": reg.EventID},
-			bson.M{"$inc": bson.M{"number_of_participants": -reg.TotalQuantity}},
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to return Event participants: %v", err)
-		}
-
-		return nil, nil
-	})
-
-	return err
-}
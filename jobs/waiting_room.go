@@ -0,0 +1,18 @@
+package jobs
+
+import (
+	"EventHunting/service"
+	"context"
+	"log"
+	"time"
+)
+
+// Cho người trong hàng chờ của các sự kiện đang bật phòng chờ vào trang đăng ký theo tốc độ cấu hình
+func AdvanceWaitingRooms() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := service.AdvanceWaitingRooms(ctx); err != nil {
+		log.Println("CRON JOB:(waiting room) Lỗi do hệ thống!", err)
+	}
+}
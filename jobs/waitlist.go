@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"EventHunting/service"
+	"context"
+	"log"
+	"time"
+)
+
+// Chuyển lượt giữ vé hết hạn cho người kế tiếp và mời người chờ khi có vé trống
+func ProcessWaitlists() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	expiredCount, heldCount, err := service.ProcessWaitlists(ctx)
+	if err != nil {
+		log.Println("CRON JOB:(waitlist) Lỗi do hệ thống!", err)
+		return
+	}
+	if expiredCount > 0 || heldCount > 0 {
+		log.Printf("CRON JOB:(waitlist) %d lượt giữ vé hết hạn, %d vé được giữ mới", expiredCount, heldCount)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"EventHunting/collections"
 	"EventHunting/configs"
 	"EventHunting/database"
 	"EventHunting/jobs"
@@ -21,11 +22,14 @@ func main() {
 	if err != nil {
 		fmt.Println(err)
 	}
+	collections.EnsureIndexes()
 
 	//Kết nối đến redis
 	err = database.NewRedisClient()
 	if err != nil {
 		fmt.Println(err)
+	} else {
+		jobs.RebuildInventory()
 	}
 
 	//Kết nối với cloudinary
@@ -43,6 +47,22 @@ func main() {
 	if err != nil {
 		log.Fatal("Lỗi khi thêm cron job:", err)
 	}
+	_, err = c.AddFunc("@every 2m", jobs.ReconcilePendingPayments)
+	if err != nil {
+		log.Fatal("Lỗi khi thêm cron job:", err)
+	}
+	_, err = c.AddFunc("@every 1m", jobs.ProcessWaitlists)
+	if err != nil {
+		log.Fatal("Lỗi khi thêm cron job:", err)
+	}
+	_, err = c.AddFunc(fmt.Sprintf("@every %ds", configs.GetWaitingRoomTickSeconds()), jobs.AdvanceWaitingRooms)
+	if err != nil {
+		log.Fatal("Lỗi khi thêm cron job:", err)
+	}
+	_, err = c.AddFunc("@every 1m", jobs.DrawLotteries)
+	if err != nil {
+		log.Fatal("Lỗi khi thêm cron job:", err)
+	}
 	_, err = c.AddFunc("@every 2m", jobs.UpdateViewsBlogToMongo)
 	if err != nil {
 		log.Fatal("Lỗi khi thêm cron job:", err)
@@ -117,6 +137,7 @@ func main() {
 	for i := 0; i < 5; i++ {
 		go jobs.StartEmailQueue()
 	}
+	go jobs.StartOutboxRelay()
 
 	//Đăng ký router
 	if err := routers.SetupRouter(); err != nil {
@@ -11,8 +11,8 @@ func CORSConfigMiddleware() gin.HandlerFunc {
 	config := cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"}, // frontend URL
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", IdempotencyKeyHeader, AdmissionTokenHeader},
+		ExposeHeaders:    []string{"Content-Length", idempotencyReplayedHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -0,0 +1,185 @@
+package middlewares
+
+import (
+	"EventHunting/configs"
+	"EventHunting/database"
+	"EventHunting/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	idempotencyMaxKeyLength   = 255
+)
+
+// Trạng thái của một Idempotency-Key lưu trong Redis
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"` // sha256 của method + path + body, chặn dùng lại key cho request khác
+	Completed   bool   `json:"completed"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Ghi lại phản hồi của handler để lưu cho các lần gửi lại
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Chống gửi trùng request (double-click, client retry) theo header Idempotency-Key.
+// Request đầu tiên giữ khóa trong lúc xử lý; phản hồi 2xx được lưu và trả lại nguyên vẹn cho các lần gửi lại trong ttl_hours.
+// Phản hồi lỗi không được lưu để client có thể thử lại với cùng key. Không có header thì bỏ qua.
+// Đặt sau AuthorizeJWTMiddleware để key được tách theo tài khoản.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			c.Next()
+			return
+		}
+		if len(idempotencyKey) > idempotencyMaxKeyLength {
+			utils.ResponseError(c, http.StatusBadRequest, fmt.Sprintf("%s tối đa %d ký tự", IdempotencyKeyHeader, idempotencyMaxKeyLength), nil)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			utils.ResponseError(c, http.StatusBadRequest, "Không đọc được dữ liệu gửi lên!", err.Error())
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		owner := c.GetString("account_id")
+		if owner == "" {
+			owner = utils.GetClientIpAdrr(c)
+		}
+		redisKey := fmt.Sprintf("idempotency:%s:%s:%s", c.FullPath(), owner, idempotencyKey)
+		fingerprint := idempotencyFingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+		ctx := c.Request.Context()
+		redisClient := database.GetRedisClient().Client
+
+		lock, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+		acquired, err := redisClient.SetNX(ctx, redisKey, lock, time.Duration(configs.GetIdempotencyLockSeconds())*time.Second).Result()
+		if err != nil {
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống redis idempotency!", err.Error())
+			c.Abort()
+			return
+		}
+		if !acquired {
+			replayIdempotentResponse(c, redisKey, fingerprint)
+			return
+		}
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		completed := false
+		defer func() {
+			// Handler lỗi hoặc panic: nhả khóa để client thử lại
+			if !completed {
+				releaseIdempotencyKey(redisKey)
+			}
+		}()
+
+		c.Next()
+
+		status := writer.Status()
+		if status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+
+		record, _ := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			StatusCode:  status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		})
+		storeCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := redisClient.Set(storeCtx, redisKey, record, time.Duration(configs.GetIdempotencyTTLHours())*time.Hour).Err(); err != nil {
+			log.Printf("ERROR: Không lưu được phản hồi cho %s %s: %v", IdempotencyKeyHeader, redisKey, err)
+			return
+		}
+		completed = true
+	}
+}
+
+func replayIdempotentResponse(c *gin.Context, redisKey string, fingerprint string) {
+	defer c.Abort()
+
+	raw, err := database.GetRedisClient().Client.Get(c.Request.Context(), redisKey).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			// Request đầu vừa thất bại và nhả khóa
+			utils.ResponseError(c, http.StatusConflict, "Yêu cầu trước với Idempotency-Key này vừa kết thúc, vui lòng thử lại!", nil)
+			return
+		}
+		utils.ResponseError(c, http.StatusInternalServerError, "Lỗi do hệ thống redis idempotency!", err.Error())
+		return
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		utils.ResponseError(c, http.StatusInternalServerError, "Dữ liệu Idempotency-Key bị hỏng!", err.Error())
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		utils.ResponseError(c, http.StatusUnprocessableEntity, "Idempotency-Key đã được dùng cho một yêu cầu khác!", nil)
+		return
+	}
+	if !record.Completed {
+		utils.ResponseError(c, http.StatusConflict, "Yêu cầu với Idempotency-Key này đang được xử lý!", nil)
+		return
+	}
+
+	c.Header(idempotencyReplayedHeader, "true")
+	c.Data(record.StatusCode, record.ContentType, record.Body)
+}
+
+func releaseIdempotencyKey(redisKey string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := database.GetRedisClient().Client.Del(ctx, redisKey).Err(); err != nil {
+		log.Printf("ERROR: Không nhả được khóa %s %s: %v", IdempotencyKeyHeader, redisKey, err)
+	}
+}
+
+func idempotencyFingerprint(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte(" "))
+	hash.Write([]byte(path))
+	hash.Write([]byte("\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
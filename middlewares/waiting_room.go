@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"EventHunting/consts"
+	"EventHunting/service"
+	"EventHunting/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const AdmissionTokenHeader = "X-Admission-Token"
+
+// Sự kiện bật phòng chờ thì chỉ cho đăng ký khi có vé vào cửa hợp lệ (header X-Admission-Token).
+// Đặt sau AuthorizeJWTMiddleware và trước IdempotencyMiddleware để request bị chặn không giữ khóa idempotency.
+func WaitingRoomMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID, err := primitive.ObjectIDFromHex(c.Param("id"))
+		if err != nil {
+			// Controller trả lỗi ID không hợp lệ
+			c.Next()
+			return
+		}
+		accountID, ok := utils.GetAccountID(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+
+		err = service.CheckAdmission(c.Request.Context(), eventID, accountID, c.GetHeader(AdmissionTokenHeader))
+		switch {
+		case errors.Is(err, consts.ErrAdmissionTokenRequired),
+			errors.Is(err, consts.ErrAdmissionTokenInvalid):
+			utils.ResponseError(c, http.StatusForbidden, "Chưa tới lượt vào trang đăng ký!", err.Error())
+			c.Abort()
+			return
+		case err != nil:
+			utils.ResponseError(c, http.StatusInternalServerError, "Lỗi hệ thống khi kiểm tra phòng chờ!", err.Error())
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,153 @@
+package payment
+
+import (
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/utils"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kết quả giả lập tại trang thanh toán FAKE
+const (
+	FakeOutcomeSuccess = "success"
+	FakeOutcomeFailure = "failure"
+	FakeOutcomeDelay   = "delay" // Trả về trang chờ, IPN thành công đến sau payment.fake.delay_seconds
+)
+
+// Giao dịch đã giả lập, dùng cho QueryTransaction
+var fakeTransactions sync.Map
+
+type fakeProvider struct{}
+
+func (fakeProvider) Name() string {
+	return consts.PaymentMethodFake
+}
+
+// Link thanh toán trỏ về trang checkout giả lập của chính server
+func (fakeProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*CreatePaymentResult, error) {
+	params := url.Values{}
+	params.Set("order_id", req.OrderID)
+	params.Set("amount", strconv.FormatInt(req.Amount, 10))
+	params.Set("signature", signFakeParams(params))
+
+	return &CreatePaymentResult{
+		PaymentURL: strings.TrimSpace(configs.GetServerDomain()) + "/payments/fake/checkout?" + params.Encode(),
+	}, nil
+}
+
+func (fakeProvider) VerifyCallback(ctx context.Context, params url.Values) (*CallbackResult, error) {
+	if !validSignature(params.Get("signature"), signFakeParams(params)) {
+		return nil, ErrInvalidSignature
+	}
+
+	amount, _ := strconv.ParseInt(params.Get("amount"), 10, 64)
+	paidAt, err := time.Parse(time.RFC3339, params.Get("pay_date"))
+	if err != nil {
+		paidAt = time.Now()
+	}
+	responseCode := params.Get("response_code")
+
+	return &CallbackResult{
+		OrderID:       params.Get("order_id"),
+		Success:       responseCode == "00",
+		Amount:        amount,
+		TransactionNo: params.Get("transaction_no"),
+		ResponseCode:  responseCode,
+		Message:       ResponsePaymentMessage(responseCode),
+		PaidAt:        paidAt,
+	}, nil
+}
+
+func (fakeProvider) QueryTransaction(ctx context.Context, req QueryTransactionRequest) (*TransactionResult, error) {
+	value, ok := fakeTransactions.Load(req.OrderID)
+	if !ok {
+		return &TransactionResult{OrderID: req.OrderID, Status: TransactionNotFound, ResponseCode: "91"}, nil
+	}
+	result := value.(TransactionResult)
+	return &result, nil
+}
+
+func (fakeProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("số tiền hoàn không hợp lệ: %d", req.Amount)
+	}
+	log.Printf("INFO: [FAKE] Hoàn %d VNĐ cho đơn %s (giao dịch %s)", req.Amount, req.OrderID, req.TransactionCode)
+	return &RefundResult{ProviderRefundID: "FAKE-RF-" + req.RefundID.Hex()}, nil
+}
+
+// Xác thực link checkout giả lập (order_id, amount do server ký khi tạo link)
+func VerifyFakeCheckout(params url.Values) (orderID string, amount int64, err error) {
+	checkout := url.Values{}
+	checkout.Set("order_id", params.Get("order_id"))
+	checkout.Set("amount", params.Get("amount"))
+	if !validSignature(params.Get("signature"), signFakeParams(checkout)) {
+		return "", 0, ErrInvalidSignature
+	}
+	amount, err = strconv.ParseInt(params.Get("amount"), 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	return params.Get("order_id"), amount, nil
+}
+
+// Tạo bộ tham số callback đã ký như thể cổng thanh toán gửi về
+func SimulateFakeCallback(orderID string, amount int64, success bool) url.Values {
+	now := time.Now()
+	responseCode := "00"
+	status := TransactionSuccess
+	if !success {
+		responseCode = "24" // Khách hàng hủy giao dịch
+		status = TransactionFailed
+	}
+	transactionNo := fmt.Sprintf("FAKE%d", now.UnixNano())
+
+	fakeTransactions.Store(orderID, TransactionResult{
+		OrderID:       orderID,
+		Status:        status,
+		Amount:        amount,
+		TransactionNo: transactionNo,
+		ResponseCode:  responseCode,
+		Message:       ResponsePaymentMessage(responseCode),
+		PaidAt:        now,
+	})
+
+	params := url.Values{}
+	params.Set("order_id", orderID)
+	params.Set("amount", strconv.FormatInt(amount, 10))
+	params.Set("response_code", responseCode)
+	params.Set("transaction_no", transactionNo)
+	params.Set("pay_date", now.Format(time.RFC3339))
+	params.Set("signature", signFakeParams(params))
+	return params
+}
+
+// Ký HMAC-SHA512 các tham số (trừ signature) theo thứ tự key
+func signFakeParams(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k == "signature" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var data strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			data.WriteString("&")
+		}
+		data.WriteString(k)
+		data.WriteString("=")
+		data.WriteString(url.QueryEscape(params.Get(k)))
+	}
+	return utils.HmacSha512(configs.GetFakePaymentSecret(), data.String())
+}
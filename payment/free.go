@@ -0,0 +1,38 @@
+package payment
+
+import (
+	"EventHunting/consts"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+var ErrFreeOrder = errors.New("đơn miễn phí không cần thanh toán")
+
+// Đơn 0đ: xác nhận ngay khi đăng ký, không có giao dịch ở cổng nào
+type freeProvider struct{}
+
+func (freeProvider) Name() string {
+	return consts.PaymentMethodFree
+}
+
+func (freeProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*CreatePaymentResult, error) {
+	return nil, ErrFreeOrder
+}
+
+func (freeProvider) VerifyCallback(ctx context.Context, params url.Values) (*CallbackResult, error) {
+	return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, ErrFreeOrder)
+}
+
+func (freeProvider) QueryTransaction(ctx context.Context, req QueryTransactionRequest) (*TransactionResult, error) {
+	return &TransactionResult{OrderID: req.OrderID, Status: TransactionNotFound}, nil
+}
+
+// Hoàn vé miễn phí chỉ trả lại suất, không có tiền để hoàn
+func (freeProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	if req.Amount != 0 {
+		return nil, fmt.Errorf("đơn miễn phí không thể hoàn %d VNĐ", req.Amount)
+	}
+	return &RefundResult{}, nil
+}
@@ -0,0 +1,271 @@
+package payment
+
+import (
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type MomoCreateRequest struct {
+	PartnerCode string `json:"partnerCode"`
+	RequestID   string `json:"requestId"`
+	Amount      int64  `json:"amount"`
+	OrderID     string `json:"orderId"`
+	OrderInfo   string `json:"orderInfo"`
+	RedirectUrl string `json:"redirectUrl"`
+	IpnUrl      string `json:"ipnUrl"`
+	RequestType string `json:"requestType"`
+	ExtraData   string `json:"extraData"`
+	Lang        string `json:"lang"`
+	Signature   string `json:"signature"`
+}
+
+type MomoCreateResponse struct {
+	PartnerCode  string `json:"partnerCode"`
+	RequestID    string `json:"requestId"`
+	OrderID      string `json:"orderId"`
+	Amount       int64  `json:"amount"`
+	ResponseTime int64  `json:"responseTime"`
+	Message      string `json:"message"`
+	ResultCode   int    `json:"resultCode"`
+	PayUrl       string `json:"payUrl"`
+}
+
+type MomoQueryRequest struct {
+	PartnerCode string `json:"partnerCode"`
+	RequestID   string `json:"requestId"`
+	OrderID     string `json:"orderId"`
+	Lang        string `json:"lang"`
+	Signature   string `json:"signature"`
+}
+
+type MomoQueryResponse struct {
+	PartnerCode  string `json:"partnerCode"`
+	RequestID    string `json:"requestId"`
+	OrderID      string `json:"orderId"`
+	Amount       int64  `json:"amount"`
+	TransID      int64  `json:"transId"`
+	PayType      string `json:"payType"`
+	ResultCode   int    `json:"resultCode"`
+	Message      string `json:"message"`
+	ResponseTime int64  `json:"responseTime"`
+	LastUpdated  int64  `json:"lastUpdated"`
+}
+
+type MomoRefundRequest struct {
+	PartnerCode string `json:"partnerCode"`
+	OrderID     string `json:"orderId"` // Mã riêng cho lần hoàn, không trùng mã đơn gốc
+	RequestID   string `json:"requestId"`
+	Amount      int64  `json:"amount"`
+	TransID     int64  `json:"transId"`
+	Lang        string `json:"lang"`
+	Description string `json:"description"`
+	Signature   string `json:"signature"`
+}
+
+type MomoRefundResponse struct {
+	PartnerCode  string `json:"partnerCode"`
+	OrderID      string `json:"orderId"`
+	RequestID    string `json:"requestId"`
+	Amount       int64  `json:"amount"`
+	TransID      int64  `json:"transId"`
+	ResultCode   int    `json:"resultCode"`
+	Message      string `json:"message"`
+	ResponseTime int64  `json:"responseTime"`
+}
+
+type momoProvider struct{}
+
+func (momoProvider) Name() string {
+	return consts.PaymentMethodMoMo
+}
+
+// Tạo giao dịch ví MoMo (captureWallet), trả về payUrl
+func (momoProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*CreatePaymentResult, error) {
+	callbackUrl := strings.TrimSpace(configs.GetServerDomain()) + "/payments/" + consts.PaymentMethodMoMo + "/callback"
+
+	momoReq := MomoCreateRequest{
+		PartnerCode: strings.TrimSpace(configs.GetMoMoPartnerCode()),
+		RequestID:   fmt.Sprintf("%s-%d", req.OrderID, time.Now().UnixNano()),
+		Amount:      req.Amount,
+		OrderID:     req.OrderID,
+		OrderInfo:   req.OrderInfo,
+		RedirectUrl: ReturnUrl(consts.PaymentMethodMoMo),
+		IpnUrl:      callbackUrl,
+		RequestType: "captureWallet",
+		Lang:        "vi",
+	}
+	momoReq.Signature = signMomo(
+		"amount", strconv.FormatInt(momoReq.Amount, 10),
+		"extraData", momoReq.ExtraData,
+		"ipnUrl", momoReq.IpnUrl,
+		"orderId", momoReq.OrderID,
+		"orderInfo", momoReq.OrderInfo,
+		"partnerCode", momoReq.PartnerCode,
+		"redirectUrl", momoReq.RedirectUrl,
+		"requestId", momoReq.RequestID,
+		"requestType", momoReq.RequestType,
+	)
+
+	var res MomoCreateResponse
+	if err := postJSON(ctx, momoApiUrl("/v2/gateway/api/create"), momoReq, &res); err != nil {
+		return nil, fmt.Errorf("lỗi gọi API tạo giao dịch MoMo: %w", err)
+	}
+	if res.ResultCode != 0 || res.PayUrl == "" {
+		return nil, fmt.Errorf("MoMo trả mã %d: %s", res.ResultCode, res.Message)
+	}
+	return &CreatePaymentResult{PaymentURL: res.PayUrl}, nil
+}
+
+// Xác thực IPN (POST JSON) hoặc redirect (query) của MoMo, hai nguồn dùng chung bộ tham số
+func (momoProvider) VerifyCallback(ctx context.Context, params url.Values) (*CallbackResult, error) {
+	signature := signMomo(
+		"amount", params.Get("amount"),
+		"extraData", params.Get("extraData"),
+		"message", params.Get("message"),
+		"orderId", params.Get("orderId"),
+		"orderInfo", params.Get("orderInfo"),
+		"orderType", params.Get("orderType"),
+		"partnerCode", params.Get("partnerCode"),
+		"payType", params.Get("payType"),
+		"requestId", params.Get("requestId"),
+		"responseTime", params.Get("responseTime"),
+		"resultCode", params.Get("resultCode"),
+		"transId", params.Get("transId"),
+	)
+	if !validSignature(params.Get("signature"), signature) {
+		return nil, ErrInvalidSignature
+	}
+	if params.Get("partnerCode") != strings.TrimSpace(configs.GetMoMoPartnerCode()) {
+		return nil, fmt.Errorf("%w: sai partnerCode", ErrInvalidSignature)
+	}
+
+	amount, _ := strconv.ParseInt(params.Get("amount"), 10, 64)
+	paidAt := time.Now()
+	if responseTime, err := strconv.ParseInt(params.Get("responseTime"), 10, 64); err == nil {
+		paidAt = time.UnixMilli(responseTime)
+	}
+	resultCode := params.Get("resultCode")
+
+	return &CallbackResult{
+		OrderID:       params.Get("orderId"),
+		Success:       resultCode == "0",
+		Amount:        amount,
+		TransactionNo: params.Get("transId"),
+		ResponseCode:  resultCode,
+		Message:       params.Get("message"),
+		PaidAt:        paidAt,
+	}, nil
+}
+
+func (momoProvider) QueryTransaction(ctx context.Context, req QueryTransactionRequest) (*TransactionResult, error) {
+	momoReq := MomoQueryRequest{
+		PartnerCode: strings.TrimSpace(configs.GetMoMoPartnerCode()),
+		RequestID:   fmt.Sprintf("%s-%d", req.OrderID, time.Now().UnixNano()),
+		OrderID:     req.OrderID,
+		Lang:        "vi",
+	}
+	momoReq.Signature = signMomo(
+		"orderId", momoReq.OrderID,
+		"partnerCode", momoReq.PartnerCode,
+		"requestId", momoReq.RequestID,
+	)
+
+	var res MomoQueryResponse
+	if err := postJSON(ctx, momoApiUrl("/v2/gateway/api/query"), momoReq, &res); err != nil {
+		return nil, fmt.Errorf("lỗi gọi API tra cứu MoMo: %w", err)
+	}
+
+	result := &TransactionResult{
+		OrderID:       req.OrderID,
+		Amount:        res.Amount,
+		ResponseCode:  strconv.Itoa(res.ResultCode),
+		Message:       res.Message,
+		TransactionNo: strconv.FormatInt(res.TransID, 10),
+	}
+	if res.ResponseTime > 0 {
+		result.PaidAt = time.UnixMilli(res.ResponseTime)
+	}
+
+	switch res.ResultCode {
+	case 0:
+		result.Status = TransactionSuccess
+	case 1000, 7000, 7002, 9000: // Đã khởi tạo / đang xử lý / đã xác nhận chờ capture
+		result.Status = TransactionPending
+	case 42: // Không tìm thấy orderId
+		result.Status = TransactionNotFound
+	case 11, 12, 13, 20, 22, 40, 41: // Lỗi request (quyền truy cập, chữ ký, định dạng)
+		return nil, fmt.Errorf("MoMo trả mã %d: %s", res.ResultCode, res.Message)
+	default:
+		result.Status = TransactionFailed
+	}
+	return result, nil
+}
+
+func (momoProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	transID, err := strconv.ParseInt(req.TransactionCode, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("mã giao dịch MoMo không hợp lệ: %s", req.TransactionCode)
+	}
+
+	momoReq := MomoRefundRequest{
+		PartnerCode: strings.TrimSpace(configs.GetMoMoPartnerCode()),
+		OrderID:     "RF-" + req.RefundID.Hex(),
+		RequestID:   fmt.Sprintf("%s-%d", req.RefundID.Hex(), time.Now().UnixNano()),
+		Amount:      req.Amount,
+		TransID:     transID,
+		Lang:        "vi",
+		Description: "Hoan tien don " + req.OrderID,
+	}
+	momoReq.Signature = signMomo(
+		"amount", strconv.FormatInt(momoReq.Amount, 10),
+		"description", momoReq.Description,
+		"orderId", momoReq.OrderID,
+		"partnerCode", momoReq.PartnerCode,
+		"requestId", momoReq.RequestID,
+		"transId", strconv.FormatInt(momoReq.TransID, 10),
+	)
+
+	var res MomoRefundResponse
+	if err := postJSON(ctx, momoApiUrl("/v2/gateway/api/refund"), momoReq, &res); err != nil {
+		return nil, fmt.Errorf("lỗi gọi API hoàn tiền MoMo: %w", err)
+	}
+	if res.ResultCode != 0 {
+		return nil, fmt.Errorf("MoMo trả mã %d: %s", res.ResultCode, res.Message)
+	}
+	return &RefundResult{ProviderRefundID: strconv.FormatInt(res.TransID, 10)}, nil
+}
+
+// MoMo chỉ cần HTTP 204 khi đã nhận IPN, lỗi hệ thống trả 500 để MoMo gửi lại
+func (momoProvider) IPNResponse(err error) (int, interface{}) {
+	if err != nil && !errors.Is(err, consts.ErrPaymentAlreadyConfirmed) && !isIPNRejected(err) {
+		return http.StatusInternalServerError, nil
+	}
+	return http.StatusNoContent, nil
+}
+
+// Ký HMAC-SHA256 theo định dạng accessKey=...&k1=v1&k2=v2 (các key truyền vào đã theo thứ tự alphabet)
+func signMomo(pairs ...string) string {
+	var rawData strings.Builder
+	rawData.WriteString("accessKey=")
+	rawData.WriteString(strings.TrimSpace(configs.GetMoMoAccessKey()))
+	for i := 0; i+1 < len(pairs); i += 2 {
+		rawData.WriteString("&")
+		rawData.WriteString(pairs[i])
+		rawData.WriteString("=")
+		rawData.WriteString(pairs[i+1])
+	}
+	return utils.HmacSha256(strings.TrimSpace(configs.GetMoMoSecretKey()), rawData.String())
+}
+
+func momoApiUrl(path string) string {
+	return strings.TrimRight(strings.TrimSpace(configs.GetMoMoEndpoint()), "/") + path
+}
@@ -0,0 +1,169 @@
+package payment
+
+import (
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrUnknownProvider  = errors.New("cổng thanh toán không được hỗ trợ")
+	ErrInvalidSignature = errors.New("sai chữ ký dữ liệu thanh toán")
+)
+
+// Trạng thái giao dịch khi tra cứu ở cổng thanh toán
+type TransactionStatus string
+
+const (
+	TransactionSuccess  TransactionStatus = "success"
+	TransactionFailed   TransactionStatus = "failed"
+	TransactionPending  TransactionStatus = "pending"
+	TransactionNotFound TransactionStatus = "not_found"
+)
+
+type CreatePaymentRequest struct {
+	OrderID   string
+	Amount    int64 // VNĐ
+	OrderInfo string
+	IPAddr    string
+	CreatedAt time.Time
+}
+
+type CreatePaymentResult struct {
+	PaymentURL string
+}
+
+// Kết quả callback/IPN đã được xác thực chữ ký
+type CallbackResult struct {
+	OrderID       string
+	Success       bool
+	Amount        int64 // VNĐ
+	TransactionNo string
+	ResponseCode  string
+	Message       string
+	PaidAt        time.Time
+}
+
+type QueryTransactionRequest struct {
+	OrderID   string
+	CreatedAt time.Time // Thời điểm tạo giao dịch thanh toán
+	IPAddr    string
+}
+
+type TransactionResult struct {
+	OrderID       string
+	Status        TransactionStatus
+	Amount        int64 // VNĐ
+	TransactionNo string
+	ResponseCode  string
+	Message       string
+	PaidAt        time.Time
+}
+
+type RefundRequest struct {
+	RefundID        primitive.ObjectID
+	OrderID         string
+	TransactionCode string
+	PaidAt          time.Time
+	Amount          int64 // VNĐ
+	FullRefund      bool
+	RequestedBy     string
+	IPAddr          string
+}
+
+type RefundResult struct {
+	ProviderRefundID string
+}
+
+// Cổng thanh toán: tạo link thanh toán, xác thực callback, tra cứu và hoàn tiền
+type Provider interface {
+	Name() string
+	CreatePayment(ctx context.Context, req CreatePaymentRequest) (*CreatePaymentResult, error)
+	VerifyCallback(ctx context.Context, params url.Values) (*CallbackResult, error)
+	QueryTransaction(ctx context.Context, req QueryTransactionRequest) (*TransactionResult, error)
+	Refund(ctx context.Context, req RefundRequest) (*RefundResult, error)
+}
+
+// Cổng yêu cầu định dạng phản hồi IPN riêng (MoMo, ZaloPay gửi IPN dạng POST JSON).
+// err = nil nghĩa là đơn đã được xử lý thành công.
+type IPNResponder interface {
+	IPNResponse(err error) (status int, body interface{})
+}
+
+// Lấy cổng thanh toán theo tên (không phân biệt hoa thường). Tên rỗng = VNPAY (đơn cũ chưa lưu cổng).
+func Get(name string) (Provider, error) {
+	switch strings.ToUpper(strings.TrimSpace(name)) {
+	case "", consts.PaymentMethodVNPAY:
+		return vnpayProvider{}, nil
+	case consts.PaymentMethodMoMo:
+		return momoProvider{}, nil
+	case consts.PaymentMethodZaloPay:
+		return zaloPayProvider{}, nil
+	case consts.PaymentMethodFree:
+		return freeProvider{}, nil
+	case consts.PaymentMethodFake:
+		if !configs.GetFakePaymentEnabled() {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+		}
+		return fakeProvider{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+}
+
+// Cổng thanh toán mặc định theo cấu hình payment.default_provider
+func Default() (Provider, error) {
+	return Get(configs.GetPaymentDefaultProvider())
+}
+
+// Gửi request JSON tới API của cổng thanh toán
+func postJSON(ctx context.Context, endpoint string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	httpRes, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+
+	return json.NewDecoder(httpRes.Body).Decode(out)
+}
+
+// So sánh chữ ký trong thời gian không đổi
+func validSignature(received, expected string) bool {
+	return hmac.Equal([]byte(received), []byte(expected))
+}
+
+// Link trình duyệt quay về sau khi thanh toán: chỉ hiển thị kết quả, trạng thái đơn do IPN/callback cập nhật
+func ReturnUrl(providerName string) string {
+	return strings.TrimSpace(configs.GetServerDomain()) + "/payments/" + providerName + "/return"
+}
+
+// Lỗi nghiệp vụ khi xử lý IPN (sai chữ ký, sai đơn, sai số tiền, đơn đã hủy): cổng gửi lại cũng không xử lý được
+func isIPNRejected(err error) bool {
+	return errors.Is(err, ErrInvalidSignature) ||
+		errors.Is(err, consts.ErrPaymentOrderNotFound) ||
+		errors.Is(err, consts.ErrPaymentInvalidAmount) ||
+		errors.Is(err, consts.ErrRegistrationNotPending)
+}
@@ -0,0 +1,487 @@
+package payment
+
+import (
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type VnpayRequest struct {
+	Version    string `json:"vnp_Version"`
+	Command    string `json:"vnp_Command"`
+	TmnCode    string `json:"vnp_TmnCode"`
+	Locale     string `json:"vnp_Locale"`
+	CurrCode   string `json:"vnp_CurrCode"`
+	TxnRef     string `json:"vnp_TxnRef"`
+	OrderInfo  string `json:"vnp_OrderInfo"`
+	OrderType  string `json:"vnp_OrderType"`
+	Amount     int64  `json:"vnp_Amount"` // This is amount * 100
+	ReturnUrl  string `json:"vnp_ReturnUrl"`
+	IpAddr     string `json:"vnp_IpAddr"`
+	CreateDate string `json:"vnp_CreateDate"`
+	ExpireDate string `json:"vnp_ExpireDate"`
+	SecureHash string `json:"vnp_SecureHash"`
+}
+
+// Hàm tạo URL thanh toán VNPAY (BuildVnpayURL)
+// Hàm này đã đúng
+func BuildVnpayURL(req VnpayRequest) string {
+	baseURL := strings.TrimSpace(configs.GetVNPAYUrl())
+
+	params := url.Values{}
+	params.Add("vnp_Version", req.Version)
+	params.Add("vnp_Command", req.Command)
+	params.Add("vnp_TmnCode", req.TmnCode)
+	params.Add("vnp_Locale", req.Locale)
+	params.Add("vnp_CurrCode", req.CurrCode)
+	params.Add("vnp_TxnRef", req.TxnRef)
+	params.Add("vnp_OrderInfo", req.OrderInfo)
+	params.Add("vnp_OrderType", req.OrderType)
+	params.Add("vnp_Amount", strconv.FormatInt(req.Amount, 10)) // Dùng req.Amount
+	params.Add("vnp_ReturnUrl", req.ReturnUrl)
+	params.Add("vnp_IpAddr", req.IpAddr)
+	params.Add("vnp_CreateDate", req.CreateDate)
+	params.Add("vnp_ExpireDate", req.ExpireDate)
+
+	encoded := params.Encode()
+
+	return baseURL + "?" + encoded +
+		"&vnp_SecureHashType=SHA512&vnp_SecureHash=" + req.SecureHash
+}
+
+// Tạo URL thanh toán (CreatePaymentURL)
+func CreatePaymentURL(orderID string, amount int64, orderInfo string, ipAdrr string, createdAt time.Time) (string, error) {
+	now := createdAt.In(vnpayLoc)
+
+	tmnCode := strings.TrimSpace(configs.GetVNPAYTmnCode())
+	hashSecret := strings.TrimSpace(configs.GetVNPAYHashSecret())
+	appBaseUrl := strings.TrimSpace(configs.GetServerDomain())
+
+	req := VnpayRequest{
+		Version:    "2.1.0",
+		Command:    "pay",
+		TmnCode:    tmnCode,
+		Locale:     "vn",
+		CurrCode:   "VND",
+		TxnRef:     orderID,
+		OrderInfo:  orderInfo,
+		OrderType:  "other",
+		Amount:     amount * 100, // (1) req.Amount = amount * 100
+		ReturnUrl:  appBaseUrl + "/vnpay_return",
+		IpAddr:     ipAdrr,
+		CreateDate: now.Format("20060102150405"),
+		ExpireDate: now.Add(15 * time.Minute).Format("20060102150405"),
+	}
+
+	// Build map params để tạo hash
+	params := map[string]string{
+		"vnp_Version":    req.Version,
+		"vnp_Command":    req.Command,
+		"vnp_TmnCode":    req.TmnCode,
+		"vnp_Locale":     req.Locale,
+		"vnp_CurrCode":   req.CurrCode,
+		"vnp_TxnRef":     req.TxnRef,
+		"vnp_OrderInfo":  req.OrderInfo,
+		"vnp_OrderType":  req.OrderType,
+		"vnp_Amount":     strconv.FormatInt(req.Amount, 10), // (2) Dùng req.Amount để hash. Đây là logic ĐÚNG.
+		"vnp_ReturnUrl":  req.ReturnUrl,
+		"vnp_IpAddr":     req.IpAddr,
+		"vnp_CreateDate": req.CreateDate,
+		"vnp_ExpireDate": req.ExpireDate,
+	}
+
+	// Loại bỏ các tham số rỗng
+	for k, v := range params {
+		if v == "" {
+			delete(params, k)
+		}
+	}
+
+	// Sort keys theo thứ tự alphabet
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Build rawData để tạo hash
+	var rawData strings.Builder
+	for i, k := range keys {
+		rawData.WriteString(k)
+		rawData.WriteString("=")
+		// SỬA LỖI QUAN TRỌNG:
+		// Giá trị (value) PHẢI được URL Encode (giống hệt Java demo)
+		rawData.WriteString(url.QueryEscape(params[k]))
+		if i < len(keys)-1 {
+			rawData.WriteString("&")
+		}
+	}
+
+	// Tạo chữ ký
+	req.SecureHash = utils.HmacSha512(hashSecret, rawData.String())
+
+	return BuildVnpayURL(req), nil
+}
+
+// HÀM 2: VERIFY IPN
+func VerifyIPNChecksum(vnpParams url.Values) error {
+	receivedHash := vnpParams.Get("vnp_SecureHash")
+	hashSecret := strings.TrimSpace(configs.GetVNPAYHashSecret())
+
+	var keys []string
+	for k := range vnpParams {
+		if k == "vnp_SecureHash" || k == "vnp_SecureHashType" {
+			continue
+		}
+		if vnpParams.Get(k) == "" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var hashDataBuffer strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			hashDataBuffer.WriteString("&")
+		}
+		hashDataBuffer.WriteString(k)
+		hashDataBuffer.WriteString("=")
+		hashDataBuffer.WriteString(url.QueryEscape(vnpParams.Get(k)))
+	}
+
+	myHash := utils.HmacSha512(hashSecret, hashDataBuffer.String())
+
+	if myHash != receivedHash {
+		return fmt.Errorf("sai chữ ký cho đơn %s", vnpParams.Get("vnp_TxnRef"))
+	}
+
+	return nil
+}
+
+func ResponsePaymentMessage(code string) string {
+	switch code {
+	case "00":
+		return "Giao dịch thành công"
+	case "07":
+		return "Trừ tiền thành công. Giao dịch bị nghi ngờ (liên quan tới lừa đảo, giao dịch bất thường)."
+	case "09":
+		return "Thẻ/Tài khoản của khách hàng chưa đăng ký dịch vụ InternetBanking tại ngân hàng."
+	case "10":
+		return "Khách hàng xác thực thông tin thẻ/tài khoản không đúng quá 3 lần."
+	case "11":
+		return "Đã hết hạn chờ thanh toán. Vui lòng thực hiện lại giao dịch."
+	case "12":
+		return "Thẻ/Tài khoản của khách hàng bị khóa."
+	case "13":
+		return "Quý khách nhập sai mật khẩu xác thực giao dịch (OTP). Vui lòng thực hiện lại giao dịch."
+	case "24":
+		return "Khách hàng hủy giao dịch."
+	case "51":
+		return "Tài khoản của quý khách không đủ số dư để thực hiện giao dịch."
+	case "65":
+		return "Tài khoản của quý khách đã vượt quá hạn mức giao dịch trong ngày."
+	case "75":
+		return "Ngân hàng thanh toán đang bảo trì."
+	case "79":
+		return "Khách hàng nhập sai mật khẩu thanh toán quá số lần quy định. Vui lòng thực hiện lại giao dịch."
+	case "99":
+		return "Lỗi khác (không có trong danh sách mã lỗi)."
+	default:
+		return "Không xác định mã lỗi."
+	}
+}
+
+type VnpayRefundRequest struct {
+	RequestID       string `json:"vnp_RequestId"`
+	Version         string `json:"vnp_Version"`
+	Command         string `json:"vnp_Command"`
+	TmnCode         string `json:"vnp_TmnCode"`
+	TransactionType string `json:"vnp_TransactionType"` // 02: hoàn toàn phần, 03: hoàn một phần
+	TxnRef          string `json:"vnp_TxnRef"`
+	Amount          int64  `json:"vnp_Amount"` // amount * 100
+	OrderInfo       string `json:"vnp_OrderInfo"`
+	TransactionNo   string `json:"vnp_TransactionNo"`
+	TransactionDate string `json:"vnp_TransactionDate"`
+	CreateBy        string `json:"vnp_CreateBy"`
+	CreateDate      string `json:"vnp_CreateDate"`
+	IpAddr          string `json:"vnp_IpAddr"`
+	SecureHash      string `json:"vnp_SecureHash"`
+}
+
+type VnpayRefundResponse struct {
+	ResponseID        string `json:"vnp_ResponseId"`
+	Command           string `json:"vnp_Command"`
+	ResponseCode      string `json:"vnp_ResponseCode"`
+	Message           string `json:"vnp_Message"`
+	TmnCode           string `json:"vnp_TmnCode"`
+	TxnRef            string `json:"vnp_TxnRef"`
+	Amount            string `json:"vnp_Amount"`
+	BankCode          string `json:"vnp_BankCode"`
+	PayDate           string `json:"vnp_PayDate"`
+	TransactionNo     string `json:"vnp_TransactionNo"`
+	TransactionType   string `json:"vnp_TransactionType"`
+	TransactionStatus string `json:"vnp_TransactionStatus"`
+	OrderInfo         string `json:"vnp_OrderInfo"`
+	SecureHash        string `json:"vnp_SecureHash"`
+}
+
+// Gọi API hoàn tiền của VNPAY (merchant_webapi, vnp_Command=refund)
+func VNPAYRefund(ctx context.Context, txnRef, transactionNo string, transactionDate time.Time, amount int64, fullRefund bool, createBy, ipAddr string) (*VnpayRefundResponse, error) {
+	now := time.Now().In(vnpayLoc)
+
+	transactionType := "03"
+	if fullRefund {
+		transactionType = "02"
+	}
+
+	req := VnpayRefundRequest{
+		RequestID:       strconv.FormatInt(now.UnixNano(), 10),
+		Version:         "2.1.0",
+		Command:         "refund",
+		TmnCode:         strings.TrimSpace(configs.GetVNPAYTmnCode()),
+		TransactionType: transactionType,
+		TxnRef:          txnRef,
+		Amount:          amount * 100,
+		OrderInfo:       "Hoan tien don " + txnRef,
+		TransactionNo:   transactionNo,
+		TransactionDate: transactionDate.In(vnpayLoc).Format("20060102150405"),
+		CreateBy:        createBy,
+		CreateDate:      now.Format("20060102150405"),
+		IpAddr:          ipAddr,
+	}
+
+	hashData := strings.Join([]string{
+		req.RequestID, req.Version, req.Command, req.TmnCode, req.TransactionType, req.TxnRef,
+		strconv.FormatInt(req.Amount, 10), req.TransactionNo, req.TransactionDate, req.CreateBy,
+		req.CreateDate, req.IpAddr, req.OrderInfo,
+	}, "|")
+	hashSecret := strings.TrimSpace(configs.GetVNPAYHashSecret())
+	req.SecureHash = utils.HmacSha512(hashSecret, hashData)
+
+	var res VnpayRefundResponse
+	if err := postVnpayAPI(ctx, req, &res); err != nil {
+		return nil, fmt.Errorf("lỗi gọi API hoàn tiền VNPAY: %w", err)
+	}
+
+	// Kiểm tra chữ ký phản hồi
+	if res.SecureHash != "" {
+		resHashData := strings.Join([]string{
+			res.ResponseID, res.Command, res.ResponseCode, res.Message, res.TmnCode, res.TxnRef,
+			res.Amount, res.BankCode, res.PayDate, res.TransactionNo, res.TransactionType,
+			res.TransactionStatus, res.OrderInfo,
+		}, "|")
+		if utils.HmacSha512(hashSecret, resHashData) != res.SecureHash {
+			return nil, fmt.Errorf("sai chữ ký phản hồi hoàn tiền VNPAY")
+		}
+	}
+
+	return &res, nil
+}
+
+type VnpayQueryRequest struct {
+	RequestID       string `json:"vnp_RequestId"`
+	Version         string `json:"vnp_Version"`
+	Command         string `json:"vnp_Command"`
+	TmnCode         string `json:"vnp_TmnCode"`
+	TxnRef          string `json:"vnp_TxnRef"`
+	OrderInfo       string `json:"vnp_OrderInfo"`
+	TransactionDate string `json:"vnp_TransactionDate"`
+	CreateDate      string `json:"vnp_CreateDate"`
+	IpAddr          string `json:"vnp_IpAddr"`
+	SecureHash      string `json:"vnp_SecureHash"`
+}
+
+type VnpayQueryResponse struct {
+	ResponseID        string `json:"vnp_ResponseId"`
+	Command           string `json:"vnp_Command"`
+	ResponseCode      string `json:"vnp_ResponseCode"`
+	Message           string `json:"vnp_Message"`
+	TmnCode           string `json:"vnp_TmnCode"`
+	TxnRef            string `json:"vnp_TxnRef"`
+	Amount            string `json:"vnp_Amount"`
+	BankCode          string `json:"vnp_BankCode"`
+	PayDate           string `json:"vnp_PayDate"`
+	TransactionNo     string `json:"vnp_TransactionNo"`
+	TransactionType   string `json:"vnp_TransactionType"`
+	TransactionStatus string `json:"vnp_TransactionStatus"`
+	OrderInfo         string `json:"vnp_OrderInfo"`
+	PromotionCode     string `json:"vnp_PromotionCode"`
+	PromotionAmount   string `json:"vnp_PromotionAmount"`
+	SecureHash        string `json:"vnp_SecureHash"`
+}
+
+// Tra cứu giao dịch (merchant_webapi, vnp_Command=querydr)
+func VNPAYQueryDR(ctx context.Context, txnRef string, transactionDate time.Time, ipAddr string) (*VnpayQueryResponse, error) {
+	now := time.Now().In(vnpayLoc)
+
+	req := VnpayQueryRequest{
+		RequestID:       strconv.FormatInt(now.UnixNano(), 10),
+		Version:         "2.1.0",
+		Command:         "querydr",
+		TmnCode:         strings.TrimSpace(configs.GetVNPAYTmnCode()),
+		TxnRef:          txnRef,
+		OrderInfo:       "Tra cuu don " + txnRef,
+		TransactionDate: transactionDate.In(vnpayLoc).Format("20060102150405"),
+		CreateDate:      now.Format("20060102150405"),
+		IpAddr:          ipAddr,
+	}
+
+	hashData := strings.Join([]string{
+		req.RequestID, req.Version, req.Command, req.TmnCode, req.TxnRef,
+		req.TransactionDate, req.CreateDate, req.IpAddr, req.OrderInfo,
+	}, "|")
+	hashSecret := strings.TrimSpace(configs.GetVNPAYHashSecret())
+	req.SecureHash = utils.HmacSha512(hashSecret, hashData)
+
+	var res VnpayQueryResponse
+	if err := postVnpayAPI(ctx, req, &res); err != nil {
+		return nil, fmt.Errorf("lỗi gọi API tra cứu VNPAY: %w", err)
+	}
+
+	if res.SecureHash != "" {
+		resHashData := strings.Join([]string{
+			res.ResponseID, res.Command, res.ResponseCode, res.Message, res.TmnCode, res.TxnRef,
+			res.Amount, res.BankCode, res.PayDate, res.TransactionNo, res.TransactionType,
+			res.TransactionStatus, res.OrderInfo, res.PromotionCode, res.PromotionAmount,
+		}, "|")
+		if utils.HmacSha512(hashSecret, resHashData) != res.SecureHash {
+			return nil, fmt.Errorf("sai chữ ký phản hồi tra cứu VNPAY")
+		}
+	}
+
+	return &res, nil
+}
+
+// Gửi request JSON tới merchant_webapi của VNPAY
+func postVnpayAPI(ctx context.Context, payload interface{}, out interface{}) error {
+	return postJSON(ctx, strings.TrimSpace(configs.GetVNPAYApiUrl()), payload, out)
+}
+
+var vnpayLoc = time.FixedZone("Asia/Ho_Chi_Minh", 7*60*60)
+
+type vnpayProvider struct{}
+
+func (vnpayProvider) Name() string {
+	return consts.PaymentMethodVNPAY
+}
+
+func (vnpayProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*CreatePaymentResult, error) {
+	createdAt := req.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	paymentURL, err := CreatePaymentURL(req.OrderID, req.Amount, req.OrderInfo, req.IPAddr, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	return &CreatePaymentResult{PaymentURL: paymentURL}, nil
+}
+
+func (vnpayProvider) VerifyCallback(ctx context.Context, params url.Values) (*CallbackResult, error) {
+	if err := VerifyIPNChecksum(params); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	amount, _ := strconv.ParseInt(params.Get("vnp_Amount"), 10, 64)
+	responseCode := params.Get("vnp_ResponseCode")
+	transactionStatus := params.Get("vnp_TransactionStatus")
+	paidAt, err := time.ParseInLocation("20060102150405", params.Get("vnp_PayDate"), vnpayLoc)
+	if err != nil {
+		paidAt = time.Now()
+	}
+
+	return &CallbackResult{
+		OrderID:       params.Get("vnp_TxnRef"),
+		Success:       responseCode == "00" && (transactionStatus == "" || transactionStatus == "00"),
+		Amount:        amount / 100,
+		TransactionNo: params.Get("vnp_TransactionNo"),
+		ResponseCode:  responseCode,
+		Message:       ResponsePaymentMessage(responseCode),
+		PaidAt:        paidAt,
+	}, nil
+}
+
+func (vnpayProvider) QueryTransaction(ctx context.Context, req QueryTransactionRequest) (*TransactionResult, error) {
+	ipAddr := req.IPAddr
+	if ipAddr == "" {
+		ipAddr = "127.0.0.1"
+	}
+	res, err := VNPAYQueryDR(ctx, req.OrderID, req.CreatedAt, ipAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &TransactionResult{
+		OrderID:       req.OrderID,
+		TransactionNo: res.TransactionNo,
+		ResponseCode:  res.ResponseCode,
+		Message:       res.Message,
+	}
+	amount, _ := strconv.ParseInt(res.Amount, 10, 64)
+	result.Amount = amount / 100
+	if paidAt, err := time.ParseInLocation("20060102150405", res.PayDate, vnpayLoc); err == nil {
+		result.PaidAt = paidAt
+	}
+
+	switch {
+	case res.ResponseCode == "91": // Không tìm thấy giao dịch
+		result.Status = TransactionNotFound
+	case res.ResponseCode != "00":
+		return nil, fmt.Errorf("VNPAY trả mã %s: %s", res.ResponseCode, res.Message)
+	case res.TransactionStatus == "00":
+		result.Status = TransactionSuccess
+	case res.TransactionStatus == "01":
+		result.Status = TransactionPending
+	default:
+		result.Status = TransactionFailed
+	}
+	return result, nil
+}
+
+func (vnpayProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	ipAddr := req.IPAddr
+	if ipAddr == "" {
+		ipAddr = "127.0.0.1"
+	}
+	res, err := VNPAYRefund(ctx, req.OrderID, req.TransactionCode, req.PaidAt, req.Amount, req.FullRefund, req.RequestedBy, ipAddr)
+	if err != nil {
+		return nil, err
+	}
+	if res.ResponseCode != "00" {
+		return nil, fmt.Errorf("VNPAY trả mã %s: %s", res.ResponseCode, res.Message)
+	}
+	return &RefundResult{ProviderRefundID: res.TransactionNo}, nil
+}
+
+// Phản hồi IPN theo đúng bảng RspCode của VNPAY
+type VnpayIPNResponse struct {
+	RspCode string `json:"RspCode"`
+	Message string `json:"Message"`
+}
+
+func (vnpayProvider) IPNResponse(err error) (int, interface{}) {
+	switch {
+	case err == nil:
+		return http.StatusOK, VnpayIPNResponse{RspCode: "00", Message: "Confirm Success"}
+	case errors.Is(err, ErrInvalidSignature):
+		return http.StatusOK, VnpayIPNResponse{RspCode: "97", Message: "Invalid Checksum"}
+	case errors.Is(err, consts.ErrPaymentOrderNotFound):
+		return http.StatusOK, VnpayIPNResponse{RspCode: "01", Message: "Order not found"}
+	case errors.Is(err, consts.ErrPaymentAlreadyConfirmed), errors.Is(err, consts.ErrRegistrationNotPending):
+		return http.StatusOK, VnpayIPNResponse{RspCode: "02", Message: "Order already confirmed"}
+	case errors.Is(err, consts.ErrPaymentInvalidAmount):
+		return http.StatusOK, VnpayIPNResponse{RspCode: "04", Message: "Invalid amount"}
+	default:
+		return http.StatusOK, VnpayIPNResponse{RspCode: "99", Message: "Unknown error"}
+	}
+}
@@ -0,0 +1,299 @@
+package payment
+
+import (
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ZaloPayCreateResponse struct {
+	ReturnCode       int    `json:"return_code"`
+	ReturnMessage    string `json:"return_message"`
+	SubReturnCode    int    `json:"sub_return_code"`
+	SubReturnMessage string `json:"sub_return_message"`
+	OrderUrl         string `json:"order_url"`
+	ZpTransToken     string `json:"zp_trans_token"`
+}
+
+// Dữ liệu trong trường data của callback ZaloPay
+type ZaloPayCallbackData struct {
+	AppID          int64  `json:"app_id"`
+	AppTransID     string `json:"app_trans_id"`
+	AppTime        int64  `json:"app_time"`
+	AppUser        string `json:"app_user"`
+	Amount         int64  `json:"amount"`
+	EmbedData      string `json:"embed_data"`
+	Item           string `json:"item"`
+	ZpTransID      int64  `json:"zp_trans_id"`
+	ServerTime     int64  `json:"server_time"`
+	Channel        int    `json:"channel"`
+	MerchantUserID string `json:"merchant_user_id"`
+	UserFeeAmount  int64  `json:"user_fee_amount"`
+	DiscountAmount int64  `json:"discount_amount"`
+}
+
+type ZaloPayQueryResponse struct {
+	ReturnCode       int    `json:"return_code"`
+	ReturnMessage    string `json:"return_message"`
+	SubReturnCode    int    `json:"sub_return_code"`
+	SubReturnMessage string `json:"sub_return_message"`
+	IsProcessing     bool   `json:"is_processing"`
+	Amount           int64  `json:"amount"`
+	ZpTransID        int64  `json:"zp_trans_id"`
+	ServerTime       int64  `json:"server_time"`
+}
+
+type ZaloPayRefundResponse struct {
+	ReturnCode       int    `json:"return_code"`
+	ReturnMessage    string `json:"return_message"`
+	SubReturnCode    int    `json:"sub_return_code"`
+	SubReturnMessage string `json:"sub_return_message"`
+	RefundID         int64  `json:"refund_id"`
+}
+
+// Phản hồi callback theo yêu cầu của ZaloPay
+type ZaloPayIPNResponse struct {
+	ReturnCode    int    `json:"return_code"`
+	ReturnMessage string `json:"return_message"`
+}
+
+type zaloPayProvider struct{}
+
+func (zaloPayProvider) Name() string {
+	return consts.PaymentMethodZaloPay
+}
+
+func (zaloPayProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*CreatePaymentResult, error) {
+	createdAt := req.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	callbackUrl := strings.TrimSpace(configs.GetServerDomain()) + "/payments/" + consts.PaymentMethodZaloPay + "/callback"
+	embedData, _ := json.Marshal(map[string]string{"redirecturl": ReturnUrl(consts.PaymentMethodZaloPay)})
+
+	appID := strings.TrimSpace(configs.GetZaloPayAppID())
+	appTransID := zaloPayAppTransID(req.OrderID, createdAt)
+	appUser := "EventHunting"
+	amount := strconv.FormatInt(req.Amount, 10)
+	appTime := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	item := "[]"
+
+	params := url.Values{}
+	params.Set("app_id", appID)
+	params.Set("app_user", appUser)
+	params.Set("app_trans_id", appTransID)
+	params.Set("app_time", appTime)
+	params.Set("amount", amount)
+	params.Set("item", item)
+	params.Set("embed_data", string(embedData))
+	params.Set("description", req.OrderInfo)
+	params.Set("bank_code", "")
+	params.Set("callback_url", callbackUrl)
+	params.Set("mac", utils.HmacSha256(strings.TrimSpace(configs.GetZaloPayKey1()),
+		strings.Join([]string{appID, appTransID, appUser, amount, appTime, string(embedData), item}, "|")))
+
+	var res ZaloPayCreateResponse
+	if err := postZaloPayAPI(ctx, "/v2/create", params, &res); err != nil {
+		return nil, fmt.Errorf("lỗi gọi API tạo đơn ZaloPay: %w", err)
+	}
+	if res.ReturnCode != 1 || res.OrderUrl == "" {
+		return nil, fmt.Errorf("ZaloPay trả mã %d/%d: %s", res.ReturnCode, res.SubReturnCode, res.SubReturnMessage)
+	}
+	return &CreatePaymentResult{PaymentURL: res.OrderUrl}, nil
+}
+
+// Callback (POST JSON {data, mac}) ký bằng key2; redirect trình duyệt (query, checksum) chỉ báo trạng thái nên
+// phải tra cứu lại để lấy zp_trans_id
+func (p zaloPayProvider) VerifyCallback(ctx context.Context, params url.Values) (*CallbackResult, error) {
+	key2 := strings.TrimSpace(configs.GetZaloPayKey2())
+
+	if data := params.Get("data"); data != "" {
+		if !validSignature(params.Get("mac"), utils.HmacSha256(key2, data)) {
+			return nil, ErrInvalidSignature
+		}
+
+		var cbData ZaloPayCallbackData
+		if err := json.Unmarshal([]byte(data), &cbData); err != nil {
+			return nil, fmt.Errorf("dữ liệu callback ZaloPay không hợp lệ: %w", err)
+		}
+		paidAt := time.Now()
+		if cbData.ServerTime > 0 {
+			paidAt = time.UnixMilli(cbData.ServerTime)
+		}
+
+		// ZaloPay chỉ gửi callback khi thanh toán thành công
+		return &CallbackResult{
+			OrderID:       zaloPayOrderID(cbData.AppTransID),
+			Success:       true,
+			Amount:        cbData.Amount,
+			TransactionNo: strconv.FormatInt(cbData.ZpTransID, 10),
+			ResponseCode:  "1",
+			Message:       "Giao dịch thành công",
+			PaidAt:        paidAt,
+		}, nil
+	}
+
+	checksum := utils.HmacSha256(key2, strings.Join([]string{
+		params.Get("appid"), params.Get("apptransid"), params.Get("pmcid"), params.Get("bankcode"),
+		params.Get("amount"), params.Get("discountamount"), params.Get("status"),
+	}, "|"))
+	if !validSignature(params.Get("checksum"), checksum) {
+		return nil, ErrInvalidSignature
+	}
+
+	orderID := zaloPayOrderID(params.Get("apptransid"))
+	amount, _ := strconv.ParseInt(params.Get("amount"), 10, 64)
+	result := &CallbackResult{
+		OrderID:      orderID,
+		Amount:       amount,
+		ResponseCode: params.Get("status"),
+		Message:      "Giao dịch không thành công",
+	}
+	if params.Get("status") != "1" {
+		return result, nil
+	}
+
+	transaction, err := p.queryAppTransID(ctx, orderID, params.Get("apptransid"))
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Status == TransactionSuccess {
+		result.Success = true
+		result.Amount = transaction.Amount
+		result.TransactionNo = transaction.TransactionNo
+		result.Message = transaction.Message
+		result.PaidAt = transaction.PaidAt
+	}
+	return result, nil
+}
+
+func (p zaloPayProvider) QueryTransaction(ctx context.Context, req QueryTransactionRequest) (*TransactionResult, error) {
+	createdAt := req.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	return p.queryAppTransID(ctx, req.OrderID, zaloPayAppTransID(req.OrderID, createdAt))
+}
+
+func (zaloPayProvider) queryAppTransID(ctx context.Context, orderID, appTransID string) (*TransactionResult, error) {
+	appID := strings.TrimSpace(configs.GetZaloPayAppID())
+	key1 := strings.TrimSpace(configs.GetZaloPayKey1())
+
+	params := url.Values{}
+	params.Set("app_id", appID)
+	params.Set("app_trans_id", appTransID)
+	params.Set("mac", utils.HmacSha256(key1, strings.Join([]string{appID, appTransID, key1}, "|")))
+
+	var res ZaloPayQueryResponse
+	if err := postZaloPayAPI(ctx, "/v2/query", params, &res); err != nil {
+		return nil, fmt.Errorf("lỗi gọi API tra cứu ZaloPay: %w", err)
+	}
+
+	result := &TransactionResult{
+		OrderID:      orderID,
+		Amount:       res.Amount,
+		ResponseCode: strconv.Itoa(res.ReturnCode),
+		Message:      res.ReturnMessage,
+	}
+	if res.ZpTransID > 0 {
+		result.TransactionNo = strconv.FormatInt(res.ZpTransID, 10)
+	}
+	if res.ServerTime > 0 {
+		result.PaidAt = time.UnixMilli(res.ServerTime)
+	}
+
+	switch res.ReturnCode {
+	case 1:
+		result.Status = TransactionSuccess
+	case 3: // Chưa thanh toán hoặc đang xử lý
+		result.Status = TransactionPending
+	default:
+		result.Status = TransactionFailed
+	}
+	return result, nil
+}
+
+func (zaloPayProvider) Refund(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	appID := strings.TrimSpace(configs.GetZaloPayAppID())
+	amount := strconv.FormatInt(req.Amount, 10)
+	description := "Hoan tien don " + req.OrderID
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	params := url.Values{}
+	params.Set("app_id", appID)
+	params.Set("m_refund_id", time.Now().In(vnpayLoc).Format("060102")+"_"+appID+"_"+req.RefundID.Hex())
+	params.Set("zp_trans_id", req.TransactionCode)
+	params.Set("amount", amount)
+	params.Set("timestamp", timestamp)
+	params.Set("description", description)
+	params.Set("mac", utils.HmacSha256(strings.TrimSpace(configs.GetZaloPayKey1()),
+		strings.Join([]string{appID, req.TransactionCode, amount, description, timestamp}, "|")))
+
+	var res ZaloPayRefundResponse
+	if err := postZaloPayAPI(ctx, "/v2/refund", params, &res); err != nil {
+		return nil, fmt.Errorf("lỗi gọi API hoàn tiền ZaloPay: %w", err)
+	}
+	switch res.ReturnCode {
+	case 1:
+	case 3:
+		log.Printf("INFO: [ZALOPAY] Yêu cầu hoàn %s VNĐ cho đơn %s đang được xử lý (refund_id %d)", amount, req.OrderID, res.RefundID)
+	default:
+		return nil, fmt.Errorf("ZaloPay trả mã %d/%d: %s", res.ReturnCode, res.SubReturnCode, res.SubReturnMessage)
+	}
+	return &RefundResult{ProviderRefundID: strconv.FormatInt(res.RefundID, 10)}, nil
+}
+
+// return_code: 1 = thành công, 2 = đã xử lý trước đó, 0 = ZaloPay gửi lại callback, còn lại = từ chối
+func (zaloPayProvider) IPNResponse(err error) (int, interface{}) {
+	switch {
+	case err == nil:
+		return http.StatusOK, ZaloPayIPNResponse{ReturnCode: 1, ReturnMessage: "success"}
+	case errors.Is(err, consts.ErrPaymentAlreadyConfirmed):
+		return http.StatusOK, ZaloPayIPNResponse{ReturnCode: 2, ReturnMessage: err.Error()}
+	case isIPNRejected(err):
+		return http.StatusOK, ZaloPayIPNResponse{ReturnCode: -1, ReturnMessage: err.Error()}
+	default:
+		return http.StatusOK, ZaloPayIPNResponse{ReturnCode: 0, ReturnMessage: err.Error()}
+	}
+}
+
+// Mã giao dịch ZaloPay bắt buộc dạng yymmdd_xxx theo ngày giờ Việt Nam
+func zaloPayAppTransID(orderID string, createdAt time.Time) string {
+	return createdAt.In(vnpayLoc).Format("060102") + "_" + orderID
+}
+
+func zaloPayOrderID(appTransID string) string {
+	if idx := strings.Index(appTransID, "_"); idx >= 0 {
+		return appTransID[idx+1:]
+	}
+	return appTransID
+}
+
+// Gửi request form-urlencoded tới API của ZaloPay
+func postZaloPayAPI(ctx context.Context, path string, params url.Values, out interface{}) error {
+	endpoint := strings.TrimRight(strings.TrimSpace(configs.GetZaloPayEndpoint()), "/") + path
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	httpRes, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpRes.Body.Close()
+
+	return json.NewDecoder(httpRes.Body).Decode(out)
+}
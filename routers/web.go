@@ -103,7 +103,12 @@ func Register(router *gin.RouterGroup) {
 		eventRouter.GET("/:id/detail", middlewares.OptionalAuthMiddleware(), controllers.GetEvent)
 		eventRouter.GET("/search", controllers.GetListEvents)
 		eventRouter.GET("/:id/ticket_types", controllers.GetListTicketTypes)
-		eventRouter.POST("/:id/registration", middlewares.AuthorizeJWTMiddleware(), controllers.RegistrationEvent)
+		eventRouter.POST("/:id/registration", middlewares.AuthorizeJWTMiddleware(), middlewares.WaitingRoomMiddleware(), middlewares.IdempotencyMiddleware(), controllers.RegistrationEvent)
+		eventRouter.POST("/:id/waitlist", middlewares.AuthorizeJWTMiddleware(), controllers.JoinWaitlist)
+		eventRouter.POST("/:id/waiting-room", middlewares.AuthorizeJWTMiddleware(), controllers.JoinWaitingRoom)
+		eventRouter.GET("/:id/waiting-room", middlewares.AuthorizeJWTMiddleware(), controllers.GetWaitingRoomStatus)
+		eventRouter.POST("/:id/lottery", middlewares.AuthorizeJWTMiddleware(), controllers.EnterLottery)
+		eventRouter.GET("/:id/attendees/export", middlewares.AuthorizeJWTMiddleware(), controllers.ExportEventAttendees)
 		eventRouter.GET("/:id/comments", controllers.GetCommentFromEvent)
 	}
 
@@ -126,13 +131,104 @@ func Register(router *gin.RouterGroup) {
 		ticketTypeRouter.PATCH("/:id/update", controllers.UpdateTicketType)
 	}
 
+	//Waitlist
+	waitlistRouter := router.Group("waitlist")
+	{
+		waitlistRouter.Use(middlewares.AuthorizeJWTMiddleware())
+		waitlistRouter.GET("/me", controllers.GetMyWaitlist)
+		waitlistRouter.PATCH("/:id/cancel", controllers.CancelMyWaitlist)
+		waitlistRouter.GET("/events/:id", controllers.GetEventWaitlist)
+	}
+
+	//Lottery
+	lotteryRouter := router.Group("lottery")
+	{
+		lotteryRouter.GET("/events/:id/draws", controllers.GetEventLotteryDraws)
+		lotteryRouter.GET("/draws/:id/verify", controllers.VerifyLotteryDraw)
+		lotteryRouter.GET("/me", middlewares.AuthorizeJWTMiddleware(), controllers.GetMyLotteryEntries)
+		lotteryRouter.PATCH("/:id/cancel", middlewares.AuthorizeJWTMiddleware(), controllers.CancelMyLotteryEntry)
+		lotteryRouter.GET("/events/:id/entries", middlewares.AuthorizeJWTMiddleware(), controllers.GetEventLotteryEntries)
+	}
+
+	//Promo Code
+	promoCodeRouter := router.Group("promo_codes")
+	{
+		promoCodeRouter.Use(middlewares.AuthorizeJWTMiddleware())
+		promoCodeRouter.POST("/add", controllers.CreatePromoCode)
+		promoCodeRouter.PATCH("/:id/update", controllers.UpdatePromoCode)
+		promoCodeRouter.GET("/events/:id", controllers.GetEventPromoCodes)
+	}
+
+	//Ticket (check-in tại cổng, đường dẫn cố định theo mã QR trong email vé)
+	ticketCheckInRouter := router.Group("ticket")
+	{
+		ticketCheckInRouter.Use(middlewares.AuthorizeJWTMiddleware())
+		ticketCheckInRouter.GET("/checkin/token", controllers.CheckInTicket)
+		ticketCheckInRouter.POST("/checkin/token", controllers.CheckInTicket)
+		ticketCheckInRouter.GET("/checkin/keys", controllers.GetCheckInPublicKeys)
+		ticketCheckInRouter.GET("/checkin/events/:id/manifest", controllers.GetCheckInManifest)
+		ticketCheckInRouter.POST("/checkin/events/:id/sync", controllers.SyncOfflineCheckIns)
+		ticketCheckInRouter.GET("/checkin/events/:id/conflicts", controllers.GetCheckInConflicts)
+	}
+
+	//Ticket
+	ticketRouter := router.Group("tickets")
+	{
+		ticketRouter.Use(middlewares.AuthorizeJWTMiddleware())
+		ticketRouter.GET("/me", controllers.GetMyTickets)
+		ticketRouter.GET("/:id/detail", controllers.GetTicket)
+		ticketRouter.GET("/:id/qr", controllers.GetTicketQRCode)
+	}
+
+	//Registration (đơn đăng ký của người dùng)
+	registrationRouter := router.Group("registrations")
+	{
+		registrationRouter.Use(middlewares.AuthorizeJWTMiddleware())
+		registrationRouter.GET("/me", controllers.GetMyRegistrations)
+		registrationRouter.GET("/:id/detail", controllers.GetMyRegistration)
+		registrationRouter.PATCH("/:id/cancel", controllers.CancelMyRegistration)
+		registrationRouter.POST("/:id/refund", middlewares.IdempotencyMiddleware(), controllers.CreateRefund)
+		registrationRouter.GET("/:id/payment-events", controllers.GetRegistrationPaymentEvents)
+	}
+
+	//Refund
+	refundRouter := router.Group("refunds")
+	{
+		refundRouter.Use(middlewares.AuthorizeJWTMiddleware())
+		refundRouter.GET("/me", controllers.GetMyRefunds)
+		refundRouter.GET("/events/:id", controllers.GetEventRefunds)
+		refundRouter.PATCH("/:id/approve", controllers.ApproveRefund)
+		refundRouter.PATCH("/:id/reject", controllers.RejectRefund)
+	}
+
 	//Media
 	mediaRouter := router.Group("medias")
 	{
 		mediaRouter.POST("/upload", middlewares.MaxBodySizeMiddleware(10*1024*1024), controllers.UploadMedia)
 	}
 
-	router.GET("/vnpay_return", func(c *gin.Context) {
-		controllers.HandleCallbackVNPAY(c)
-	})
+	router.GET("/vnpay_return", controllers.HandleReturnVNPAY)
+	router.GET("/vnpay_ipn", controllers.HandleIPNVNPAY)
+
+	//Invoice
+	invoiceRouter := router.Group("invoices")
+	{
+		invoiceRouter.Use(middlewares.AuthorizeJWTMiddleware())
+		invoiceRouter.GET("/:id/pdf", controllers.DownloadInvoicePDF)
+		invoiceRouter.GET("/:id/einvoice/xml", controllers.ExportEInvoiceXML)
+		invoiceRouter.POST("/:id/einvoice/submit", controllers.SubmitEInvoice)
+	}
+
+	//Payment (callback/IPN chung cho các cổng thanh toán)
+	paymentRouter := router.Group("payments")
+	{
+		paymentRouter.GET("/:provider/callback", controllers.HandlePaymentCallback)
+		paymentRouter.POST("/:provider/callback", controllers.HandlePaymentCallback)
+		paymentRouter.GET("/:provider/return", controllers.HandlePaymentReturn)
+		paymentRouter.GET("/fake/checkout", controllers.FakeCheckout)
+		paymentRouter.GET("/discrepancies", middlewares.AuthorizeJWTMiddleware(), controllers.GetPaymentDiscrepancies)
+		paymentRouter.GET("/events", middlewares.AuthorizeJWTMiddleware(), controllers.GetPaymentEvents)
+		paymentRouter.GET("/outbox/metrics", middlewares.AuthorizeJWTMiddleware(), controllers.GetOutboxMetrics)
+		paymentRouter.PATCH("/discrepancies/:id/resolve", middlewares.AuthorizeJWTMiddleware(), controllers.ResolvePaymentDiscrepancy)
+	}
 }
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Xuất danh sách người tham dự (vé còn hiệu lực) ra CSV cho ban tổ chức.
+// Mỗi câu hỏi của form đăng ký là một cột; câu hỏi đã bị xóa khỏi form vẫn được giữ theo nhãn đã lưu trên vé.
+func ExportEventAttendeesCSV(ctx context.Context, eventEntry *collections.Event) ([]byte, error) {
+	var (
+		ticketEntry     = &collections.Ticket{}
+		ticketTypeEntry = &collections.TicketType{}
+		regisEntry      = &collections.Registration{}
+		accountEntry    = &collections.Account{}
+	)
+
+	tickets, err := ticketEntry.Find(ctx, bson.M{
+		"event_id": eventEntry.ID,
+		"status":   bson.M{"$in": []consts.TicketStatus{consts.TicketStatusConfirmed, consts.TicketStatusCheckedIn}},
+	}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("lỗi hệ thống khi lấy danh sách vé: %w", err)
+	}
+
+	ticketTypes, err := ticketTypeEntry.Find(ctx, bson.M{"event_id": eventEntry.ID})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi hệ thống khi lấy loại vé: %w", err)
+	}
+	ticketTypeMap := make(map[primitive.ObjectID]collections.TicketType, len(ticketTypes))
+	for _, ticketType := range ticketTypes {
+		ticketTypeMap[ticketType.ID] = ticketType
+	}
+
+	// Người mua của từng đơn
+	regisIDs := make([]primitive.ObjectID, 0)
+	seenRegis := make(map[primitive.ObjectID]bool)
+	for _, ticket := range tickets {
+		if !seenRegis[ticket.RegisID] {
+			seenRegis[ticket.RegisID] = true
+			regisIDs = append(regisIDs, ticket.RegisID)
+		}
+	}
+	buyerMap := make(map[primitive.ObjectID]collections.Account)
+	if len(regisIDs) > 0 {
+		registrations, err := regisEntry.Find(ctx, bson.M{"_id": bson.M{"$in": regisIDs}})
+		if err != nil {
+			return nil, fmt.Errorf("lỗi hệ thống khi lấy đơn đăng ký: %w", err)
+		}
+		buyerIDs := make([]primitive.ObjectID, 0, len(registrations))
+		regisBuyer := make(map[primitive.ObjectID]primitive.ObjectID, len(registrations))
+		for _, registration := range registrations {
+			buyerIDs = append(buyerIDs, registration.CreatedBy)
+			regisBuyer[registration.ID] = registration.CreatedBy
+		}
+		accounts, err := accountEntry.Find(bson.M{"_id": bson.M{"$in": buyerIDs}})
+		if err != nil {
+			return nil, fmt.Errorf("lỗi hệ thống khi lấy tài khoản: %w", err)
+		}
+		accountMap := make(map[primitive.ObjectID]collections.Account, len(accounts))
+		for _, account := range accounts {
+			accountMap[account.ID] = account
+		}
+		for regisID, buyerID := range regisBuyer {
+			buyerMap[regisID] = accountMap[buyerID]
+		}
+	}
+
+	// Cột câu hỏi: theo thứ tự form hiện tại, sau đó là câu hỏi cũ chỉ còn trên vé
+	var (
+		fieldIDs    []string
+		fieldLabels []string
+		fieldIndex  = make(map[string]int)
+	)
+	addField := func(id, label string) {
+		if _, ok := fieldIndex[id]; ok {
+			return
+		}
+		fieldIndex[id] = len(fieldIDs)
+		fieldIDs = append(fieldIDs, id)
+		fieldLabels = append(fieldLabels, label)
+	}
+	if eventEntry.RegistrationForm != nil {
+		for _, field := range eventEntry.RegistrationForm.Fields {
+			addField(field.ID, field.Label)
+		}
+	}
+	for _, ticket := range tickets {
+		if ticket.Attendee == nil {
+			continue
+		}
+		for _, answer := range ticket.Attendee.Answers {
+			addField(answer.FieldID, answer.Label)
+		}
+	}
+
+	var buf bytes.Buffer
+	// BOM để Excel đọc đúng tiếng Việt
+	buf.WriteString("\xEF\xBB\xBF")
+	writer := csv.NewWriter(&buf)
+
+	header := []string{
+		"Mã vé", "Loại vé", "Trạng thái", "Người tham dự", "Email người tham dự",
+		"Người mua", "Email người mua", "Mã đơn", "Ngày đăng ký", "Check-in",
+	}
+	header = append(header, fieldLabels...)
+	if err = writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	vietnamLoc := time.FixedZone("Asia/Ho_Chi_Minh", 7*60*60)
+	for _, ticket := range tickets {
+		buyer := buyerMap[ticket.RegisID]
+		row := []string{
+			ticket.ID.Hex(),
+			ticketTypeMap[ticket.TicketTypeID].Name,
+			string(ticket.Status),
+			"",
+			"",
+			buyer.Name,
+			buyer.Email,
+			ticket.RegisID.Hex(),
+			ticket.CreatedAt.In(vietnamLoc).Format("15:04 02/01/2006"),
+			strings.Join(ticket.CheckedInAt, "; "),
+		}
+		answers := make([]string, len(fieldIDs))
+		if ticket.Attendee != nil {
+			row[3] = ticket.Attendee.Name
+			row[4] = ticket.Attendee.Email
+			for _, answer := range ticket.Attendee.Answers {
+				answers[fieldIndex[answer.FieldID]] = answer.Display()
+			}
+		}
+		if err = writer.Write(append(row, answers...)); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
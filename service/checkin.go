@@ -0,0 +1,298 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/utils"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var checkInLoc = time.FixedZone("Asia/Ho_Chi_Minh", 7*60*60)
+
+const (
+	checkInClockLayout = "15:04"
+	checkInDateLayout  = "2006-01-02"
+	checkInTimeLayout  = time.RFC3339
+)
+
+// Một ngày diễn ra sự kiện cùng khung giờ check-in của ngày đó (giờ Việt Nam)
+type EventDay struct {
+	Date    string
+	OpenAt  time.Time
+	CloseAt time.Time
+}
+
+// Điểm danh theo từng ngày của một vé
+type TicketAttendanceDay struct {
+	Date      string   `json:"date"`
+	CheckedIn bool     `json:"checked_in"`
+	Times     []string `json:"times"`
+}
+
+// Check-in vé tại cổng sự kiện
+func CheckInTicket(ctx context.Context, eventEntry *collections.Event, qrCodeData string, staffID primitive.ObjectID, scanTime time.Time) (*collections.Ticket, error) {
+	var (
+		ticketEntry     = &collections.Ticket{}
+		ticketTypeEntry = &collections.TicketType{}
+		err             error
+	)
+
+	err = findTicketByCode(ctx, ticketEntry, qrCodeData, eventEntry.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ticketTypeEntry.First(ctx, bson.M{"_id": ticketEntry.TicketTypeID})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi hệ thống khi tìm loại vé: %w", err)
+	}
+	policy := ticketTypeEntry.GetCheckInPolicy()
+
+	if err = validateTicketForCheckIn(ticketEntry, eventEntry, policy); err != nil {
+		return nil, err
+	}
+
+	//Kiểm tra khung giờ check-in của ngày sự kiện
+	day, err := ResolveEventDay(eventEntry, scanTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkPolicyForDay(ticketEntry, policy, day); err != nil {
+		return nil, err
+	}
+
+	checkedInAt := scanTime.In(checkInLoc).Format(checkInTimeLayout)
+	ticketFilter := bson.M{"_id": ticketEntry.ID}
+	switch policy {
+	case consts.CheckInPolicyDaily:
+		ticketFilter["status"] = bson.M{"$in": []consts.TicketStatus{consts.TicketStatusConfirmed, consts.TicketStatusCheckedIn}}
+		ticketFilter["checked_in_at"] = bson.M{"$not": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(day.Date)}}
+	case consts.CheckInPolicyUnlimited:
+		ticketFilter["status"] = bson.M{"$in": []consts.TicketStatus{consts.TicketStatusConfirmed, consts.TicketStatusCheckedIn}}
+	default:
+		ticketFilter["status"] = consts.TicketStatusConfirmed
+	}
+	ticketUpdate := bson.M{
+		"$set": bson.M{
+			"status":     consts.TicketStatusCheckedIn,
+			"updated_at": time.Now(),
+			"updated_by": staffID,
+		},
+		"$push": bson.M{"checked_in_at": checkedInAt},
+	}
+	err = ticketEntry.Update(ctx, ticketFilter, ticketUpdate)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Vé vừa được cổng khác check-in hoặc đổi trạng thái
+			if reloadErr := ticketEntry.First(ctx, bson.M{"_id": ticketEntry.ID}); reloadErr != nil {
+				return nil, fmt.Errorf("lỗi hệ thống khi tìm vé: %w", reloadErr)
+			}
+			if err = validateTicketForCheckIn(ticketEntry, eventEntry, policy); err != nil {
+				return nil, err
+			}
+			if err = checkPolicyForDay(ticketEntry, policy, day); err != nil {
+				return nil, err
+			}
+			return nil, consts.ErrTicketAlreadyCheckedIn
+		}
+		return nil, fmt.Errorf("lỗi hệ thống khi cập nhật vé: %w", err)
+	}
+
+	ticketEntry.Status = consts.TicketStatusCheckedIn
+	ticketEntry.CheckedInAt = append(ticketEntry.CheckedInAt, checkedInAt)
+	return ticketEntry, nil
+}
+
+// Tìm vé theo mã QR: mã có chữ ký được verify trước khi truy vấn DB, mã TICKET-<uuid> cũ tra trực tiếp
+func findTicketByCode(ctx context.Context, ticketEntry *collections.Ticket, qrCodeData string, eventID primitive.ObjectID) error {
+	filter := bson.M{"qr_code_data": qrCodeData}
+
+	if utils.IsSignedTicketToken(qrCodeData) {
+		claims, err := utils.VerifyTicketToken(qrCodeData)
+		if err != nil {
+			return fmt.Errorf("%w: %v", consts.ErrTicketTokenInvalid, err)
+		}
+		if claims.EventID != eventID {
+			return consts.ErrTicketWrongEvent
+		}
+		filter["_id"] = claims.TicketID
+		filter["ticket_type_id"] = claims.TicketTypeID
+	} else if !configs.GetTicketQRAcceptLegacy() {
+		return consts.ErrTicketLegacyCode
+	}
+
+	err := ticketEntry.First(ctx, filter)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return consts.ErrTicketNotFound
+		}
+		return fmt.Errorf("lỗi hệ thống khi tìm vé: %w", err)
+	}
+	return nil
+}
+
+// Sự kiện của vé khi link QR không kèm event_id: chỉ lấy từ claims của mã ký đã verify.
+// Mã cũ bắt buộc kèm event_id để không tra DB trước khi kiểm tra quyền (tránh dò mã vé).
+func ResolveTicketEventID(qrCodeData string) (primitive.ObjectID, error) {
+	if utils.IsSignedTicketToken(qrCodeData) {
+		claims, err := utils.VerifyTicketToken(qrCodeData)
+		if err != nil {
+			return primitive.NilObjectID, fmt.Errorf("%w: %v", consts.ErrTicketTokenInvalid, err)
+		}
+		return claims.EventID, nil
+	}
+	if !configs.GetTicketQRAcceptLegacy() {
+		return primitive.NilObjectID, consts.ErrTicketLegacyCode
+	}
+	return primitive.NilObjectID, consts.ErrCheckInEventRequired
+}
+
+func validateTicketForCheckIn(ticketEntry *collections.Ticket, eventEntry *collections.Event, policy consts.CheckInPolicy) error {
+	if ticketEntry.EventID != eventEntry.ID {
+		return consts.ErrTicketWrongEvent
+	}
+
+	switch ticketEntry.Status {
+	case consts.TicketStatusCancelled:
+		return consts.ErrTicketCancelled
+	case consts.TicketStatusRefunding:
+		return consts.ErrTicketRefunding
+	case consts.TicketStatusRefunded:
+		return consts.ErrTicketRefunded
+	case consts.TicketStatusCheckedIn:
+		if policy == consts.CheckInPolicySingle {
+			return alreadyCheckedInError(ticketEntry.CheckedInAt, "")
+		}
+	}
+	return nil
+}
+
+func checkPolicyForDay(ticketEntry *collections.Ticket, policy consts.CheckInPolicy, day EventDay) error {
+	if policy != consts.CheckInPolicyDaily {
+		return nil
+	}
+
+	var sameDay []string
+	for _, checkedInAt := range ticketEntry.CheckedInAt {
+		if parsed, err := time.Parse(checkInTimeLayout, checkedInAt); err == nil && parsed.In(checkInLoc).Format(checkInDateLayout) == day.Date {
+			sameDay = append(sameDay, checkedInAt)
+		}
+	}
+	if len(sameDay) > 0 {
+		return alreadyCheckedInError(sameDay, "hôm nay")
+	}
+	return nil
+}
+
+func alreadyCheckedInError(checkedInAt []string, when string) error {
+	if len(checkedInAt) == 0 {
+		return consts.ErrTicketAlreadyCheckedIn
+	}
+
+	last := checkedInAt[len(checkedInAt)-1]
+	lastTime, err := time.Parse(checkInTimeLayout, last)
+	if err != nil {
+		return consts.ErrTicketAlreadyCheckedIn
+	}
+
+	lastTime = lastTime.In(checkInLoc)
+	if when == "" {
+		when = "ngày " + lastTime.Format("02/01/2006")
+	}
+	return fmt.Errorf("%w %s lúc %s", consts.ErrTicketAlreadyCheckedIn, when, lastTime.Format("15:04"))
+}
+
+// Tìm ngày sự kiện có khung giờ check-in chứa thời điểm quét vé
+func ResolveEventDay(eventEntry *collections.Event, scanTime time.Time) (EventDay, error) {
+	days := EventDays(eventEntry)
+	if len(days) == 0 {
+		return EventDay{}, consts.ErrCheckInNotOpen
+	}
+
+	for _, day := range days {
+		if scanTime.Before(day.OpenAt) {
+			return EventDay{}, fmt.Errorf("%w, mở check-in lúc %s", consts.ErrCheckInNotOpen, day.OpenAt.Format("15:04 02/01/2006"))
+		}
+		if !scanTime.After(day.CloseAt) {
+			return day, nil
+		}
+	}
+
+	lastDay := days[len(days)-1]
+	return EventDay{}, fmt.Errorf("%w, đóng check-in lúc %s", consts.ErrCheckInClosed, lastDay.CloseAt.Format("15:04 02/01/2006"))
+}
+
+// Danh sách các ngày diễn ra sự kiện: mỗi ngày mở check-in trước giờ bắt đầu và đóng vào giờ kết thúc
+func EventDays(eventEntry *collections.Event) []EventDay {
+	startDate := eventEntry.EventTime.StartDate
+	endDate := eventEntry.EventTime.EndDate
+	if startDate.IsZero() {
+		return nil
+	}
+	if endDate.IsZero() || endDate.Before(startDate) {
+		endDate = startDate
+	}
+
+	earlyMinutes := time.Duration(configs.GetCheckInEarlyMinutes()) * time.Minute
+	lastDay := atClock(endDate, "", 0, 0)
+
+	var days []EventDay
+	for day := atClock(startDate, "", 0, 0); !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		days = append(days, EventDay{
+			Date:    day.Format(checkInDateLayout),
+			OpenAt:  atClock(day, eventEntry.EventTime.StartTime, 0, 0).Add(-earlyMinutes),
+			CloseAt: atClock(day, eventEntry.EventTime.EndTime, 23, 59),
+		})
+	}
+	return days
+}
+
+// Tổng hợp lượt check-in của vé theo từng ngày sự kiện
+func BuildTicketAttendance(ticketEntry *collections.Ticket, eventEntry *collections.Event) []TicketAttendanceDay {
+	timesByDate := make(map[string][]string)
+	for _, checkedInAt := range ticketEntry.CheckedInAt {
+		parsed, err := time.Parse(checkInTimeLayout, checkedInAt)
+		if err != nil {
+			continue
+		}
+		parsed = parsed.In(checkInLoc)
+		date := parsed.Format(checkInDateLayout)
+		timesByDate[date] = append(timesByDate[date], parsed.Format("15:04"))
+	}
+
+	attendance := []TicketAttendanceDay{}
+	for _, day := range EventDays(eventEntry) {
+		times := timesByDate[day.Date]
+		if times == nil {
+			times = []string{}
+		}
+		attendance = append(attendance, TicketAttendanceDay{
+			Date:      day.Date,
+			CheckedIn: len(times) > 0,
+			Times:     times,
+		})
+	}
+	return attendance
+}
+
+// Ghép ngày (theo lịch Việt Nam) với giờ dạng "15:04"
+func atClock(date time.Time, clock string, defaultHour, defaultMinute int) time.Time {
+	local := date.In(checkInLoc)
+	hour, minute := defaultHour, defaultMinute
+	if clock != "" {
+		if parsed, err := time.Parse(checkInClockLayout, clock); err == nil {
+			hour, minute = parsed.Hour(), parsed.Minute()
+		}
+	}
+	return time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, checkInLoc)
+}
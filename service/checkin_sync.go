@@ -0,0 +1,385 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// Số lần thử lại khi vé bị cổng khác cập nhật cùng lúc
+	maxSyncRetries = 3
+	// Sai lệch đồng hồ tối đa cho phép của thiết bị
+	maxDeviceClockSkew = 5 * time.Minute
+)
+
+// Một lượt quét vé offline gửi lên từ thiết bị
+type OfflineScan struct {
+	QRCodeData string
+	TicketID   primitive.ObjectID
+	DeviceID   string
+	Gate       string
+	ScannedAt  time.Time
+}
+
+type OfflineScanResult struct {
+	TicketID  primitive.ObjectID                `json:"ticket_id,omitempty"`
+	DeviceID  string                            `json:"device_id"`
+	Gate      string                            `json:"gate,omitempty"`
+	ScannedAt time.Time                         `json:"scanned_at"`
+	Result    consts.CheckInResult              `json:"result"`
+	Reason    string                            `json:"reason,omitempty"`
+	Winner    *collections.CheckInLogWinnerScan `json:"winner,omitempty"`
+}
+
+// Vé hợp lệ xuất cho thiết bị check-in offline
+type ManifestTicket struct {
+	TicketID       primitive.ObjectID   `json:"ticket_id"`
+	CodeHash       string               `json:"code_hash"`
+	TicketTypeID   primitive.ObjectID   `json:"ticket_type_id"`
+	TicketTypeName string               `json:"ticket_type_name"`
+	CheckInPolicy  consts.CheckInPolicy `json:"check_in_policy"`
+	Status         consts.TicketStatus  `json:"status"`
+	CheckedInAt    []string             `json:"checked_in_at"`
+	AttendeeName   string               `json:"attendee_name,omitempty"`
+}
+
+// Lượt quét ứng viên khi gộp dữ liệu server và thiết bị
+type scanCandidate struct {
+	at       time.Time
+	deviceID string
+	gate     string
+	index    int // -1: đã có trên server
+}
+
+// Hash mã QR để thiết bị đối chiếu mà không cần giữ mã gốc
+func HashTicketCode(qrCodeData string) string {
+	sum := sha256.Sum256([]byte(qrCodeData))
+	return hex.EncodeToString(sum[:])
+}
+
+// Xuất danh sách vé hợp lệ của sự kiện cho thiết bị check-in
+func BuildCheckInManifest(ctx context.Context, eventEntry *collections.Event) ([]ManifestTicket, error) {
+	var (
+		ticketEntry     = &collections.Ticket{}
+		ticketTypeEntry = &collections.TicketType{}
+	)
+
+	tickets, err := ticketEntry.Find(ctx, bson.M{
+		"event_id": eventEntry.ID,
+		"status":   bson.M{"$in": []consts.TicketStatus{consts.TicketStatusConfirmed, consts.TicketStatusCheckedIn}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi hệ thống khi lấy danh sách vé: %w", err)
+	}
+
+	ticketTypes, err := ticketTypeEntry.Find(ctx, bson.M{"event_id": eventEntry.ID})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi hệ thống khi lấy loại vé: %w", err)
+	}
+	ticketTypeMap := make(map[primitive.ObjectID]collections.TicketType)
+	for _, ticketType := range ticketTypes {
+		ticketTypeMap[ticketType.ID] = ticketType
+	}
+
+	manifest := make([]ManifestTicket, 0, len(tickets))
+	for _, ticket := range tickets {
+		ticketType := ticketTypeMap[ticket.TicketTypeID]
+		checkedInAt := ticket.CheckedInAt
+		if checkedInAt == nil {
+			checkedInAt = []string{}
+		}
+		manifest = append(manifest, ManifestTicket{
+			TicketID:       ticket.ID,
+			CodeHash:       HashTicketCode(ticket.QRCodeData),
+			TicketTypeID:   ticket.TicketTypeID,
+			TicketTypeName: ticketType.Name,
+			CheckInPolicy:  ticketType.GetCheckInPolicy(),
+			Status:         ticket.Status,
+			CheckedInAt:    checkedInAt,
+			AttendeeName:   ticket.AttendeeName(),
+		})
+	}
+	return manifest, nil
+}
+
+// Đồng bộ các lượt quét offline.
+// Kết quả không phụ thuộc thứ tự đồng bộ: mọi lượt quét (server + thiết bị) được sắp theo
+// (thời gian quét, device_id, gate) rồi áp dụng chính sách check-in của loại vé; lượt sớm nhất thắng.
+func SyncOfflineScans(ctx context.Context, eventEntry *collections.Event, staffID primitive.ObjectID, scans []OfflineScan) ([]OfflineScanResult, error) {
+	var (
+		results     = make([]OfflineScanResult, len(scans))
+		scansByID   = make(map[primitive.ObjectID][]int)
+		ticketOrder []primitive.ObjectID
+		now         = time.Now()
+	)
+
+	for i, scan := range scans {
+		results[i] = OfflineScanResult{
+			DeviceID:  scan.DeviceID,
+			Gate:      scan.Gate,
+			ScannedAt: scan.ScannedAt,
+		}
+
+		if scan.ScannedAt.After(now.Add(maxDeviceClockSkew)) {
+			results[i].Result = consts.CheckInResultRejected
+			results[i].Reason = "thời gian quét ở tương lai, kiểm tra lại đồng hồ thiết bị"
+			continue
+		}
+
+		ticketID, err := resolveScanTicketID(ctx, scan, eventEntry.ID)
+		if err != nil {
+			results[i].Result = consts.CheckInResultRejected
+			results[i].Reason = err.Error()
+			continue
+		}
+		results[i].TicketID = ticketID
+
+		if _, ok := scansByID[ticketID]; !ok {
+			ticketOrder = append(ticketOrder, ticketID)
+		}
+		scansByID[ticketID] = append(scansByID[ticketID], i)
+	}
+
+	policyCache := make(map[primitive.ObjectID]consts.CheckInPolicy)
+	for _, ticketID := range ticketOrder {
+		err := syncTicketScans(ctx, eventEntry, staffID, ticketID, scans, scansByID[ticketID], results, policyCache)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Ghi lại lịch sử để ban tổ chức xem các lượt quét trùng
+	logEntry := &collections.CheckInLog{}
+	logs := make([]collections.CheckInLog, 0, len(results))
+	for _, result := range results {
+		logs = append(logs, collections.CheckInLog{
+			ID:        primitive.NewObjectID(),
+			EventID:   eventEntry.ID,
+			TicketID:  result.TicketID,
+			DeviceID:  result.DeviceID,
+			Gate:      result.Gate,
+			ScannedAt: result.ScannedAt,
+			Result:    result.Result,
+			Reason:    result.Reason,
+			Winner:    result.Winner,
+			SyncedAt:  now,
+			SyncedBy:  staffID,
+		})
+	}
+	if err := logEntry.CreateMany(ctx, logs); err != nil {
+		log.Printf("ERROR: Không thể lưu lịch sử check-in offline của sự kiện %s: %v", eventEntry.ID.Hex(), err)
+	}
+
+	return results, nil
+}
+
+func resolveScanTicketID(ctx context.Context, scan OfflineScan, eventID primitive.ObjectID) (primitive.ObjectID, error) {
+	ticketEntry := &collections.Ticket{}
+
+	if scan.QRCodeData != "" {
+		if err := findTicketByCode(ctx, ticketEntry, scan.QRCodeData, eventID); err != nil {
+			return primitive.NilObjectID, err
+		}
+	} else {
+		err := ticketEntry.First(ctx, bson.M{"_id": scan.TicketID})
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return primitive.NilObjectID, consts.ErrTicketNotFound
+			}
+			return primitive.NilObjectID, fmt.Errorf("lỗi hệ thống khi tìm vé: %w", err)
+		}
+	}
+
+	if ticketEntry.EventID != eventID {
+		return primitive.NilObjectID, consts.ErrTicketWrongEvent
+	}
+	return ticketEntry.ID, nil
+}
+
+func syncTicketScans(
+	ctx context.Context,
+	eventEntry *collections.Event,
+	staffID primitive.ObjectID,
+	ticketID primitive.ObjectID,
+	scans []OfflineScan,
+	scanIndexes []int,
+	results []OfflineScanResult,
+	policyCache map[primitive.ObjectID]consts.CheckInPolicy,
+) error {
+	for attempt := 0; attempt < maxSyncRetries; attempt++ {
+		ticketEntry := &collections.Ticket{}
+		if err := ticketEntry.First(ctx, bson.M{"_id": ticketID}); err != nil {
+			return fmt.Errorf("lỗi hệ thống khi tìm vé: %w", err)
+		}
+
+		policy, ok := policyCache[ticketEntry.TicketTypeID]
+		if !ok {
+			ticketTypeEntry := &collections.TicketType{}
+			if err := ticketTypeEntry.First(ctx, bson.M{"_id": ticketEntry.TicketTypeID}); err != nil {
+				return fmt.Errorf("lỗi hệ thống khi tìm loại vé: %w", err)
+			}
+			policy = ticketTypeEntry.GetCheckInPolicy()
+			policyCache[ticketEntry.TicketTypeID] = policy
+		}
+
+		// Vé đã hủy/hoàn tiền thì từ chối toàn bộ lượt quét
+		var reason string
+		switch ticketEntry.Status {
+		case consts.TicketStatusCancelled:
+			reason = consts.ErrTicketCancelled.Error()
+		case consts.TicketStatusRefunding:
+			reason = consts.ErrTicketRefunding.Error()
+		case consts.TicketStatusRefunded:
+			reason = consts.ErrTicketRefunded.Error()
+		}
+		if reason != "" {
+			for _, i := range scanIndexes {
+				results[i].Result = consts.CheckInResultRejected
+				results[i].Reason = reason
+			}
+			return nil
+		}
+
+		// Gộp lượt quét trên server với lượt quét từ thiết bị
+		var candidates []scanCandidate
+		for _, checkedInAt := range ticketEntry.CheckedInAt {
+			parsed, err := time.Parse(checkInTimeLayout, checkedInAt)
+			if err != nil {
+				continue
+			}
+			candidates = append(candidates, scanCandidate{at: parsed, index: -1})
+		}
+		for _, i := range scanIndexes {
+			if _, err := ResolveEventDay(eventEntry, scans[i].ScannedAt); err != nil {
+				results[i].Result = consts.CheckInResultRejected
+				results[i].Reason = err.Error()
+				continue
+			}
+			candidates = append(candidates, scanCandidate{
+				at:       scans[i].ScannedAt.Truncate(time.Second),
+				deviceID: scans[i].DeviceID,
+				gate:     scans[i].Gate,
+				index:    i,
+			})
+		}
+
+		accepted, winners := resolveScanCandidates(candidates, policy)
+
+		newCheckedInAt := make([]string, 0, len(accepted))
+		for _, candidate := range accepted {
+			newCheckedInAt = append(newCheckedInAt, candidate.at.In(checkInLoc).Format(checkInTimeLayout))
+		}
+		acceptedIndexes := make(map[int]bool)
+		for _, candidate := range accepted {
+			if candidate.index >= 0 {
+				acceptedIndexes[candidate.index] = true
+			}
+		}
+		for _, candidate := range candidates {
+			if candidate.index < 0 {
+				continue
+			}
+			if acceptedIndexes[candidate.index] {
+				results[candidate.index].Result = consts.CheckInResultAccepted
+				results[candidate.index].Reason = ""
+				results[candidate.index].Winner = nil
+				continue
+			}
+			winner := winners[candidate]
+			results[candidate.index].Result = consts.CheckInResultDuplicate
+			results[candidate.index].Reason = alreadyCheckedInError([]string{winner.at.In(checkInLoc).Format(checkInTimeLayout)}, "").Error()
+			results[candidate.index].Winner = &collections.CheckInLogWinnerScan{
+				CheckedInAt: winner.at.In(checkInLoc).Format(checkInTimeLayout),
+				DeviceID:    winner.deviceID,
+				Gate:        winner.gate,
+			}
+		}
+
+		if slices.Equal(newCheckedInAt, ticketEntry.CheckedInAt) {
+			return nil
+		}
+
+		// Chỉ ghi nếu vé chưa bị cổng khác thay đổi kể từ lúc đọc
+		ticketFilter := bson.M{"_id": ticketEntry.ID, "status": ticketEntry.Status}
+		if len(ticketEntry.CheckedInAt) == 0 {
+			ticketFilter["checked_in_at"] = bson.M{"$in": bson.A{nil, bson.A{}}}
+		} else {
+			ticketFilter["checked_in_at"] = ticketEntry.CheckedInAt
+		}
+		ticketUpdate := bson.M{"$set": bson.M{
+			"checked_in_at": newCheckedInAt,
+			"status":        consts.TicketStatusCheckedIn,
+			"updated_at":    time.Now(),
+			"updated_by":    staffID,
+		}}
+
+		err := ticketEntry.Update(ctx, ticketFilter, ticketUpdate)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("lỗi hệ thống khi cập nhật vé: %w", err)
+		}
+	}
+
+	for _, i := range scanIndexes {
+		if results[i].Result == "" || results[i].Result == consts.CheckInResultAccepted {
+			results[i].Result = consts.CheckInResultRejected
+			results[i].Reason = "vé đang được cập nhật từ cổng khác, vui lòng đồng bộ lại"
+		}
+	}
+	return nil
+}
+
+// Chọn các lượt check-in hợp lệ theo chính sách; trả về map lượt bị loại -> lượt thắng
+func resolveScanCandidates(candidates []scanCandidate, policy consts.CheckInPolicy) ([]scanCandidate, map[scanCandidate]scanCandidate) {
+	sorted := make([]scanCandidate, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].at.Equal(sorted[j].at) {
+			return sorted[i].at.Before(sorted[j].at)
+		}
+		if sorted[i].deviceID != sorted[j].deviceID {
+			return sorted[i].deviceID < sorted[j].deviceID
+		}
+		return sorted[i].gate < sorted[j].gate
+	})
+
+	var (
+		accepted []scanCandidate
+		winners  = make(map[scanCandidate]scanCandidate)
+		byKey    = make(map[string]scanCandidate)
+	)
+	for _, candidate := range sorted {
+		var key string
+		switch policy {
+		case consts.CheckInPolicyDaily:
+			key = candidate.at.In(checkInLoc).Format(checkInDateLayout)
+		case consts.CheckInPolicyUnlimited:
+			key = candidate.at.UTC().Format(time.RFC3339)
+		default:
+			key = "single"
+		}
+
+		if winner, ok := byKey[key]; ok {
+			winners[candidate] = winner
+			continue
+		}
+		byKey[key] = candidate
+		accepted = append(accepted, candidate)
+	}
+	return accepted, winners
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/einvoice"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Xuất XML hóa đơn điện tử, hóa đơn điều chỉnh giảm kèm thông tin hóa đơn gốc
+func ExportEInvoiceXML(ctx context.Context, invoiceEntry *collections.Invoice) ([]byte, error) {
+	originalInvoice, err := findOriginalInvoice(ctx, invoiceEntry)
+	if err != nil {
+		return nil, err
+	}
+	return einvoice.BuildXML(invoiceEntry, originalInvoice)
+}
+
+// Gửi hóa đơn sang nhà cung cấp hóa đơn điện tử và lưu trạng thái vào invoice.einvoice.
+// Chỉ gửi hóa đơn chưa gửi hoặc lần gửi trước bị lỗi; hóa đơn điều chỉnh cần hóa đơn gốc đã phát hành.
+func SubmitEInvoice(ctx context.Context, invoiceEntry *collections.Invoice) error {
+	if invoiceEntry.EInvoice != nil {
+		switch invoiceEntry.EInvoice.Status {
+		case consts.EInvoiceStatusSubmitted, consts.EInvoiceStatusIssued:
+			return consts.ErrEInvoiceAlreadySubmitted
+		case consts.EInvoiceStatusSubmitting:
+			return consts.ErrEInvoiceInProgress
+		}
+	}
+
+	originalInvoice, err := findOriginalInvoice(ctx, invoiceEntry)
+	if err != nil {
+		return err
+	}
+	if originalInvoice != nil && (originalInvoice.EInvoice == nil || originalInvoice.EInvoice.Status != consts.EInvoiceStatusIssued) {
+		return consts.ErrEInvoiceOriginalMissing
+	}
+
+	xmlData, err := einvoice.BuildXML(invoiceEntry, originalInvoice)
+	if err != nil {
+		return err
+	}
+
+	submitter, err := einvoice.Default()
+	if err != nil {
+		return err
+	}
+
+	// Giữ hóa đơn ở trạng thái submitting, tránh hai request cùng gửi một hóa đơn
+	now := time.Now()
+	err = invoiceEntry.Update(ctx,
+		bson.M{
+			"_id": invoiceEntry.ID,
+			"$or": bson.A{
+				bson.M{"einvoice": bson.M{"$exists": false}},
+				bson.M{"einvoice.status": consts.EInvoiceStatusFailed},
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"einvoice.provider":   submitter.Name(),
+				"einvoice.status":     consts.EInvoiceStatusSubmitting,
+				"einvoice.updated_at": now,
+			},
+			"$inc": bson.M{"einvoice.attempts": 1},
+		},
+	)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return consts.ErrEInvoiceInProgress
+		}
+		return err
+	}
+
+	result, err := submitter.Submit(ctx, einvoice.SubmitRequest{
+		InvoiceID:     invoiceEntry.ID,
+		InvoiceNumber: invoiceEntry.InvoiceNumber,
+		XML:           xmlData,
+	})
+	if err != nil {
+		log.Printf("ERROR: Gửi hóa đơn điện tử %s qua %s thất bại: %v", invoiceEntry.InvoiceNumber, submitter.Name(), err)
+		updateErr := invoiceEntry.Update(ctx, bson.M{"_id": invoiceEntry.ID}, bson.M{"$set": bson.M{
+			"einvoice.status":     consts.EInvoiceStatusFailed,
+			"einvoice.error":      err.Error(),
+			"einvoice.updated_at": time.Now(),
+		}})
+		if updateErr != nil {
+			log.Printf("CRITICAL: Không cập nhật được trạng thái lỗi hóa đơn điện tử %s: %v", invoiceEntry.InvoiceNumber, updateErr)
+		}
+		return fmt.Errorf("%w: %v", consts.ErrEInvoiceProvider, err)
+	}
+
+	submittedAt := time.Now()
+	update := bson.M{
+		"einvoice.status":              result.Status,
+		"einvoice.provider_invoice_id": result.ProviderInvoiceID,
+		"einvoice.lookup_code":         result.LookupCode,
+		"einvoice.submitted_at":        submittedAt,
+		"einvoice.updated_at":          submittedAt,
+	}
+	if result.Status == consts.EInvoiceStatusIssued {
+		update["einvoice.issued_at"] = submittedAt
+	}
+	err = invoiceEntry.Update(ctx, bson.M{"_id": invoiceEntry.ID}, bson.M{
+		"$set":   update,
+		"$unset": bson.M{"einvoice.error": ""},
+	})
+	if err != nil {
+		log.Printf("CRITICAL: Hóa đơn %s đã gửi %s (%s) nhưng không lưu được trạng thái: %v", invoiceEntry.InvoiceNumber, submitter.Name(), result.ProviderInvoiceID, err)
+		return err
+	}
+
+	return invoiceEntry.First(ctx, bson.M{"_id": invoiceEntry.ID})
+}
+
+func findOriginalInvoice(ctx context.Context, invoiceEntry *collections.Invoice) (*collections.Invoice, error) {
+	if invoiceEntry.Type != consts.InvoiceTypeCreditNote || invoiceEntry.OriginalInvoiceID.IsZero() {
+		return nil, nil
+	}
+	originalInvoice := &collections.Invoice{}
+	if err := originalInvoice.First(ctx, bson.M{"_id": invoiceEntry.OriginalInvoiceID}); err != nil {
+		return nil, fmt.Errorf("không tìm thấy hóa đơn gốc: %w", err)
+	}
+	return originalInvoice, nil
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Bộ đếm tồn kho của loại vé bán số lượng lớn (high_demand): số vé còn có thể giữ = quantity - registered_count.
+// Redis chỉ là lớp chặn phía trước, điều kiện registered_count trong transaction Mongo vẫn là nguồn chính.
+const inventoryKeyPrefix = "inventory:ticket_type:"
+
+// Giữ vé cho mọi loại vé trong đơn hoặc không giữ gì.
+// Trả về 0 nếu thành công, i nếu key thứ i không đủ vé, -i nếu key thứ i chưa được nạp.
+var reserveInventoryScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	local available = redis.call('GET', key)
+	if not available then
+		return -i
+	end
+	if tonumber(available) < tonumber(ARGV[i]) then
+		return i
+	end
+end
+for i, key in ipairs(KEYS) do
+	redis.call('DECRBY', key, ARGV[i])
+end
+return 0
+`)
+
+// Cộng/trừ bộ đếm, bỏ qua key chưa được nạp (sẽ nạp lại từ Mongo khi cần)
+var adjustInventoryScript = redis.NewScript(`
+for i, key in ipairs(KEYS) do
+	if redis.call('EXISTS', key) == 1 then
+		redis.call('INCRBY', key, ARGV[i])
+	end
+end
+return 0
+`)
+
+// Số vé cần giữ của một loại vé trong đơn
+type InventoryItem struct {
+	TicketType collections.TicketType
+	Quantity   int
+}
+
+func inventoryKey(ticketTypeID primitive.ObjectID) string {
+	return inventoryKeyPrefix + ticketTypeID.Hex()
+}
+
+func getRedisClient() (*redis.Client, error) {
+	redisClient := database.GetRedisClient()
+	if redisClient == nil {
+		return nil, errors.New("chưa kết nối Redis")
+	}
+	return redisClient.Client, nil
+}
+
+// Giữ vé trên Redis trước khi mở transaction đăng ký. Key chưa có (Redis khởi động lại, vừa bật high_demand)
+// thì nạp từ Mongo rồi thử lại. Gọi ReleaseInventory nếu transaction sau đó thất bại.
+func ReserveInventory(ctx context.Context, items []InventoryItem) error {
+	rdb, err := getRedisClient()
+	if err != nil {
+		return err
+	}
+
+	items = append([]InventoryItem(nil), items...)
+	for attempt := 0; attempt <= len(items); attempt++ {
+		if len(items) == 0 {
+			return nil
+		}
+		keys := make([]string, 0, len(items))
+		args := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			keys = append(keys, inventoryKey(item.TicketType.ID))
+			args = append(args, item.Quantity)
+		}
+
+		res, err := reserveInventoryScript.Run(ctx, rdb, keys, args...).Int()
+		if err != nil {
+			return fmt.Errorf("lỗi giữ vé trên Redis: %w", err)
+		}
+		if res == 0 {
+			return nil
+		}
+		if res > 0 {
+			return fmt.Errorf("%w: vé '%s'", consts.ErrInventorySoldOut, items[res-1].TicketType.Name)
+		}
+
+		missing := -res - 1
+		tracked, err := loadInventoryCounter(ctx, items[missing].TicketType.ID, false)
+		if err != nil {
+			return err
+		}
+		if !tracked {
+			// Loại vé vừa tắt high_demand: chỉ còn điều kiện trong Mongo
+			items = append(items[:missing], items[missing+1:]...)
+		}
+	}
+	return errors.New("không nạp được bộ đếm tồn kho")
+}
+
+// Trả lại số vé đã giữ trên Redis khi transaction đăng ký thất bại
+func ReleaseInventory(ctx context.Context, items []InventoryItem) {
+	delta := make(map[primitive.ObjectID]int, len(items))
+	for _, item := range items {
+		delta[item.TicketType.ID] += item.Quantity
+	}
+	AdjustInventory(ctx, delta)
+}
+
+// Áp số vé trả về kho (dương) / giữ thêm (âm) lên bộ đếm sau khi transaction Mongo đã commit.
+// Chỉ ghi log khi lỗi: Mongo vẫn chặn bán vượt, bộ đếm được dựng lại khi khởi động.
+func AdjustInventory(ctx context.Context, delta map[primitive.ObjectID]int) {
+	keys := make([]string, 0, len(delta))
+	args := make([]interface{}, 0, len(delta))
+	for ticketTypeID, quantity := range delta {
+		if quantity == 0 {
+			continue
+		}
+		keys = append(keys, inventoryKey(ticketTypeID))
+		args = append(args, quantity)
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	// Request bị hủy (client ngắt kết nối) vẫn phải trả vé về bộ đếm
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+
+	rdb, err := getRedisClient()
+	if err == nil {
+		err = adjustInventoryScript.Run(ctx, rdb, keys, args...).Err()
+	}
+	if err != nil {
+		log.Printf("WARN: Cập nhật bộ đếm tồn kho %v thất bại: %v", keys, err)
+	}
+}
+
+// Đồng bộ bộ đếm của một loại vé với Mongo sau khi ban tổ chức bật/tắt high_demand hoặc đổi số lượng
+func SyncInventory(ctx context.Context, ticketTypeID primitive.ObjectID) error {
+	_, err := loadInventoryCounter(ctx, ticketTypeID, true)
+	return err
+}
+
+// Nạp số vé còn lại từ Mongo. overwrite = false chỉ ghi khi key chưa có để không đè lượt giữ đang diễn ra.
+// Loại vé không dùng bộ đếm thì xóa key.
+func loadInventoryCounter(ctx context.Context, ticketTypeID primitive.ObjectID, overwrite bool) (bool, error) {
+	ticketTypeEntry := &collections.TicketType{}
+
+	rdb, err := getRedisClient()
+	if err != nil {
+		return false, err
+	}
+
+	err = ticketTypeEntry.First(ctx, bson.M{"_id": ticketTypeID, "deleted_at": bson.M{"$exists": false}})
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return false, fmt.Errorf("lỗi tìm loại vé: %w", err)
+	}
+	if err != nil || !ticketTypeEntry.UsesInventoryCounter() {
+		return false, rdb.Del(ctx, inventoryKey(ticketTypeID)).Err()
+	}
+
+	available := availableInventory(ticketTypeEntry)
+	if overwrite {
+		err = rdb.Set(ctx, inventoryKey(ticketTypeID), available, 0).Err()
+	} else {
+		err = rdb.SetNX(ctx, inventoryKey(ticketTypeID), available, 0).Err()
+	}
+	if err != nil {
+		return false, fmt.Errorf("lỗi nạp bộ đếm tồn kho: %w", err)
+	}
+	return true, nil
+}
+
+func availableInventory(ticketType *collections.TicketType) int {
+	available := *ticketType.Quantity - ticketType.RegisteredCount
+	if available < 0 {
+		return 0
+	}
+	return available
+}
+
+// Dựng lại toàn bộ bộ đếm từ Mongo khi khởi động và xóa key của loại vé không còn dùng bộ đếm
+func RebuildInventory(ctx context.Context) (int, error) {
+	ticketTypeEntry := &collections.TicketType{}
+
+	rdb, err := getRedisClient()
+	if err != nil {
+		return 0, err
+	}
+
+	ticketTypes, err := ticketTypeEntry.Find(ctx, bson.M{
+		"high_demand": true,
+		"quantity":    bson.M{"$exists": true},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	tracked := make(map[string]bool, len(ticketTypes))
+	for i := range ticketTypes {
+		key := inventoryKey(ticketTypes[i].ID)
+		if err = rdb.Set(ctx, key, availableInventory(&ticketTypes[i]), 0).Err(); err != nil {
+			return 0, fmt.Errorf("lỗi nạp bộ đếm tồn kho: %w", err)
+		}
+		tracked[key] = true
+	}
+
+	iter := rdb.Scan(ctx, 0, inventoryKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if tracked[key] || !strings.HasPrefix(key, inventoryKeyPrefix) {
+			continue
+		}
+		if err = rdb.Del(ctx, key).Err(); err != nil {
+			return 0, err
+		}
+	}
+	if err = iter.Err(); err != nil {
+		return 0, err
+	}
+
+	return len(ticketTypes), nil
+}
@@ -2,8 +2,12 @@ package service
 
 import (
 	"EventHunting/collections"
-	"EventHunting/utils"
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/view"
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,22 +15,28 @@ import (
 )
 
 // Tạo hóa đơn
-func CreateInvoiceForRegistration(regisID primitive.ObjectID, vnpTransactionNo string, payDate time.Time) (*collections.Invoice, error) {
-	var (
-		regisEntry   = &collections.Registration{}
-		eventEntry   = &collections.Event{}
-		accountEntry = &collections.Account{}
-		err          error
-	)
+func CreateInvoiceForRegistration(regisID primitive.ObjectID, paymentMethod string, transactionNo string, payDate time.Time) (*collections.Invoice, error) {
+	regisEntry := &collections.Registration{}
 
 	// Lấy thông tin Registration
-	err = regisEntry.First(nil, bson.M{"_id": regisID})
+	err := regisEntry.First(nil, bson.M{"_id": regisID})
 	if err != nil {
 		return nil, fmt.Errorf("không tìm thấy registration: %w", err)
 	}
 
+	return BuildInvoiceForRegistration(nil, regisEntry, paymentMethod, transactionNo, payDate)
+}
+
+// Dựng hóa đơn từ đơn đăng ký đã có trong bộ nhớ (dùng được trong transaction tạo đơn)
+func BuildInvoiceForRegistration(ctx context.Context, regisEntry *collections.Registration, paymentMethod string, transactionNo string, payDate time.Time) (*collections.Invoice, error) {
+	var (
+		eventEntry   = &collections.Event{}
+		accountEntry = &collections.Account{}
+		err          error
+	)
+
 	// Lấy thông tin Event
-	err = eventEntry.First(nil, bson.M{"_id": regisEntry.EventID})
+	err = eventEntry.First(ctx, bson.M{"_id": regisEntry.EventID})
 	if err != nil {
 		return nil, fmt.Errorf("không tìm thấy event: %w", err)
 	}
@@ -43,8 +53,16 @@ func CreateInvoiceForRegistration(regisID primitive.ObjectID, vnpTransactionNo s
 		return nil, fmt.Errorf("lỗi lấy loại vé: %w", err)
 	}
 
-	// Build danh sách hàng hóa (Line Items)
+	// Build danh sách hàng hóa (Line Items), thuế suất chốt tại thời điểm lập hóa đơn
+	vatRate := configs.GetEInvoiceVATRate()
+	discountByType := make(map[primitive.ObjectID]int)
+	if regisEntry.Discount != nil {
+		for _, allocation := range regisEntry.Discount.Allocations {
+			discountByType[allocation.TicketTypeID] += allocation.Amount
+		}
+	}
 	var lstItems []collections.InvoiceLstItem
+	totalAmount := 0
 	for _, t := range regisEntry.Tickets {
 		ticketType, exists := ticketTypeMap[t.TicketTypeID]
 		desc := "Vé sự kiện"
@@ -52,28 +70,51 @@ func CreateInvoiceForRegistration(regisID primitive.ObjectID, vnpTransactionNo s
 
 		if exists {
 			desc = fmt.Sprintf("Vé %s - %s", ticketType.Name, eventEntry.Name)
-			unitPrice = ticketType.Price
+			if t.PriceTier != "" {
+				desc = fmt.Sprintf("Vé %s (%s) - %s", ticketType.Name, t.PriceTier, eventEntry.Name)
+			}
+			unitPrice = t.GetUnitPrice(ticketType)
+		} else if t.UnitPrice != nil {
+			unitPrice = *t.UnitPrice
 		}
 
 		item := collections.InvoiceLstItem{
-			ItemID:      t.TicketTypeID,
-			Description: desc,
-			Quantity:    t.Quantity,
-			UnitPrice:   unitPrice,
-			TotalAmount: unitPrice * t.Quantity,
+			ItemID:         t.TicketTypeID,
+			Description:    desc,
+			Quantity:       t.Quantity,
+			UnitPrice:      unitPrice,
+			TotalAmount:    unitPrice * t.Quantity,
+			VATRate:        &vatRate,
+			DiscountAmount: discountByType[t.TicketTypeID],
 		}
 		lstItems = append(lstItems, item)
+		totalAmount += item.TotalAmount
+	}
+
+	// Giảm giá thể hiện thành một dòng âm riêng trên hóa đơn
+	if regisEntry.Discount != nil && regisEntry.Discount.Amount > 0 {
+		item := collections.InvoiceLstItem{
+			ItemID:      regisEntry.Discount.PromoCodeID,
+			Description: fmt.Sprintf("Giảm giá mã %s", regisEntry.Discount.Code),
+			Quantity:    1,
+			UnitPrice:   -regisEntry.Discount.Amount,
+			TotalAmount: -regisEntry.Discount.Amount,
+			VATRate:     &vatRate,
+		}
+		lstItems = append(lstItems, item)
+		totalAmount += item.TotalAmount
 	}
 
 	// 6. Tạo struct Invoice
 	newInvoice := &collections.Invoice{
 		ID:             primitive.NewObjectID(),
-		InvoiceNumber:  utils.GenerateInvoiceNumber(),
 		RegistrationID: regisEntry.ID,
-		Status:         "Completed",
+		Status:         consts.InvoiceStatusCompleted,
+		Type:           consts.InvoiceTypeInvoice,
+		TotalAmount:    totalAmount,
 		PaymentDetails: collections.InvoicePaymentDetails{
-			Method:          "VNPAY",
-			TransactionCode: vnpTransactionNo,
+			Method:          paymentMethod,
+			TransactionCode: transactionNo,
 			PaidAt:          payDate,
 		},
 		CustomerDetails: collections.InvoiceCustomerDetails{
@@ -95,3 +136,21 @@ func CreateInvoiceForRegistration(regisID primitive.ObjectID, vnpTransactionNo s
 	}
 	return newInvoice, nil
 }
+
+// Xuất PDF cho hóa đơn, hóa đơn điều chỉnh giảm kèm số của hóa đơn gốc
+func RenderInvoicePDF(ctx context.Context, invoiceEntry *collections.Invoice) ([]byte, error) {
+	originalInvoice, err := findOriginalInvoice(ctx, invoiceEntry)
+	if err != nil {
+		return nil, err
+	}
+	return view.BuildInvoicePDF(invoiceEntry, originalInvoice)
+}
+
+// Tên file PDF theo số hóa đơn (định dạng số có thể chứa ký tự không dùng được trong tên file)
+func InvoicePDFFilename(invoiceEntry *collections.Invoice) string {
+	name := strings.NewReplacer("/", "-", "\\", "-", " ", "_", "\"", "").Replace(invoiceEntry.InvoiceNumber)
+	if name == "" {
+		name = invoiceEntry.ID.Hex()
+	}
+	return name + ".pdf"
+}
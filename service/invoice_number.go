@@ -0,0 +1,117 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/configs"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInvoiceNumberFormat = "INV-{YYYY}-{SEQ:6}"
+	defaultInvoiceNumberReset  = "yearly"
+)
+
+var invoiceSeqPattern = regexp.MustCompile(`\{SEQ(?::(\d+))?\}`)
+
+// Cấp số hóa đơn tiếp theo theo định dạng cấu hình, trả về cả số thứ tự trong kỳ.
+// Phải gọi trong cùng transaction với việc tạo hóa đơn để số không bị nhảy khi transaction bị hủy.
+func NextInvoiceNumber(ctx context.Context, issuedAt time.Time) (string, int64, error) {
+	format := strings.TrimSpace(configs.GetInvoiceNumberFormat())
+	if format == "" {
+		format = defaultInvoiceNumberFormat
+	}
+	if !invoiceSeqPattern.MatchString(format) {
+		return "", 0, fmt.Errorf("định dạng số hóa đơn '%s' thiếu {SEQ}", format)
+	}
+
+	issuedAt = issuedAt.In(time.FixedZone("Asia/Ho_Chi_Minh", 7*60*60))
+	period, err := invoiceNumberPeriod(issuedAt)
+	if err != nil {
+		return "", 0, err
+	}
+
+	counterEntry := &collections.Counter{}
+	seq, err := counterEntry.Next(ctx, "invoice_number:"+period)
+	if err != nil {
+		return "", 0, fmt.Errorf("lỗi cấp số hóa đơn: %w", err)
+	}
+	return formatInvoiceNumber(format, issuedAt, seq), seq, nil
+}
+
+// Gán số và tạo hóa đơn (hóa đơn gốc hoặc hóa đơn điều chỉnh) dùng chung một dãy số
+func CreateInvoice(ctx context.Context, invoiceEntry *collections.Invoice) error {
+	invoiceNumber, seq, err := NextInvoiceNumber(ctx, invoiceEntry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	einvoiceSeq, err := nextEInvoiceSeq(ctx, invoiceEntry.CreatedAt, seq)
+	if err != nil {
+		return err
+	}
+	invoiceEntry.InvoiceNumber = invoiceNumber
+	invoiceEntry.InvoiceSeq = seq
+	invoiceEntry.EInvoiceSeq = einvoiceSeq
+	return invoiceEntry.Create(ctx)
+}
+
+// SHDon của hóa đơn điện tử đánh số lại mỗi năm cùng ký hiệu KHHDon.
+// Số hóa đơn đánh số lại theo năm thì dùng chung số thứ tự, kỳ khác (tháng/ngày/không reset) thì dùng bộ đếm riêng theo năm.
+func nextEInvoiceSeq(ctx context.Context, issuedAt time.Time, invoiceSeq int64) (int64, error) {
+	if invoiceNumberReset() == "yearly" {
+		return invoiceSeq, nil
+	}
+	issuedAt = issuedAt.In(time.FixedZone("Asia/Ho_Chi_Minh", 7*60*60))
+	counterEntry := &collections.Counter{}
+	seq, err := counterEntry.Next(ctx, "einvoice_seq:"+issuedAt.Format("2006"))
+	if err != nil {
+		return 0, fmt.Errorf("lỗi cấp số hóa đơn điện tử: %w", err)
+	}
+	return seq, nil
+}
+
+func invoiceNumberReset() string {
+	reset := strings.ToLower(strings.TrimSpace(configs.GetInvoiceNumberReset()))
+	if reset == "" {
+		return defaultInvoiceNumberReset
+	}
+	return reset
+}
+
+// Kỳ đánh số lại của bộ đếm
+func invoiceNumberPeriod(issuedAt time.Time) (string, error) {
+	reset := invoiceNumberReset()
+	switch reset {
+	case "yearly":
+		return issuedAt.Format("2006"), nil
+	case "monthly":
+		return issuedAt.Format("200601"), nil
+	case "daily":
+		return issuedAt.Format("20060102"), nil
+	case "never":
+		return "all", nil
+	default:
+		return "", fmt.Errorf("invoice.number_reset '%s' không hợp lệ", reset)
+	}
+}
+
+func formatInvoiceNumber(format string, issuedAt time.Time, seq int64) string {
+	number := strings.NewReplacer(
+		"{YYYY}", issuedAt.Format("2006"),
+		"{YY}", issuedAt.Format("06"),
+		"{MM}", issuedAt.Format("01"),
+		"{DD}", issuedAt.Format("02"),
+	).Replace(format)
+
+	return invoiceSeqPattern.ReplaceAllStringFunc(number, func(placeholder string) string {
+		width := 0
+		if match := invoiceSeqPattern.FindStringSubmatch(placeholder); match[1] != "" {
+			width, _ = strconv.Atoi(match[1])
+		}
+		return fmt.Sprintf("%0*d", width, seq)
+	})
+}
@@ -0,0 +1,621 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/database"
+	"EventHunting/utils"
+	"EventHunting/view"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	mathrand "math/rand/v2"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Các lượt được đưa vào bốc thăm. Hết thời gian đăng ký thì danh sách này không đổi nữa.
+var drawnLotteryStatuses = []consts.LotteryEntryStatus{
+	consts.LotteryEntryPending,
+	consts.LotteryEntryWon,
+	consts.LotteryEntryLost,
+}
+
+// Kết quả của một lượt tham gia sau khi xáo trộn
+type LotteryResult struct {
+	Rank int  // Thứ tự sau khi xáo trộn, bắt đầu từ 1
+	Won  bool // Còn đủ vé cho toàn bộ số lượng khi tới lượt
+}
+
+// Dữ liệu công khai để kiểm chứng một lần bốc thăm, không chứa thông tin tài khoản
+type LotteryVerification struct {
+	Draw        collections.LotteryDraw `json:"draw"`
+	EntriesHash string                  `json:"entries_hash"` // Mã băm tính lại từ danh sách tham gia hiện tại
+	Verified    bool                    `json:"verified"`
+	Mismatches  []string                `json:"mismatches,omitempty"`
+	Entries     []LotteryVerifiedEntry  `json:"entries"`
+}
+
+type LotteryVerifiedEntry struct {
+	EntryID        primitive.ObjectID        `json:"entry_id"`
+	Quantity       int                       `json:"quantity"`
+	Rank           int                       `json:"rank"`
+	Status         consts.LotteryEntryStatus `json:"status"`          // Kết quả đã ghi
+	ExpectedStatus consts.LotteryEntryStatus `json:"expected_status"` // Kết quả khi chạy lại thuật toán
+}
+
+// Đăng ký (hoặc đổi số lượng) bốc thăm một loại vé trong thời gian nhận đăng ký.
+// Tổng số vé đăng ký của tài khoản trong sự kiện không vượt quá max_ticket_per_user.
+func EnterLottery(ctx context.Context, event *collections.Event, ticketType collections.TicketType, accountID primitive.ObjectID, quantity int, now time.Time) (*collections.LotteryEntry, error) {
+	if !event.LotteryEnabled() {
+		return nil, consts.ErrLotteryNotEnabled
+	}
+	if !lotteryApplyOpen(event, now) {
+		return nil, consts.ErrLotteryClosed
+	}
+	if ticketType.Price != 0 || len(ticketType.PriceTiers) > 0 {
+		return nil, consts.ErrLotteryPaidTicket
+	}
+	if quantity <= 0 || quantity > event.MaxTicketPerUser {
+		return nil, consts.ErrLotteryQuantity
+	}
+
+	lotteryEntry := &collections.LotteryEntry{}
+	entries, err := lotteryEntry.Find(ctx, bson.M{
+		"event_id":   event.ID,
+		"account_id": accountID,
+		"status":     consts.LotteryEntryPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+	total := quantity
+	for _, entry := range entries {
+		if entry.TicketTypeID != ticketType.ID {
+			total += entry.Quantity
+		}
+	}
+	if total > event.MaxTicketPerUser {
+		return nil, consts.ErrLotteryQuantity
+	}
+
+	// Đã có lượt (kể cả đã rút) thì cập nhật lại, tạo đồng thời bị trùng unique index thì đọc lại và cập nhật
+	for attempt := 0; attempt < 2; attempt++ {
+		existing := &collections.LotteryEntry{}
+		err = existing.First(ctx, bson.M{"ticket_type_id": ticketType.ID, "account_id": accountID})
+		if err == nil {
+			err = existing.Update(ctx,
+				bson.M{"_id": existing.ID, "status": bson.M{"$in": []consts.LotteryEntryStatus{consts.LotteryEntryPending, consts.LotteryEntryCancelled}}},
+				bson.M{"$set": bson.M{"quantity": quantity, "status": consts.LotteryEntryPending, "updated_at": now}},
+			)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, consts.ErrLotteryClosed
+			}
+			if err != nil {
+				return nil, err
+			}
+			existing.Quantity = quantity
+			existing.Status = consts.LotteryEntryPending
+			existing.UpdatedAt = now
+			return existing, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+
+		newEntry := &collections.LotteryEntry{
+			ID:           primitive.NewObjectID(),
+			EventID:      event.ID,
+			TicketTypeID: ticketType.ID,
+			AccountID:    accountID,
+			Quantity:     quantity,
+			Status:       consts.LotteryEntryPending,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		err = newEntry.Create(ctx)
+		if err == nil {
+			return newEntry, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+// Rút khỏi bốc thăm, chỉ được khi còn trong thời gian nhận đăng ký
+func CancelLotteryEntry(ctx context.Context, entryID, accountID primitive.ObjectID, now time.Time) error {
+	var (
+		lotteryEntry = &collections.LotteryEntry{}
+		eventEntry   = &collections.Event{}
+	)
+
+	err := lotteryEntry.First(ctx, bson.M{"_id": entryID, "account_id": accountID})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return consts.ErrLotteryEntryNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = eventEntry.First(ctx, bson.M{"_id": lotteryEntry.EventID}); err != nil {
+		return err
+	}
+	if !lotteryApplyOpen(eventEntry, now) {
+		return consts.ErrLotteryClosed
+	}
+
+	err = lotteryEntry.Update(ctx,
+		bson.M{"_id": entryID, "status": consts.LotteryEntryPending},
+		bson.M{"$set": bson.M{"status": consts.LotteryEntryCancelled, "updated_at": now}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return consts.ErrLotteryEntryNotFound
+	}
+	return err
+}
+
+func lotteryApplyOpen(event *collections.Event, now time.Time) bool {
+	return event.LotteryEnabled() &&
+		event.Lottery.Status != consts.LotteryDrawn &&
+		!now.Before(event.Lottery.ApplyStartAt) &&
+		now.Before(event.Lottery.ApplyEndAt)
+}
+
+// Thứ tự xáo trộn n lượt tham gia từ seed: Fisher-Yates với ChaCha8 (C2SP chacha8rand).
+// Không dùng rand.Shuffle / IntN vì cách lấy số trong khoảng có thể đổi giữa các phiên bản Go.
+func LotteryOrder(seed [32]byte, n int) []int {
+	rng := mathrand.NewChaCha8(seed)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	for i := n - 1; i > 0; i-- {
+		j := int(lotteryUint64n(rng, uint64(i+1)))
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// Số ngẫu nhiên trong [0, bound), loại bỏ phần dư để không lệch phân phối
+func lotteryUint64n(rng *mathrand.ChaCha8, bound uint64) uint64 {
+	limit := math.MaxUint64 - (math.MaxUint64%bound+1)%bound
+	for {
+		v := rng.Uint64()
+		if v <= limit {
+			return v % bound
+		}
+	}
+}
+
+// SHA-256 của danh sách tham gia theo thứ tự _id, mỗi dòng "entry_id:quantity"
+func LotteryEntriesHash(entries []collections.LotteryEntry) string {
+	var sb strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&sb, "%s:%d\n", entry.ID.Hex(), entry.Quantity)
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// Chia vé theo thứ tự đã xáo trộn: lượt nào còn đủ vé cho toàn bộ số lượng thì trúng,
+// không đủ thì bỏ qua và xét lượt kế tiếp. capacity = nil là không giới hạn.
+func AllocateLottery(entries []collections.LotteryEntry, order []int, capacity *int) []LotteryResult {
+	results := make([]LotteryResult, len(entries))
+	remaining := 0
+	if capacity != nil {
+		remaining = *capacity
+	}
+	for rank, idx := range order {
+		results[idx].Rank = rank + 1
+		if capacity == nil || entries[idx].Quantity <= remaining {
+			results[idx].Won = true
+			remaining -= entries[idx].Quantity
+		}
+	}
+	return results
+}
+
+func decodeLotterySeed(seedHex string) ([32]byte, error) {
+	var seed [32]byte
+	raw, err := hex.DecodeString(seedHex)
+	if err != nil || len(raw) != len(seed) {
+		return seed, fmt.Errorf("seed bốc thăm không hợp lệ: %s", seedHex)
+	}
+	copy(seed[:], raw)
+	return seed, nil
+}
+
+// Bốc thăm các sự kiện đã hết thời gian nhận đăng ký. Mỗi loại vé chốt seed một lần rồi ghi kết quả từng lượt
+// trong transaction riêng, job lỗi giữa chừng thì lần chạy sau tiếp tục với đúng seed đó. Trả về số sự kiện đã bốc xong.
+func DrawLotteries(ctx context.Context) (int, error) {
+	var (
+		eventEntry   = &collections.Event{}
+		lotteryEntry = &collections.LotteryEntry{}
+	)
+
+	now := time.Now()
+	events, err := eventEntry.Find(ctx, utils.GetFilter(bson.M{
+		"lottery.enabled":      true,
+		"lottery.status":       consts.LotteryOpen,
+		"lottery.apply_end_at": bson.M{"$lte": now},
+	}))
+	if err != nil {
+		return 0, err
+	}
+
+	drawnCount := 0
+	for i := range events {
+		event := &events[i]
+		entries, err := lotteryEntry.Find(ctx,
+			bson.M{"event_id": event.ID, "status": bson.M{"$in": drawnLotteryStatuses}},
+			options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}),
+		)
+		if err != nil {
+			log.Printf("ERROR: Lấy danh sách bốc thăm sự kiện %s thất bại: %v", event.ID.Hex(), err)
+			continue
+		}
+
+		var ticketTypeIDs []primitive.ObjectID
+		byTicketType := make(map[primitive.ObjectID][]collections.LotteryEntry)
+		for _, entry := range entries {
+			if _, ok := byTicketType[entry.TicketTypeID]; !ok {
+				ticketTypeIDs = append(ticketTypeIDs, entry.TicketTypeID)
+			}
+			byTicketType[entry.TicketTypeID] = append(byTicketType[entry.TicketTypeID], entry)
+		}
+
+		done := true
+		for _, ticketTypeID := range ticketTypeIDs {
+			if err = drawTicketTypeLottery(ctx, event, ticketTypeID, byTicketType[ticketTypeID]); err != nil {
+				log.Printf("ERROR: Bốc thăm loại vé %s của sự kiện %s thất bại: %v", ticketTypeID.Hex(), event.ID.Hex(), err)
+				done = false
+			}
+		}
+		if !done {
+			continue
+		}
+
+		drawnAt := time.Now()
+		err = eventEntry.Update(ctx,
+			bson.M{"_id": event.ID, "lottery.status": consts.LotteryOpen},
+			bson.M{"$set": bson.M{"lottery.status": consts.LotteryDrawn, "lottery.drawn_at": drawnAt}},
+		)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Printf("ERROR: Cập nhật trạng thái bốc thăm sự kiện %s thất bại: %v", event.ID.Hex(), err)
+			continue
+		}
+		drawnCount++
+	}
+	return drawnCount, nil
+}
+
+// Chốt seed cho loại vé (hoặc dùng lại seed đã chốt) và ghi kết quả cho các lượt chưa có kết quả
+func drawTicketTypeLottery(ctx context.Context, event *collections.Event, ticketTypeID primitive.ObjectID, entries []collections.LotteryEntry) error {
+	drawEntry := &collections.LotteryDraw{}
+
+	entriesHash := LotteryEntriesHash(entries)
+	err := drawEntry.First(ctx, bson.M{"ticket_type_id": ticketTypeID})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		drawEntry, err = createLotteryDraw(ctx, event, ticketTypeID, entries, entriesHash)
+	}
+	if err != nil {
+		return err
+	}
+	if drawEntry.Status == consts.LotteryDrawCompleted {
+		return nil
+	}
+	if drawEntry.EntriesHash != entriesHash || drawEntry.EntryCount != len(entries) {
+		return fmt.Errorf("danh sách tham gia đã thay đổi sau khi chốt seed (%s != %s)", entriesHash, drawEntry.EntriesHash)
+	}
+
+	seed, err := decodeLotterySeed(drawEntry.Seed)
+	if err != nil {
+		return err
+	}
+	results := AllocateLottery(entries, LotteryOrder(seed, len(entries)), drawEntry.Capacity)
+
+	session, err := database.GetDB().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	winnerCount, wonQuantity := 0, 0
+	for i, entry := range entries {
+		if results[i].Won {
+			winnerCount++
+			wonQuantity += entry.Quantity
+		}
+		if entry.Status != consts.LotteryEntryPending {
+			continue
+		}
+
+		_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+			return nil, applyLotteryResult(sessionContext, drawEntry, entry, results[i], time.Now())
+		})
+		if err != nil {
+			return fmt.Errorf("lỗi ghi kết quả lượt %s: %w", entry.ID.Hex(), err)
+		}
+		if results[i].Won {
+			AdjustInventory(ctx, map[primitive.ObjectID]int{ticketTypeID: -entry.Quantity})
+		}
+	}
+
+	completedAt := time.Now()
+	return drawEntry.Update(ctx,
+		bson.M{"_id": drawEntry.ID},
+		bson.M{"$set": bson.M{
+			"status":       consts.LotteryDrawCompleted,
+			"winner_count": winnerCount,
+			"won_quantity": wonQuantity,
+			"completed_at": completedAt,
+		}},
+	)
+}
+
+// Seed sinh bằng crypto/rand sau khi đã đóng đăng ký và được ghi cùng mã băm danh sách trước khi chia vé
+func createLotteryDraw(ctx context.Context, event *collections.Event, ticketTypeID primitive.ObjectID, entries []collections.LotteryEntry, entriesHash string) (*collections.LotteryDraw, error) {
+	ticketTypeEntry := &collections.TicketType{}
+
+	// Loại vé đã bị xóa / ngừng bán thì không còn vé để chia
+	capacity := 0
+	err := ticketTypeEntry.First(ctx, utils.GetFilter(bson.M{"_id": ticketTypeID, "status": consts.TicketTypeActive}))
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+	case err != nil:
+		return nil, err
+	case ticketTypeEntry.Quantity == nil:
+		capacity = -1
+	default:
+		capacity = *ticketTypeEntry.Quantity - ticketTypeEntry.RegisteredCount
+		if capacity < 0 {
+			capacity = 0
+		}
+	}
+
+	var seed [32]byte
+	if _, err = rand.Read(seed[:]); err != nil {
+		return nil, err
+	}
+
+	drawEntry := &collections.LotteryDraw{
+		ID:           primitive.NewObjectID(),
+		EventID:      event.ID,
+		TicketTypeID: ticketTypeID,
+		Algorithm:    consts.LotteryAlgorithmV1,
+		Seed:         hex.EncodeToString(seed[:]),
+		EntriesHash:  entriesHash,
+		EntryCount:   len(entries),
+		Status:       consts.LotteryDrawRunning,
+		CreatedAt:    time.Now(),
+	}
+	if capacity >= 0 {
+		drawEntry.Capacity = &capacity
+	}
+
+	err = drawEntry.Create(ctx)
+	if mongo.IsDuplicateKeyError(err) {
+		// Job khác vừa chốt seed trước, dùng seed đó
+		drawEntry = &collections.LotteryDraw{}
+		err = drawEntry.First(ctx, bson.M{"ticket_type_id": ticketTypeID})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return drawEntry, nil
+}
+
+// Ghi kết quả một lượt trong transaction. Lượt trúng được tạo đơn 0đ đã xác nhận kèm hóa đơn và email vé,
+// mọi lượt đều có email báo kết quả.
+func applyLotteryResult(ctx context.Context, drawEntry *collections.LotteryDraw, entry collections.LotteryEntry, result LotteryResult, now time.Time) error {
+	var (
+		lotteryEntry    = &collections.LotteryEntry{}
+		ticketTypeEntry = &collections.TicketType{}
+		eventEntry      = &collections.Event{}
+	)
+
+	set := bson.M{
+		"status":     consts.LotteryEntryLost,
+		"draw_id":    drawEntry.ID,
+		"draw_rank":  result.Rank,
+		"updated_at": now,
+	}
+
+	if result.Won {
+		unitPrice := 0
+		newRegistration := collections.Registration{
+			ID:      primitive.NewObjectID(),
+			EventID: entry.EventID,
+			Tickets: []collections.RegistrationTicket{{
+				TicketTypeID: entry.TicketTypeID,
+				Quantity:     entry.Quantity,
+				UnitPrice:    &unitPrice,
+			}},
+			TotalQuantity:  entry.Quantity,
+			TotalPrice:     0,
+			Status:         consts.RegistrationPaid,
+			PaymentMethod:  consts.PaymentMethodFree,
+			PaidAt:         &now,
+			LotteryEntryID: entry.ID,
+			CreatedBy:      entry.AccountID,
+			UpdatedBy:      entry.AccountID,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+
+		// Số vé trúng đã được giới hạn theo capacity chốt lúc tạo seed nên không đặt điều kiện registered_count ở đây
+		err := ticketTypeEntry.Update(ctx,
+			bson.M{"_id": entry.TicketTypeID},
+			bson.M{"$inc": bson.M{"registered_count": entry.Quantity}},
+		)
+		if err != nil {
+			return fmt.Errorf("lỗi cập nhật số vé: %w", err)
+		}
+		err = eventEntry.Update(ctx,
+			bson.M{"_id": entry.EventID},
+			bson.M{"$inc": bson.M{"number_of_participants": entry.Quantity}},
+		)
+		if err != nil {
+			return fmt.Errorf("lỗi cập nhật sự kiện: %w", err)
+		}
+
+		newInvoice, err := BuildInvoiceForRegistration(ctx, &newRegistration, consts.PaymentMethodFree, "", now)
+		if err != nil {
+			return fmt.Errorf("lỗi tạo hóa đơn: %w", err)
+		}
+		if err = CreateInvoice(ctx, newInvoice); err != nil {
+			return fmt.Errorf("lỗi tạo hóa đơn: %w", err)
+		}
+		newRegistration.InvoiceID = newInvoice.ID
+
+		if err = newRegistration.Create(ctx); err != nil {
+			return fmt.Errorf("lỗi tạo đăng ký: %w", err)
+		}
+		if err = CreateTicketEmailOutbox(ctx, newRegistration.ID); err != nil {
+			return err
+		}
+
+		set["status"] = consts.LotteryEntryWon
+		set["registration_id"] = newRegistration.ID
+	}
+
+	err := lotteryEntry.Update(ctx,
+		bson.M{"_id": entry.ID, "status": consts.LotteryEntryPending},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return fmt.Errorf("lỗi cập nhật lượt bốc thăm: %w", err)
+	}
+	return CreateLotteryResultOutbox(ctx, entry.ID)
+}
+
+// Chạy lại thuật toán từ seed và danh sách tham gia đã lưu rồi so với kết quả đã ghi
+func VerifyLotteryDraw(ctx context.Context, drawID primitive.ObjectID) (*LotteryVerification, error) {
+	var (
+		drawEntry    = &collections.LotteryDraw{}
+		lotteryEntry = &collections.LotteryEntry{}
+	)
+
+	err := drawEntry.First(ctx, bson.M{"_id": drawID})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, consts.ErrLotteryDrawNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := lotteryEntry.Find(ctx,
+		bson.M{"ticket_type_id": drawEntry.TicketTypeID, "status": bson.M{"$in": drawnLotteryStatuses}},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &LotteryVerification{
+		Draw:        *drawEntry,
+		EntriesHash: LotteryEntriesHash(entries),
+		Entries:     make([]LotteryVerifiedEntry, 0, len(entries)),
+	}
+	if verification.EntriesHash != drawEntry.EntriesHash {
+		verification.Mismatches = append(verification.Mismatches, "Mã băm danh sách tham gia không khớp")
+	}
+	if drawEntry.Algorithm != consts.LotteryAlgorithmV1 {
+		verification.Mismatches = append(verification.Mismatches, fmt.Sprintf("Không hỗ trợ thuật toán %s", drawEntry.Algorithm))
+	}
+	seed, err := decodeLotterySeed(drawEntry.Seed)
+	if err != nil {
+		verification.Mismatches = append(verification.Mismatches, err.Error())
+	}
+	if len(verification.Mismatches) > 0 {
+		return verification, nil
+	}
+
+	results := AllocateLottery(entries, LotteryOrder(seed, len(entries)), drawEntry.Capacity)
+	for i, entry := range entries {
+		expected := consts.LotteryEntryLost
+		if results[i].Won {
+			expected = consts.LotteryEntryWon
+		}
+		verification.Entries = append(verification.Entries, LotteryVerifiedEntry{
+			EntryID:        entry.ID,
+			Quantity:       entry.Quantity,
+			Rank:           results[i].Rank,
+			Status:         entry.Status,
+			ExpectedStatus: expected,
+		})
+		if entry.Status == consts.LotteryEntryPending {
+			continue
+		}
+		if entry.Status != expected || entry.DrawRank != results[i].Rank || entry.DrawID != drawEntry.ID {
+			verification.Mismatches = append(verification.Mismatches, fmt.Sprintf("Lượt %s: đã ghi %s (thứ tự %d), kết quả đúng %s (thứ tự %d)",
+				entry.ID.Hex(), entry.Status, entry.DrawRank, expected, results[i].Rank))
+		}
+	}
+	verification.Verified = len(verification.Mismatches) == 0 && drawEntry.Status == consts.LotteryDrawCompleted
+	return verification, nil
+}
+
+// Gửi email báo kết quả bốc thăm. Lượt chưa có kết quả thì báo lỗi dữ liệu, không retry.
+func SendLotteryResultEmail(entryID primitive.ObjectID) error {
+	var (
+		lotteryEntry    = &collections.LotteryEntry{}
+		eventEntry      = &collections.Event{}
+		accountEntry    = &collections.Account{}
+		ticketTypeEntry = &collections.TicketType{}
+	)
+
+	err := lotteryEntry.First(nil, bson.M{"_id": entryID})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: LotteryEntry ID %s", consts.ErrFatalDataNotFound, entryID.Hex())
+		}
+		return err
+	}
+	if lotteryEntry.Status != consts.LotteryEntryWon && lotteryEntry.Status != consts.LotteryEntryLost {
+		return fmt.Errorf("%w: lượt bốc thăm %s chưa có kết quả (%s)", consts.ErrFatalInvalidData, entryID.Hex(), lotteryEntry.Status)
+	}
+
+	if err = eventEntry.First(nil, bson.M{"_id": lotteryEntry.EventID}); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: Event ID %s", consts.ErrFatalDataNotFound, lotteryEntry.EventID.Hex())
+		}
+		return err
+	}
+	if err = accountEntry.First(bson.M{"_id": lotteryEntry.AccountID}); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: Account ID %s", consts.ErrFatalDataNotFound, lotteryEntry.AccountID.Hex())
+		}
+		return err
+	}
+	if err = ticketTypeEntry.First(nil, bson.M{"_id": lotteryEntry.TicketTypeID}); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: TicketType ID %s", consts.ErrFatalDataNotFound, lotteryEntry.TicketTypeID.Hex())
+		}
+		return err
+	}
+
+	subject, htmlBody, err := view.BuildLotteryResultEmail(eventEntry, accountEntry, ticketTypeEntry, lotteryEntry)
+	if err != nil {
+		return fmt.Errorf("%w: %v", consts.ErrEmailBuild, err)
+	}
+
+	emailService := utils.NewEmailService()
+	return emailService.SendEmail(utils.EmailPayload{
+		Subject:  subject,
+		To:       []string{accountEntry.Email},
+		HTMLBody: htmlBody,
+	})
+}
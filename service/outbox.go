@@ -0,0 +1,54 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ghi message sinh vé + gửi email vào outbox. Phải gọi bằng session context của transaction chuyển đơn sang PAID
+// để message chỉ tồn tại khi đơn thực sự được xác nhận.
+func CreateTicketEmailOutbox(ctx context.Context, regisID primitive.ObjectID) error {
+	now := time.Now()
+	outboxEntry := &collections.OutboxMessage{
+		ID:          primitive.NewObjectID(),
+		Topic:       consts.OutboxTopicTicketEmail,
+		Data:        bson.M{"registration_id": regisID.Hex()},
+		Status:      consts.OutboxStatusPending,
+		AvailableAt: now,
+		CreatedAt:   now,
+	}
+	return outboxEntry.Create(ctx)
+}
+
+// Ghi message email mời giữ vé từ danh sách chờ, gọi trong transaction giữ vé
+func CreateWaitlistOfferOutbox(ctx context.Context, entryID primitive.ObjectID) error {
+	now := time.Now()
+	outboxEntry := &collections.OutboxMessage{
+		ID:          primitive.NewObjectID(),
+		Topic:       consts.OutboxTopicWaitlistOffer,
+		Data:        bson.M{"waitlist_entry_id": entryID.Hex()},
+		Status:      consts.OutboxStatusPending,
+		AvailableAt: now,
+		CreatedAt:   now,
+	}
+	return outboxEntry.Create(ctx)
+}
+
+// Ghi message email báo kết quả bốc thăm, gọi trong transaction ghi kết quả lượt bốc thăm
+func CreateLotteryResultOutbox(ctx context.Context, entryID primitive.ObjectID) error {
+	now := time.Now()
+	outboxEntry := &collections.OutboxMessage{
+		ID:          primitive.NewObjectID(),
+		Topic:       consts.OutboxTopicLotteryResult,
+		Data:        bson.M{"lottery_entry_id": entryID.Hex()},
+		Status:      consts.OutboxStatusPending,
+		AvailableAt: now,
+		CreatedAt:   now,
+	}
+	return outboxEntry.Create(ctx)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/database"
+	"EventHunting/payment"
+	"EventHunting/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Kiểm tra đơn của thông báo thanh toán: tồn tại, đúng cổng, đúng số tiền và còn chờ thanh toán
+func VerifyPaymentOrder(ctx context.Context, providerName string, result *payment.CallbackResult) (*collections.Registration, error) {
+	var (
+		regisEntry = &collections.Registration{}
+	)
+
+	regisID, err := primitive.ObjectIDFromHex(result.OrderID)
+	if err != nil {
+		return nil, consts.ErrPaymentOrderNotFound
+	}
+
+	err = regisEntry.First(ctx, utils.GetFilter(bson.M{"_id": regisID}))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, consts.ErrPaymentOrderNotFound
+		}
+		return nil, err
+	}
+
+	// Callback phải đến từ đúng cổng mà đơn đã chọn
+	method := regisEntry.PaymentMethod
+	if method == "" {
+		method = consts.PaymentMethodVNPAY
+	}
+	if !strings.EqualFold(method, providerName) {
+		log.Printf("ERROR: Đơn %s thanh toán qua %s nhưng nhận callback từ %s", result.OrderID, method, providerName)
+		return regisEntry, consts.ErrPaymentOrderNotFound
+	}
+
+	// Kiểm tra số tiền
+	if result.Amount != int64(regisEntry.TotalPrice) {
+		log.Printf("ERROR: Sai số tiền đơn %s. %s: %d, DB: %d", result.OrderID, providerName, result.Amount, regisEntry.TotalPrice)
+		if result.Success {
+			FlagPaymentDiscrepancy(ctx, regisEntry, providerName, result, consts.DiscrepancyAmountMismatch)
+		}
+		return regisEntry, consts.ErrPaymentInvalidAmount
+	}
+
+	// Đơn đã xử lý xong thì mọi thông báo (kể cả giao dịch thất bại) đều phản hồi là đã xác nhận
+	switch regisEntry.Status {
+	case consts.RegistrationPaid, consts.RegistrationRefunded:
+		return regisEntry, consts.ErrPaymentAlreadyConfirmed
+	case consts.RegistrationCancelled:
+		if result.Success {
+			log.Printf("CRITICAL: Đơn %s đã bị hủy lúc %v nhưng nhận được thanh toán qua %s (TransactionNo: %s), cần hoàn tiền thủ công", result.OrderID, regisEntry.PaymentVoidedAt, providerName, result.TransactionNo)
+			FlagPaymentDiscrepancy(ctx, regisEntry, providerName, result, consts.DiscrepancyPaidCancelled)
+		}
+		return regisEntry, consts.ErrRegistrationNotPending
+	case consts.RegistrationPending:
+	default:
+		return regisEntry, consts.ErrPaymentOrderNotFound
+	}
+
+	return regisEntry, nil
+}
+
+// Xác nhận thanh toán thành công cho đơn đăng ký: tạo hóa đơn và chuyển đơn sang PAID trong một transaction.
+// Dùng chung cho callback/IPN của mọi cổng thanh toán. Job sinh vé + gửi email được ghi vào outbox cùng transaction.
+func SettlePayment(ctx context.Context, providerName string, result *payment.CallbackResult) (*collections.Registration, error) {
+	regisEntry, err := VerifyPaymentOrder(ctx, providerName, result)
+	if err != nil {
+		return regisEntry, err
+	}
+	regisID := regisEntry.ID
+
+	paidAt := result.PaidAt
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+
+	//Tạo hóa đơn
+	newInvoice, err := CreateInvoiceForRegistration(regisID, providerName, result.TransactionNo, paidAt)
+	if err != nil {
+		return regisEntry, fmt.Errorf("lỗi prepare invoice data: %w", err)
+	}
+
+	session, err := database.GetDB().Client().StartSession()
+	if err != nil {
+		return regisEntry, err
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if err := CreateInvoice(sessCtx, newInvoice); err != nil {
+			return nil, err
+		}
+
+		// Chỉ chuyển sang PAID nếu đơn vẫn đang chờ (tránh ghi đè đơn vừa bị hủy)
+		err := regisEntry.Update(sessCtx,
+			bson.M{"_id": regisID, "status": consts.RegistrationPending},
+			bson.M{"$set": bson.M{
+				"status":                   consts.RegistrationPaid,
+				"invoice_id":               newInvoice.ID,
+				"paid_at":                  paidAt,
+				"updated_at":               time.Now(),
+				"payment_transaction_code": result.TransactionNo,
+			}},
+		)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, consts.ErrRegistrationNotPending
+			}
+			return nil, err
+		}
+
+		// Job sinh vé đi cùng transaction, relay sẽ đẩy sang queue sau khi commit
+		if err := CreateTicketEmailOutbox(sessCtx, regisID); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err != nil {
+		if errors.Is(err, consts.ErrRegistrationNotPending) {
+			log.Printf("CRITICAL: Đơn %s bị hủy trong lúc xử lý thanh toán (TransactionNo: %s), cần hoàn tiền thủ công", result.OrderID, result.TransactionNo)
+			regisEntry.Status = consts.RegistrationCancelled
+			FlagPaymentDiscrepancy(ctx, regisEntry, providerName, result, consts.DiscrepancyPaidCancelled)
+		}
+		return regisEntry, err
+	}
+
+	regisEntry.Status = consts.RegistrationPaid
+	regisEntry.InvoiceID = newInvoice.ID
+	regisEntry.PaidAt = &paidAt
+	regisEntry.PaymentTransactionCode = result.TransactionNo
+	return regisEntry, nil
+}
+
+// Ghi nhận sai lệch thanh toán cho admin, bỏ qua nếu cùng đơn/loại/giao dịch đã được ghi nhận
+func FlagPaymentDiscrepancy(ctx context.Context, regisEntry *collections.Registration, providerName string, result *payment.CallbackResult, discrepancyType consts.PaymentDiscrepancyType) {
+	discrepancyEntry := &collections.PaymentDiscrepancy{}
+
+	filter := bson.M{
+		"registration_id": regisEntry.ID,
+		"type":            discrepancyType,
+		"transaction_no":  result.TransactionNo,
+	}
+	err := discrepancyEntry.First(ctx, filter)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Printf("ERROR: Không kiểm tra được sai lệch thanh toán của đơn %s: %v", regisEntry.ID.Hex(), err)
+		return
+	}
+
+	now := time.Now()
+	discrepancyEntry = &collections.PaymentDiscrepancy{
+		ID:             primitive.NewObjectID(),
+		RegistrationID: regisEntry.ID,
+		EventID:        regisEntry.EventID,
+		OwnerID:        regisEntry.CreatedBy,
+		Provider:       providerName,
+		Type:           discrepancyType,
+		Status:         consts.DiscrepancyStatusOpen,
+		TransactionNo:  result.TransactionNo,
+		PaidAmount:     result.Amount,
+		ExpectedAmount: regisEntry.TotalPrice,
+		RegisStatus:    string(regisEntry.Status),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := discrepancyEntry.Create(ctx); err != nil {
+		log.Printf("CRITICAL: Không ghi nhận được sai lệch %s của đơn %s: %v", discrepancyType, regisEntry.ID.Hex(), err)
+		return
+	}
+	log.Printf("WARN: Đã ghi nhận sai lệch thanh toán %s cho đơn %s (%s, TransactionNo: %s)", discrepancyType, regisEntry.ID.Hex(), providerName, result.TransactionNo)
+}
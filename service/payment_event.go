@@ -0,0 +1,38 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/payment"
+	"errors"
+	"log"
+)
+
+// Ghi sổ callback thanh toán; lỗi ghi sổ chỉ log, không ảnh hưởng phản hồi cho cổng
+func RecordPaymentEvent(event *collections.PaymentEvent) {
+	if err := event.Create(nil); err != nil {
+		log.Printf("ERROR: Không ghi được payment event %s/%s cho đơn %s: %v", event.Provider, event.Endpoint, event.OrderID, err)
+	}
+}
+
+// Phân loại kết quả xử lý callback để lưu vào sổ
+func PaymentEventOutcomeOf(result *payment.CallbackResult, err error) consts.PaymentEventOutcome {
+	switch {
+	case result == nil:
+		return consts.PaymentEventInvalidSignature
+	case errors.Is(err, consts.ErrPaymentOrderNotFound):
+		return consts.PaymentEventOrderNotFound
+	case errors.Is(err, consts.ErrPaymentInvalidAmount):
+		return consts.PaymentEventInvalidAmount
+	case errors.Is(err, consts.ErrPaymentAlreadyConfirmed):
+		return consts.PaymentEventAlreadyConfirmed
+	case errors.Is(err, consts.ErrRegistrationNotPending):
+		return consts.PaymentEventOrderCancelled
+	case err != nil:
+		return consts.PaymentEventError
+	case !result.Success:
+		return consts.PaymentEventPaymentFailed
+	default:
+		return consts.PaymentEventSettled
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Mã giảm giá không phân biệt hoa thường, lưu dạng viết hoa
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Kiểm tra mã giảm giá trước khi mở transaction (tồn tại, thời hạn, loại vé, số lượt) và tính số tiền giảm.
+// Số lượt chỉ được giữ chỗ thật sự trong RedeemPromoCode.
+func PreparePromoCode(
+	ctx context.Context,
+	eventID primitive.ObjectID,
+	code string,
+	accountID primitive.ObjectID,
+	tickets []collections.RegistrationTicket,
+	now time.Time,
+) (*collections.PromoCode, *collections.RegistrationDiscount, error) {
+	var (
+		promoEntry = &collections.PromoCode{}
+		usageEntry = &collections.PromoCodeUsage{}
+	)
+
+	err := promoEntry.First(ctx, bson.M{
+		"event_id": eventID,
+		"code":     NormalizePromoCode(code),
+		"status":   consts.PromoCodeActive,
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, consts.ErrPromoCodeInvalid
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if promoEntry.StartAt != nil && now.Before(*promoEntry.StartAt) {
+		return nil, nil, consts.ErrPromoCodeNotStarted
+	}
+	if promoEntry.EndAt != nil && now.After(*promoEntry.EndAt) {
+		return nil, nil, consts.ErrPromoCodeExpired
+	}
+	if promoEntry.MaxUses != nil && promoEntry.UsedCount >= *promoEntry.MaxUses {
+		return nil, nil, consts.ErrPromoCodeExhausted
+	}
+	if promoEntry.MaxUsesPerUser != nil {
+		err = usageEntry.First(ctx, bson.M{"promo_code_id": promoEntry.ID, "account_id": accountID})
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil, err
+		}
+		if err == nil && usageEntry.Count >= *promoEntry.MaxUsesPerUser {
+			return nil, nil, consts.ErrPromoCodeUserLimit
+		}
+	}
+
+	discount := CalculatePromoDiscount(promoEntry, tickets)
+	if discount == nil {
+		return nil, nil, consts.ErrPromoCodeNotApplicable
+	}
+	return promoEntry, discount, nil
+}
+
+// Tính số tiền giảm trên các dòng vé (giá đã chốt) thuộc phạm vi của mã và phân bổ theo tỷ lệ thành tiền từng dòng.
+// Trả về nil nếu đơn không có vé có phí nào thuộc phạm vi của mã.
+func CalculatePromoDiscount(promo *collections.PromoCode, tickets []collections.RegistrationTicket) *collections.RegistrationDiscount {
+	allowed := make(map[primitive.ObjectID]bool, len(promo.TicketTypeIDs))
+	for _, id := range promo.TicketTypeIDs {
+		allowed[id] = true
+	}
+
+	var (
+		eligible         []collections.RegistrationDiscountAllocation
+		eligibleSubtotal int
+	)
+	for _, ticket := range tickets {
+		if len(allowed) > 0 && !allowed[ticket.TicketTypeID] {
+			continue
+		}
+		if ticket.UnitPrice == nil {
+			continue
+		}
+		lineTotal := *ticket.UnitPrice * ticket.Quantity
+		if lineTotal <= 0 {
+			continue
+		}
+		eligible = append(eligible, collections.RegistrationDiscountAllocation{TicketTypeID: ticket.TicketTypeID, Amount: lineTotal})
+		eligibleSubtotal += lineTotal
+	}
+	if eligibleSubtotal == 0 {
+		return nil
+	}
+
+	amount := 0
+	switch promo.DiscountType {
+	case consts.PromoDiscountPercent:
+		amount = eligibleSubtotal * promo.DiscountValue / 100
+		if promo.MaxDiscount != nil && amount > *promo.MaxDiscount {
+			amount = *promo.MaxDiscount
+		}
+	case consts.PromoDiscountFixed:
+		amount = promo.DiscountValue
+	}
+	if amount > eligibleSubtotal {
+		amount = eligibleSubtotal
+	}
+	if amount <= 0 {
+		return nil
+	}
+
+	// Dòng cuối nhận phần dư làm tròn để tổng phân bổ đúng bằng số tiền giảm
+	remaining := amount
+	for i := range eligible {
+		share := remaining
+		if i < len(eligible)-1 {
+			share = amount * eligible[i].Amount / eligibleSubtotal
+		}
+		eligible[i].Amount = share
+		remaining -= share
+	}
+
+	return &collections.RegistrationDiscount{
+		PromoCodeID: promo.ID,
+		Code:        promo.Code,
+		Amount:      amount,
+		Allocations: eligible,
+	}
+}
+
+// Giữ một lượt dùng mã trong transaction đăng ký. Điều kiện số lượt nằm trong câu lệnh cập nhật
+// nên hai đơn đồng thời không thể cùng lấy lượt cuối.
+func RedeemPromoCode(ctx context.Context, promo *collections.PromoCode, accountID primitive.ObjectID, now time.Time) error {
+	var (
+		promoEntry = &collections.PromoCode{}
+		usageEntry = &collections.PromoCodeUsage{}
+	)
+
+	err := promoEntry.Update(ctx,
+		bson.M{
+			"_id":    promo.ID,
+			"status": consts.PromoCodeActive,
+			"$or": bson.A{
+				bson.M{"max_uses": bson.M{"$exists": false}},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$used_count", "$max_uses"}}},
+			},
+		},
+		bson.M{
+			"$inc": bson.M{"used_count": 1},
+			"$set": bson.M{"updated_at": now},
+		},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return consts.ErrPromoCodeExhausted
+	}
+	if err != nil {
+		return err
+	}
+
+	count, err := usageEntry.Increase(ctx, promo.ID, accountID, 1)
+	if err != nil {
+		return err
+	}
+	if promo.MaxUsesPerUser != nil && count > *promo.MaxUsesPerUser {
+		return consts.ErrPromoCodeUserLimit
+	}
+	return nil
+}
+
+// Trả lại lượt dùng mã khi đơn chờ thanh toán bị hủy
+func ReleasePromoCode(ctx context.Context, reg collections.Registration) error {
+	if reg.Discount == nil {
+		return nil
+	}
+	var (
+		promoEntry = &collections.PromoCode{}
+		usageEntry = &collections.PromoCodeUsage{}
+	)
+
+	err := promoEntry.Update(ctx,
+		bson.M{"_id": reg.Discount.PromoCodeID, "used_count": bson.M{"$gt": 0}},
+		bson.M{
+			"$inc": bson.M{"used_count": -1},
+			"$set": bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	_, err = usageEntry.Increase(ctx, reg.Discount.PromoCodeID, reg.CreatedBy, -1)
+	return err
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/payment"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Kết quả đối soát một đơn với cổng thanh toán
+type ReconcileOutcome string
+
+const (
+	ReconcileSettled     ReconcileOutcome = "settled"     // Cổng đã thu tiền, đơn được chuyển sang PAID
+	ReconcileUnpaid      ReconcileOutcome = "unpaid"      // Chưa thanh toán / thanh toán thất bại
+	ReconcilePending     ReconcileOutcome = "pending"     // Giao dịch đang xử lý ở cổng
+	ReconcileDiscrepancy ReconcileOutcome = "discrepancy" // Đã thu tiền nhưng đơn đã hủy hoặc sai số tiền
+	ReconcileSkipped     ReconcileOutcome = "skipped"     // Đơn đã được xác nhận hoặc không qua cổng
+)
+
+// Tra cứu giao dịch của đơn ở cổng thanh toán và xác nhận qua cùng luồng hóa đơn/vé như callback.
+// Đơn đã hủy mà cổng báo đã thu tiền sẽ được ghi nhận sai lệch cho admin.
+func ReconcileRegistration(ctx context.Context, regisEntry collections.Registration) (ReconcileOutcome, error) {
+	provider, err := payment.Get(regisEntry.PaymentMethod)
+	if err != nil {
+		return ReconcileSkipped, err
+	}
+	if provider.Name() == consts.PaymentMethodFree {
+		return ReconcileSkipped, nil
+	}
+
+	transaction, err := provider.QueryTransaction(ctx, payment.QueryTransactionRequest{
+		OrderID:   regisEntry.ID.Hex(),
+		CreatedAt: regisEntry.CreatedAt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("lỗi tra cứu giao dịch %s: %w", provider.Name(), err)
+	}
+
+	switch transaction.Status {
+	case payment.TransactionSuccess:
+	case payment.TransactionPending:
+		return ReconcilePending, nil
+	default:
+		return ReconcileUnpaid, nil
+	}
+
+	_, err = SettlePayment(ctx, provider.Name(), &payment.CallbackResult{
+		OrderID:       regisEntry.ID.Hex(),
+		Success:       true,
+		Amount:        transaction.Amount,
+		TransactionNo: transaction.TransactionNo,
+		ResponseCode:  transaction.ResponseCode,
+		Message:       transaction.Message,
+		PaidAt:        transaction.PaidAt,
+	})
+	switch {
+	case err == nil:
+		return ReconcileSettled, nil
+	case errors.Is(err, consts.ErrPaymentAlreadyConfirmed):
+		return ReconcileSkipped, nil
+	case errors.Is(err, consts.ErrRegistrationNotPending), errors.Is(err, consts.ErrPaymentInvalidAmount):
+		return ReconcileDiscrepancy, nil
+	default:
+		return "", err
+	}
+}
@@ -0,0 +1,612 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/database"
+	"EventHunting/payment"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Tạo yêu cầu hoàn tiền cho các vé của đơn đăng ký.
+// Người mua (byStaff = false) bị giới hạn bởi chính sách hoàn tiền của sự kiện và phải chờ duyệt;
+// ban tổ chức/admin (byStaff = true) hoàn 100% và được xử lý ngay.
+func RequestRefund(
+	ctx context.Context,
+	regisEntry *collections.Registration,
+	eventEntry *collections.Event,
+	ticketIDs []primitive.ObjectID,
+	reason string,
+	requesterID primitive.ObjectID,
+	byStaff bool,
+) (*collections.Refund, error) {
+	var (
+		ticketEntry  = &collections.Ticket{}
+		invoiceEntry = &collections.Invoice{}
+	)
+
+	if regisEntry.Status != consts.RegistrationPaid {
+		return nil, consts.ErrRefundNotPaid
+	}
+
+	refundPercent := 100
+	if !byStaff {
+		policy := eventEntry.RefundPolicy
+		if policy == nil || !policy.Enabled {
+			return nil, consts.ErrRefundNotAllowed
+		}
+		deadline := atClock(eventEntry.EventTime.StartDate, eventEntry.EventTime.StartTime, 0, 0).Add(-time.Duration(policy.DeadlineHours) * time.Hour)
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w (hạn chót %s)", consts.ErrRefundDeadlinePassed, deadline.Format("15:04 02/01/2006"))
+		}
+		refundPercent = policy.RefundPercent
+	}
+
+	// Chỉ hoàn vé chưa check-in
+	ticketFilter := bson.M{
+		"regis_id": regisEntry.ID,
+		"status":   consts.TicketStatusConfirmed,
+	}
+	if len(ticketIDs) > 0 {
+		ticketFilter["_id"] = bson.M{"$in": ticketIDs}
+	}
+	tickets, err := ticketEntry.Find(ctx, ticketFilter)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi hệ thống khi tìm vé: %w", err)
+	}
+	if len(tickets) == 0 || (len(ticketIDs) > 0 && len(tickets) != len(ticketIDs)) {
+		return nil, consts.ErrRefundNoTickets
+	}
+
+	refundTicketIDs := make([]primitive.ObjectID, 0, len(tickets))
+	for _, ticket := range tickets {
+		refundTicketIDs = append(refundTicketIDs, ticket.ID)
+	}
+
+	err = invoiceEntry.First(ctx, bson.M{"_id": regisEntry.InvoiceID})
+	if err != nil {
+		return nil, fmt.Errorf("không tìm thấy hóa đơn của đơn đăng ký: %w", err)
+	}
+
+	_, requestedAmount := buildRefundLineItems(invoiceEntry, tickets, refundPercent)
+
+	now := time.Now()
+	newRefund := &collections.Refund{
+		ID:               primitive.NewObjectID(),
+		RegistrationID:   regisEntry.ID,
+		EventID:          regisEntry.EventID,
+		InvoiceID:        invoiceEntry.ID,
+		TicketIDs:        refundTicketIDs,
+		Amount:           requestedAmount,
+		RefundPercent:    refundPercent,
+		Reason:           reason,
+		Status:           consts.RefundStatusRequested,
+		InitiatedByStaff: byStaff,
+		OwnerID:          regisEntry.CreatedBy,
+		CreatedAt:        now,
+		CreatedBy:        requesterID,
+		UpdatedAt:        now,
+		UpdatedBy:        requesterID,
+	}
+	if byStaff {
+		newRefund.Status = consts.RefundStatusProcessing
+		newRefund.ReviewedBy = requesterID
+		newRefund.ReviewedAt = &now
+	}
+
+	session, err := database.GetDB().Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		// Đánh dấu vé thuộc yêu cầu này; vé đang nằm trong yêu cầu khác chưa kết thúc thì không đánh dấu được
+		modified, err := ticketEntry.UpdateMany(sessionContext,
+			bson.M{
+				"_id":       bson.M{"$in": refundTicketIDs},
+				"status":    consts.TicketStatusConfirmed,
+				"refund_id": bson.M{"$exists": false},
+			},
+			bson.M{"$set": bson.M{"refund_id": newRefund.ID}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("lỗi hệ thống khi đánh dấu vé: %w", err)
+		}
+		if int(modified) != len(refundTicketIDs) {
+			return nil, consts.ErrRefundInProgress
+		}
+
+		// Đọc lại đơn trong transaction: các yêu cầu đồng thời cùng ghi vào đơn nên bên commit sau phải chạy lại với số liệu mới
+		lockedRegis := &collections.Registration{}
+		err = lockedRegis.First(sessionContext, bson.M{"_id": regisEntry.ID, "status": consts.RegistrationPaid})
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, consts.ErrRefundNotPaid
+			}
+			return nil, err
+		}
+		amount := requestedAmount
+		if remaining := lockedRegis.TotalPrice - lockedRegis.RefundedAmount - lockedRegis.RefundPendingAmount; amount > remaining {
+			amount = remaining
+		}
+		err = lockedRegis.Update(sessionContext, bson.M{"_id": regisEntry.ID}, bson.M{
+			"$inc": bson.M{"refund_pending_amount": amount},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("lỗi cập nhật đơn đăng ký: %w", err)
+		}
+
+		newRefund.Amount = amount
+		if err = newRefund.Create(sessionContext); err != nil {
+			return nil, fmt.Errorf("lỗi hệ thống khi tạo yêu cầu hoàn tiền: %w", err)
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if byStaff {
+		if err = processRefund(ctx, newRefund, requesterID); err != nil {
+			return newRefund, err
+		}
+	}
+	return newRefund, nil
+}
+
+// Ban tổ chức/admin duyệt yêu cầu hoàn tiền (hoặc thử lại yêu cầu bị lỗi cổng thanh toán)
+func ApproveRefund(ctx context.Context, refundEntry *collections.Refund, reviewerID primitive.ObjectID) error {
+	now := time.Now()
+	err := refundEntry.Update(ctx,
+		bson.M{
+			"_id":    refundEntry.ID,
+			"status": bson.M{"$in": []consts.RefundStatus{consts.RefundStatusRequested, consts.RefundStatusFailed}},
+		},
+		bson.M{"$set": bson.M{
+			"status":      consts.RefundStatusProcessing,
+			"reviewed_by": reviewerID,
+			"reviewed_at": now,
+			"updated_at":  now,
+			"updated_by":  reviewerID,
+		}},
+	)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return consts.ErrRefundInvalidState
+		}
+		return err
+	}
+
+	refundEntry.Status = consts.RefundStatusProcessing
+	refundEntry.ReviewedBy = reviewerID
+	refundEntry.ReviewedAt = &now
+	return processRefund(ctx, refundEntry, reviewerID)
+}
+
+// Ban tổ chức/admin từ chối yêu cầu hoàn tiền (hoặc đóng yêu cầu bị lỗi cổng thanh toán)
+func RejectRefund(ctx context.Context, refundEntry *collections.Refund, reviewerID primitive.ObjectID, rejectReason string) error {
+	now := time.Now()
+	// Yêu cầu bị lỗi cổng thanh toán cũng được đóng để trả vé và số tiền đang chờ hoàn
+	err := closeRefund(ctx, refundEntry, []consts.RefundStatus{consts.RefundStatusRequested, consts.RefundStatusFailed}, bson.M{
+		"status":        consts.RefundStatusRejected,
+		"reject_reason": rejectReason,
+		"reviewed_by":   reviewerID,
+		"reviewed_at":   now,
+		"updated_at":    now,
+		"updated_by":    reviewerID,
+	})
+	if err != nil {
+		return err
+	}
+
+	refundEntry.Status = consts.RefundStatusRejected
+	refundEntry.RejectReason = rejectReason
+	refundEntry.ReviewedBy = reviewerID
+	refundEntry.ReviewedAt = &now
+	return nil
+}
+
+// Giữ vé, gọi cổng thanh toán hoàn tiền, sau đó cập nhật vé, đơn đăng ký, kho vé và xuất hóa đơn điều chỉnh
+func processRefund(ctx context.Context, refundEntry *collections.Refund, actorID primitive.ObjectID) error {
+	var (
+		regisEntry   = &collections.Registration{}
+		invoiceEntry = &collections.Invoice{}
+		ticketEntry  = &collections.Ticket{}
+		accountEntry = &collections.Account{}
+	)
+
+	if err := regisEntry.First(ctx, bson.M{"_id": refundEntry.RegistrationID}); err != nil {
+		return fmt.Errorf("không tìm thấy đơn đăng ký: %w", err)
+	}
+	if err := invoiceEntry.First(ctx, bson.M{"_id": refundEntry.InvoiceID}); err != nil {
+		return fmt.Errorf("không tìm thấy hóa đơn: %w", err)
+	}
+	// Giữ vé trước khi gọi cổng thanh toán: vé đã check-in hoặc đổi trạng thái thì không hoàn tiền
+	tickets, err := claimRefundTickets(ctx, refundEntry, actorID)
+	if err != nil {
+		if errors.Is(err, consts.ErrRefundTicketsChanged) {
+			updateErr := closeRefund(ctx, refundEntry, []consts.RefundStatus{consts.RefundStatusProcessing}, bson.M{
+				"status":        consts.RefundStatusRejected,
+				"reject_reason": err.Error(),
+				"updated_at":    time.Now(),
+				"updated_by":    actorID,
+			})
+			if updateErr != nil {
+				log.Printf("ERROR: Không hủy được refund %s: %v", refundEntry.ID.Hex(), updateErr)
+			} else {
+				refundEntry.Status = consts.RefundStatusRejected
+				refundEntry.RejectReason = err.Error()
+			}
+		}
+		return err
+	}
+	claimedFilter := bson.M{"refund_id": refundEntry.ID, "status": consts.TicketStatusRefunding}
+
+	requestedBy := actorID.Hex()
+	if accountErr := accountEntry.First(bson.M{"_id": actorID}); accountErr == nil && accountEntry.Email != "" {
+		requestedBy = accountEntry.Email
+	}
+
+	// Hoàn tiền qua đúng cổng mà đơn đã thanh toán
+	provider, err := payment.Get(regisEntry.PaymentMethod)
+	if err != nil {
+		releaseRefundTickets(ctx, refundEntry, claimedFilter, actorID)
+		return fmt.Errorf("%w: %v", consts.ErrRefundProvider, err)
+	}
+	result, err := provider.Refund(ctx, payment.RefundRequest{
+		RefundID:        refundEntry.ID,
+		OrderID:         regisEntry.ID.Hex(),
+		TransactionCode: invoiceEntry.PaymentDetails.TransactionCode,
+		PaidAt:          invoiceEntry.PaymentDetails.PaidAt,
+		Amount:          int64(refundEntry.Amount),
+		FullRefund:      refundEntry.Amount == regisEntry.TotalPrice,
+		RequestedBy:     requestedBy,
+	})
+	if err != nil {
+		log.Printf("ERROR: Hoàn tiền %s qua %s thất bại: %v", refundEntry.ID.Hex(), provider.Name(), err)
+		releaseRefundTickets(ctx, refundEntry, claimedFilter, actorID)
+		updateErr := refundEntry.Update(ctx, bson.M{"_id": refundEntry.ID}, bson.M{"$set": bson.M{
+			"status":         consts.RefundStatusFailed,
+			"provider":       provider.Name(),
+			"failure_reason": err.Error(),
+			"updated_at":     time.Now(),
+			"updated_by":     actorID,
+		}})
+		if updateErr != nil {
+			log.Printf("CRITICAL: Không cập nhật được trạng thái lỗi cho refund %s: %v", refundEntry.ID.Hex(), updateErr)
+		}
+		refundEntry.Status = consts.RefundStatusFailed
+		refundEntry.FailureReason = err.Error()
+		return fmt.Errorf("%w: %v", consts.ErrRefundProvider, err)
+	}
+
+	lineItems, _ := buildRefundLineItems(invoiceEntry, tickets, refundEntry.RefundPercent)
+	now := time.Now()
+	creditNote := &collections.Invoice{
+		ID:                primitive.NewObjectID(),
+		RegistrationID:    regisEntry.ID,
+		Status:            consts.InvoiceStatusCompleted,
+		Type:              consts.InvoiceTypeCreditNote,
+		OriginalInvoiceID: invoiceEntry.ID,
+		RefundID:          refundEntry.ID,
+		TotalAmount:       refundEntry.Amount,
+		PaymentDetails: collections.InvoicePaymentDetails{
+			Method:          provider.Name(),
+			TransactionCode: result.ProviderRefundID,
+			PaidAt:          now,
+		},
+		CustomerDetails: invoiceEntry.CustomerDetails,
+		LineItems:       lineItems,
+		EventDetails:    invoiceEntry.EventDetails,
+		CreatedAt:       now,
+		CreatedBy:       actorID,
+		UpdatedAt:       now,
+		UpdatedBy:       actorID,
+	}
+
+	// Số vé hoàn theo từng loại để trả về kho
+	refundedByType := make(map[primitive.ObjectID]int)
+	for _, ticket := range tickets {
+		refundedByType[ticket.TicketTypeID]++
+	}
+
+	// Vé còn trống sau khi giữ cho danh sách chờ, trả lại bộ đếm tồn kho sau khi commit
+	var released map[primitive.ObjectID]int
+
+	session, err := database.GetDB().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		var (
+			ticketTypeEntry = collections.TicketType{}
+			eventEntry      = collections.Event{}
+		)
+
+		modified, err := ticketEntry.UpdateMany(sessionContext,
+			claimedFilter,
+			bson.M{"$set": bson.M{
+				"status":     consts.TicketStatusRefunded,
+				"updated_at": now,
+				"updated_by": actorID,
+			}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("lỗi cập nhật vé: %w", err)
+		}
+		if int(modified) != len(tickets) {
+			return nil, fmt.Errorf("chỉ %d/%d vé còn được giữ cho yêu cầu hoàn tiền", modified, len(tickets))
+		}
+
+		// Trả vé về kho
+		totalReturned := 0
+		released = make(map[primitive.ObjectID]int, len(refundedByType))
+		for ticketTypeID, quantity := range refundedByType {
+			err = ticketTypeEntry.Update(sessionContext,
+				bson.M{"_id": ticketTypeID},
+				bson.M{"$inc": bson.M{"registered_count": -quantity}},
+			)
+			if err != nil {
+				return nil, fmt.Errorf("lỗi trả vé về kho: %w", err)
+			}
+			totalReturned += quantity
+
+			// Vé vừa trả về kho được giữ ngay cho người đầu danh sách chờ
+			held, err := OfferWaitlist(sessionContext, ticketTypeID, now)
+			if err != nil {
+				return nil, fmt.Errorf("lỗi giữ vé cho danh sách chờ: %w", err)
+			}
+			released[ticketTypeID] = quantity - held
+		}
+		if totalReturned > 0 {
+			err = eventEntry.Update(sessionContext,
+				bson.M{"_id": regisEntry.EventID},
+				bson.M{"$inc": bson.M{"number_of_participants": -totalReturned}},
+			)
+			if err != nil {
+				return nil, fmt.Errorf("lỗi cập nhật số người tham gia: %w", err)
+			}
+		}
+
+		if err = CreateInvoice(sessionContext, creditNote); err != nil {
+			return nil, fmt.Errorf("lỗi tạo hóa đơn điều chỉnh: %w", err)
+		}
+
+		// Đơn chỉ chuyển sang REFUNDED khi không còn vé hợp lệ
+		remaining, err := ticketEntry.CountDocuments(sessionContext, bson.M{
+			"regis_id": regisEntry.ID,
+			"status":   bson.M{"$in": []consts.TicketStatus{consts.TicketStatusConfirmed, consts.TicketStatusCheckedIn}},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		regisSet := bson.M{"updated_at": now, "updated_by": actorID}
+		invoiceStatus := consts.InvoiceStatusPartiallyRefunded
+		if remaining == 0 {
+			regisSet["status"] = consts.RegistrationRefunded
+			regisSet["refunded_at"] = now
+			invoiceStatus = consts.InvoiceStatusRefunded
+		}
+		err = regisEntry.Update(sessionContext, bson.M{"_id": regisEntry.ID}, bson.M{
+			"$set": regisSet,
+			"$inc": bson.M{"refunded_amount": refundEntry.Amount, "refund_pending_amount": -refundEntry.Amount},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("lỗi cập nhật đơn đăng ký: %w", err)
+		}
+
+		err = invoiceEntry.Update(sessionContext, bson.M{"_id": invoiceEntry.ID}, bson.M{"$set": bson.M{
+			"status":     invoiceStatus,
+			"updated_at": now,
+			"updated_by": actorID,
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("lỗi cập nhật hóa đơn gốc: %w", err)
+		}
+
+		err = refundEntry.Update(sessionContext, bson.M{"_id": refundEntry.ID}, bson.M{"$set": bson.M{
+			"status":             consts.RefundStatusCompleted,
+			"provider":           provider.Name(),
+			"provider_refund_id": result.ProviderRefundID,
+			"credit_note_id":     creditNote.ID,
+			"completed_at":       now,
+			"updated_at":         now,
+			"updated_by":         actorID,
+		}})
+		if err != nil {
+			return nil, fmt.Errorf("lỗi cập nhật yêu cầu hoàn tiền: %w", err)
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		// Tiền đã được hoàn ở cổng thanh toán nhưng DB chưa cập nhật: lưu mã hoàn tiền và ghi sai lệch để admin đối soát
+		log.Printf("CRITICAL: Refund %s đã hoàn qua %s (mã %s) nhưng cập nhật DB thất bại: %v", refundEntry.ID.Hex(), provider.Name(), result.ProviderRefundID, err)
+		updateErr := refundEntry.Update(ctx, bson.M{"_id": refundEntry.ID}, bson.M{"$set": bson.M{
+			"provider":           provider.Name(),
+			"provider_refund_id": result.ProviderRefundID,
+			"failure_reason":     err.Error(),
+			"updated_at":         time.Now(),
+			"updated_by":         actorID,
+		}})
+		if updateErr != nil {
+			log.Printf("CRITICAL: Không lưu được mã hoàn tiền cho refund %s: %v", refundEntry.ID.Hex(), updateErr)
+		}
+		FlagPaymentDiscrepancy(ctx, regisEntry, provider.Name(), &payment.CallbackResult{
+			OrderID:       regisEntry.ID.Hex(),
+			Success:       true,
+			Amount:        int64(refundEntry.Amount),
+			TransactionNo: result.ProviderRefundID,
+		}, consts.DiscrepancyRefundPending)
+		return err
+	}
+	AdjustInventory(ctx, released)
+
+	refundEntry.Status = consts.RefundStatusCompleted
+	refundEntry.Provider = provider.Name()
+	refundEntry.ProviderRefundID = result.ProviderRefundID
+	refundEntry.CreditNoteID = creditNote.ID
+	refundEntry.CompletedAt = &now
+	return nil
+}
+
+// Chuyển toàn bộ vé của yêu cầu từ confirmed sang refunding trong một transaction.
+// Thiếu bất kỳ vé nào thì không giữ vé nào và trả về ErrRefundTicketsChanged.
+func claimRefundTickets(ctx context.Context, refundEntry *collections.Refund, actorID primitive.ObjectID) (collections.Tickets, error) {
+	var (
+		ticketEntry   = &collections.Ticket{}
+		claimedFilter = bson.M{
+			"_id":       bson.M{"$in": refundEntry.TicketIDs},
+			"refund_id": refundEntry.ID,
+			"status":    consts.TicketStatusRefunding,
+		}
+	)
+
+	session, err := database.GetDB().Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		now := time.Now()
+		_, err := ticketEntry.UpdateMany(sessionContext,
+			bson.M{"_id": bson.M{"$in": refundEntry.TicketIDs}, "refund_id": refundEntry.ID, "status": consts.TicketStatusConfirmed},
+			bson.M{"$set": bson.M{
+				"status":     consts.TicketStatusRefunding,
+				"updated_at": now,
+				"updated_by": actorID,
+			}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("lỗi giữ vé để hoàn tiền: %w", err)
+		}
+
+		// Tính cả vé đã giữ ở lần xử lý trước bị gián đoạn
+		claimed, err := ticketEntry.CountDocuments(sessionContext, claimedFilter)
+		if err != nil {
+			return nil, err
+		}
+		if int(claimed) != len(refundEntry.TicketIDs) {
+			return nil, consts.ErrRefundTicketsChanged
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tickets, err := ticketEntry.Find(ctx, claimedFilter)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi hệ thống khi tìm vé: %w", err)
+	}
+	return tickets, nil
+}
+
+// Kết thúc yêu cầu chưa hoàn tiền (từ chối/hủy): bỏ đánh dấu vé và trả lại số tiền đang chờ hoàn của đơn
+func closeRefund(ctx context.Context, refundEntry *collections.Refund, fromStatuses []consts.RefundStatus, set bson.M) error {
+	var (
+		ticketEntry = &collections.Ticket{}
+		regisEntry  = &collections.Registration{}
+	)
+
+	session, err := database.GetDB().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		err := refundEntry.Update(sessionContext, bson.M{"_id": refundEntry.ID, "status": bson.M{"$in": fromStatuses}}, bson.M{"$set": set})
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, consts.ErrRefundInvalidState
+			}
+			return nil, err
+		}
+
+		_, err = ticketEntry.UpdateMany(sessionContext,
+			bson.M{"refund_id": refundEntry.ID, "status": bson.M{"$ne": consts.TicketStatusRefunded}},
+			bson.M{"$unset": bson.M{"refund_id": ""}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("lỗi bỏ đánh dấu vé: %w", err)
+		}
+
+		err = regisEntry.Update(sessionContext, bson.M{"_id": refundEntry.RegistrationID}, bson.M{
+			"$inc": bson.M{"refund_pending_amount": -refundEntry.Amount},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("lỗi cập nhật đơn đăng ký: %w", err)
+		}
+		return nil, nil
+	})
+	return err
+}
+
+// Trả vé đang giữ về confirmed khi cổng thanh toán không hoàn được tiền
+func releaseRefundTickets(ctx context.Context, refundEntry *collections.Refund, claimedFilter bson.M, actorID primitive.ObjectID) {
+	ticketEntry := &collections.Ticket{}
+	_, err := ticketEntry.UpdateMany(ctx, claimedFilter, bson.M{"$set": bson.M{
+		"status":     consts.TicketStatusConfirmed,
+		"updated_at": time.Now(),
+		"updated_by": actorID,
+	}})
+	if err != nil {
+		log.Printf("CRITICAL: Không trả lại được vé của refund %s: %v", refundEntry.ID.Hex(), err)
+	}
+}
+
+// Dòng hàng của hóa đơn điều chỉnh: đơn giá lấy từ hóa đơn gốc, nhân với phần trăm được hoàn
+func buildRefundLineItems(invoiceEntry *collections.Invoice, tickets collections.Tickets, refundPercent int) ([]collections.InvoiceLstItem, int) {
+	unitPrices := make(map[primitive.ObjectID]collections.InvoiceLstItem)
+	for _, item := range invoiceEntry.LineItems {
+		unitPrices[item.ItemID] = item
+	}
+
+	quantities := make(map[primitive.ObjectID]int)
+	var order []primitive.ObjectID
+	for _, ticket := range tickets {
+		if _, ok := quantities[ticket.TicketTypeID]; !ok {
+			order = append(order, ticket.TicketTypeID)
+		}
+		quantities[ticket.TicketTypeID]++
+	}
+
+	var (
+		lineItems []collections.InvoiceLstItem
+		total     int
+	)
+	for _, ticketTypeID := range order {
+		original := unitPrices[ticketTypeID]
+		// Vé mua bằng mã giảm giá chỉ hoàn trên số tiền thực trả
+		paidUnitPrice := original.UnitPrice
+		if original.DiscountAmount > 0 && original.Quantity > 0 {
+			paidUnitPrice = (original.TotalAmount - original.DiscountAmount) / original.Quantity
+		}
+		unitPrice := paidUnitPrice * refundPercent / 100
+		item := collections.InvoiceLstItem{
+			ItemID:      ticketTypeID,
+			Description: fmt.Sprintf("Hoàn tiền %d%% - %s", refundPercent, original.Description),
+			Quantity:    quantities[ticketTypeID],
+			UnitPrice:   unitPrice,
+			TotalAmount: unitPrice * quantities[ticketTypeID],
+			VATRate:     original.VATRate,
+		}
+		lineItems = append(lineItems, item)
+		total += item.TotalAmount
+	}
+	return lineItems, total
+}
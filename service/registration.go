@@ -0,0 +1,280 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/consts"
+	"EventHunting/database"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Trạng thái thanh toán hiển thị cho người mua
+const (
+	PaymentStateAwaiting = "awaiting_payment"
+	PaymentStatePaid     = "paid"
+	PaymentStateVoided   = "voided"
+	PaymentStateRefunded = "refunded"
+)
+
+// Tóm tắt đơn đăng ký cho trang "vé của tôi": sự kiện, thanh toán, hóa đơn và vé đã phát hành
+func BuildRegistrationSummaries(ctx context.Context, registrations collections.Registrations) ([]bson.M, error) {
+	var (
+		eventEntry      = &collections.Event{}
+		ticketEntry     = &collections.Ticket{}
+		ticketTypeEntry = &collections.TicketType{}
+		invoiceEntry    = &collections.Invoice{}
+	)
+
+	results := make([]bson.M, 0, len(registrations))
+	if len(registrations) == 0 {
+		return results, nil
+	}
+
+	regisIDs := make([]primitive.ObjectID, 0, len(registrations))
+	regisMap := make(map[primitive.ObjectID]collections.Registration, len(registrations))
+	eventIDSet := make(map[primitive.ObjectID]struct{})
+	for _, regis := range registrations {
+		regisIDs = append(regisIDs, regis.ID)
+		regisMap[regis.ID] = regis
+		eventIDSet[regis.EventID] = struct{}{}
+	}
+	eventIDs := make([]primitive.ObjectID, 0, len(eventIDSet))
+	for id := range eventIDSet {
+		eventIDs = append(eventIDs, id)
+	}
+
+	events, err := eventEntry.Find(ctx, bson.M{"_id": bson.M{"$in": eventIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi tìm sự kiện: %w", err)
+	}
+	eventMap := make(map[primitive.ObjectID]collections.Event, len(events))
+	for _, event := range events {
+		eventMap[event.ID] = event
+	}
+
+	ticketTypes, err := ticketTypeEntry.Find(ctx, bson.M{"event_id": bson.M{"$in": eventIDs}})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi tìm loại vé: %w", err)
+	}
+	ticketTypeMap := make(map[primitive.ObjectID]collections.TicketType, len(ticketTypes))
+	for _, ticketType := range ticketTypes {
+		ticketTypeMap[ticketType.ID] = ticketType
+	}
+
+	tickets, err := ticketEntry.Find(ctx, bson.M{"regis_id": bson.M{"$in": regisIDs}}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi tìm vé: %w", err)
+	}
+	ticketMap := make(map[primitive.ObjectID][]bson.M)
+	for _, ticket := range tickets {
+		ticketMap[ticket.RegisID] = append(ticketMap[ticket.RegisID], BuildTicketSummary(ticket, ticketTypeMap, regisMap))
+	}
+
+	// Chỉ lấy hóa đơn gốc, hóa đơn điều chỉnh (hoàn tiền) cùng registration_id không được ghi đè
+	invoices, err := invoiceEntry.Find(ctx, bson.M{
+		"registration_id": bson.M{"$in": regisIDs},
+		"type":            bson.M{"$ne": consts.InvoiceTypeCreditNote},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi tìm hóa đơn: %w", err)
+	}
+	invoiceMap := make(map[primitive.ObjectID]collections.Invoice, len(invoices))
+	for _, invoice := range invoices {
+		invoiceMap[invoice.RegistrationID] = invoice
+	}
+
+	for _, regis := range registrations {
+		item := bson.M{
+			"id":             regis.ID,
+			"status":         regis.Status,
+			"total_quantity": regis.TotalQuantity,
+			"total_price":    regis.TotalPrice,
+			"created_at":     regis.CreatedAt,
+		}
+		if regis.Discount != nil {
+			item["subtotal_price"] = regis.SubtotalPrice
+			item["discount"] = bson.M{
+				"code":   regis.Discount.Code,
+				"amount": regis.Discount.Amount,
+			}
+		}
+
+		if event, ok := eventMap[regis.EventID]; ok {
+			item["event"] = bson.M{
+				"id":             event.ID,
+				"name":           event.Name,
+				"thumbnail_url":  event.ThumbnailUrl,
+				"event_time":     event.EventTime,
+				"event_location": event.EventLocation,
+			}
+		}
+
+		lines := make([]bson.M, 0, len(regis.Tickets))
+		for _, line := range regis.Tickets {
+			ticketType := ticketTypeMap[line.TicketTypeID]
+			lines = append(lines, bson.M{
+				"ticket_type_id": line.TicketTypeID,
+				"name":           ticketType.Name,
+				"price":          line.GetUnitPrice(ticketType),
+				"price_tier":     line.PriceTier,
+				"quantity":       line.Quantity,
+			})
+		}
+		item["items"] = lines
+
+		payment := bson.M{
+			"state":            registrationPaymentState(regis),
+			"method":           regis.PaymentMethod,
+			"transaction_code": regis.PaymentTransactionCode,
+			"paid_at":          regis.PaidAt,
+			"refunded_amount":  regis.RefundedAmount,
+		}
+		if invoice, ok := invoiceMap[regis.ID]; ok {
+			payment["invoice"] = bson.M{
+				"id":             invoice.ID,
+				"invoice_number": invoice.InvoiceNumber,
+				"status":         invoice.Status,
+				"method":         invoice.PaymentDetails.Method,
+			}
+		}
+		item["payment"] = payment
+
+		if issued, ok := ticketMap[regis.ID]; ok {
+			item["tickets"] = issued
+		} else {
+			item["tickets"] = []bson.M{}
+		}
+
+		results = append(results, item)
+	}
+
+	return results, nil
+}
+
+// Thông tin vé rút gọn (không kèm mã QR, ảnh QR lấy qua endpoint riêng).
+// Giá vé là giá đã chốt trên đơn đăng ký, không phải giá hiện tại của loại vé.
+func BuildTicketSummary(ticket collections.Ticket, ticketTypeMap map[primitive.ObjectID]collections.TicketType, regisMap map[primitive.ObjectID]collections.Registration) bson.M {
+	ticketType := ticketTypeMap[ticket.TicketTypeID]
+	price := ticketType.Price
+	for _, line := range regisMap[ticket.RegisID].Tickets {
+		if line.TicketTypeID == ticket.TicketTypeID {
+			price = line.GetUnitPrice(ticketType)
+			break
+		}
+	}
+	return bson.M{
+		"id":             ticket.ID,
+		"event_id":       ticket.EventID,
+		"regis_id":       ticket.RegisID,
+		"ticket_type_id": ticket.TicketTypeID,
+		"ticket_type":    ticketType.Name,
+		"price":          price,
+		"status":         ticket.Status,
+		"checked_in_at":  ticket.CheckedInAt,
+		"created_at":     ticket.CreatedAt,
+	}
+}
+
+func registrationPaymentState(regis collections.Registration) string {
+	switch {
+	case regis.Status == consts.RegistrationRefunded:
+		return PaymentStateRefunded
+	case regis.Status == consts.RegistrationPaid || regis.PaidAt != nil:
+		return PaymentStatePaid
+	case regis.Status == consts.RegistrationCancelled:
+		return PaymentStateVoided
+	default:
+		return PaymentStateAwaiting
+	}
+}
+
+// Hủy đơn đăng ký đang chờ thanh toán: trả vé về kho và vô hiệu hóa link thanh toán (IPN đến sau sẽ bị từ chối)
+func CancelPendingRegistration(ctx context.Context, reg collections.Registration, cancelledBy primitive.ObjectID, reason string) error {
+	var (
+		db              = database.GetDB()
+		err             error
+		regisEntry      = collections.Registration{}
+		ticketTypeEntry = collections.TicketType{}
+		eventEntry      = collections.Event{}
+	)
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	result, err := session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+		now := time.Now()
+		released := make(map[primitive.ObjectID]int, len(reg.Tickets))
+
+		regFilter := bson.M{"_id": reg.ID, "status": consts.RegistrationPending}
+		regSet := bson.M{
+			"status":            consts.RegistrationCancelled,
+			"cancelled_at":      now,
+			"cancel_reason":     reason,
+			"payment_voided_at": now,
+			"updated_at":        now,
+		}
+		if !cancelledBy.IsZero() {
+			regSet["updated_by"] = cancelledBy
+		}
+
+		err := regisEntry.Update(sessionContext, regFilter, bson.M{"$set": regSet})
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil, consts.ErrRegistrationNotPending
+			}
+			return nil, err
+		}
+
+		// Trả vé về kho
+		for _, ticket := range reg.Tickets {
+			err = ticketTypeEntry.Update(sessionContext,
+				bson.M{"_id": ticket.TicketTypeID},
+				bson.M{"$inc": bson.M{"registered_count": -ticket.Quantity}},
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to return TicketType stock: %v", err)
+			}
+			released[ticket.TicketTypeID] += ticket.Quantity
+		}
+
+		//Giảm số người tham gia
+		err = eventEntry.Update(sessionContext,
+			bson.M{"_id": reg.EventID},
+			bson.M{"$inc": bson.M{"number_of_participants": -reg.TotalQuantity}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to return Event participants: %v", err)
+		}
+
+		if err = ReleasePromoCode(sessionContext, reg); err != nil {
+			return nil, fmt.Errorf("failed to release promo code: %v", err)
+		}
+
+		// Vé vừa trả về kho được giữ ngay cho người đầu danh sách chờ
+		for ticketTypeID := range released {
+			held, err := OfferWaitlist(sessionContext, ticketTypeID, now)
+			if err != nil {
+				return nil, fmt.Errorf("failed to offer waitlist: %v", err)
+			}
+			released[ticketTypeID] -= held
+		}
+
+		return released, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Vé còn trống sau khi giữ cho danh sách chờ được trả lại bộ đếm tồn kho
+	AdjustInventory(ctx, result.(map[primitive.ObjectID]int))
+	return nil
+}
@@ -12,7 +12,6 @@ import (
 	"log"
 	"time"
 
-	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -70,11 +69,17 @@ func ProcessTicketAndEmail(regisID primitive.ObjectID) error {
 		return err
 	}
 
-	// Lấy thông tin loại vé
+	// Lấy thông tin loại vé, giá hiển thị trên vé là giá đã chốt trên đơn
 	ticketTypeMap, err := fetchTicketTypes(regisEntry)
 	if err != nil {
 		return err
 	}
+	for _, line := range regisEntry.Tickets {
+		if ticketType, ok := ticketTypeMap[line.TicketTypeID]; ok {
+			ticketType.Price = line.GetUnitPrice(ticketType)
+			ticketTypeMap[line.TicketTypeID] = ticketType
+		}
+	}
 
 	// Sinh vé hoặc Lấy vé đã có (Quan trọng: Transaction)
 	newTickets, err := getOrCreateTickets(regisEntry, eventEntry)
@@ -95,6 +100,7 @@ func ProcessTicketAndEmail(regisID primitive.ObjectID) error {
 		To:             []string{accountEntry.Email},
 		HTMLBody:       htmlBody,
 		EmbeddedImages: embeddedFiles,
+		Attachments:    buildInvoiceAttachment(regisEntry),
 	}
 
 	if err := emailService.SendEmail(payload); err != nil {
@@ -116,6 +122,25 @@ func ProcessTicketAndEmail(regisID primitive.ObjectID) error {
 	return nil
 }
 
+// Đính kèm hóa đơn PDF vào email vé. Lỗi tạo PDF chỉ log, vé vẫn được gửi (người mua tải lại hóa đơn qua API).
+func buildInvoiceAttachment(regisEntry *collections.Registration) map[string][]byte {
+	if regisEntry.InvoiceID.IsZero() {
+		return nil
+	}
+
+	invoiceEntry := &collections.Invoice{}
+	if err := invoiceEntry.First(nil, bson.M{"_id": regisEntry.InvoiceID}); err != nil {
+		log.Printf("ERROR: Không tìm thấy hóa đơn %s của đơn %s để đính kèm: %v", regisEntry.InvoiceID.Hex(), regisEntry.ID.Hex(), err)
+		return nil
+	}
+	pdfData, err := RenderInvoicePDF(nil, invoiceEntry)
+	if err != nil {
+		log.Printf("ERROR: Không tạo được PDF hóa đơn %s: %v", invoiceEntry.InvoiceNumber, err)
+		return nil
+	}
+	return map[string][]byte{InvoicePDFFilename(invoiceEntry): pdfData}
+}
+
 func fetchTicketTypes(regisEntry *collections.Registration) (map[primitive.ObjectID]collections.TicketType, error) {
 	var ticketTypeIDS []primitive.ObjectID
 	for _, ticket := range regisEntry.Tickets {
@@ -173,22 +198,29 @@ func getOrCreateTickets(regisEntry *collections.Registration, eventEntry *collec
 
 		for _, ticket := range regisEntry.Tickets {
 			for i := 0; i < ticket.Quantity; i++ {
-				newUUID, err := uuid.NewUUID()
+				ticketID := primitive.NewObjectID()
+				qrCodeData, err := utils.SignTicketToken(ticketID, eventEntry.ID, ticket.TicketTypeID)
 				if err != nil {
-					return nil, fmt.Errorf("lỗi tạo UUID: %w", err)
+					return nil, fmt.Errorf("lỗi ký mã QR: %w", err)
 				}
 
 				createdTicket := collections.Ticket{
+					ID:         ticketID,
 					Status:     consts.TicketStatusConfirmed,
-					QRCodeData: "TICKET-" + newUUID.String(),
+					QRCodeData: qrCodeData,
 					//InvoiceID:    *regisEntry.InvoiceID,
 					TicketTypeID: ticket.TicketTypeID,
 					EventID:      eventEntry.ID,
+					RegisID:      regisEntry.ID,
 					CreatedAt:    creationTime,
 					CreatedBy:    regisEntry.CreatedBy,
 					UpdatedAt:    creationTime,
 					UpdatedBy:    regisEntry.CreatedBy,
 				}
+				if i < len(ticket.Attendees) {
+					attendee := ticket.Attendees[i]
+					createdTicket.Attendee = &attendee
+				}
 				newTickets = append(newTickets, createdTicket)
 			}
 		}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Hàng chờ FIFO theo sự kiện trên Redis:
+//   - seq: số thứ tự cấp cho người vào hàng
+//   - queue: ZSET account_id -> số thứ tự
+//   - admitted: số thứ tự lớn nhất đã được vào, tăng theo admit_per_minute sau mỗi tick
+//   - issued: HASH account_id -> thời điểm tới lượt, vé vào cửa hết hạn sau token_ttl_seconds kể từ đó
+type waitingRoomKeys struct {
+	seq, queue, admitted, issued, tick string
+}
+
+func newWaitingRoomKeys(eventID primitive.ObjectID) waitingRoomKeys {
+	prefix := "waiting_room:" + eventID.Hex() + ":"
+	return waitingRoomKeys{
+		seq:      prefix + "seq",
+		queue:    prefix + "queue",
+		admitted: prefix + "admitted",
+		issued:   prefix + "issued",
+		tick:     prefix + "tick",
+	}
+}
+
+// Vào hàng (giữ nguyên số thứ tự nếu đã có) và gia hạn các key của hàng chờ
+var joinWaitingRoomScript = redis.NewScript(`
+local seq = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not seq then
+	seq = redis.call('INCR', KEYS[2])
+	redis.call('ZADD', KEYS[1], seq, ARGV[1])
+end
+for _, key in ipairs(KEYS) do
+	redis.call('EXPIRE', key, ARGV[2])
+end
+return tonumber(seq)
+`)
+
+// Cho thêm tối đa ARGV[1] người vào, không vượt quá người cuối hàng.
+// Khóa tick đảm bảo nhiều instance chạy cron cùng lúc chỉ tính một lần.
+var advanceWaitingRoomScript = redis.NewScript(`
+if not redis.call('SET', KEYS[3], '1', 'NX', 'EX', ARGV[2]) then
+	return 0
+end
+local last = tonumber(redis.call('GET', KEYS[1]) or '0')
+if last == 0 then
+	return 0
+end
+local admitted = tonumber(redis.call('GET', KEYS[2]) or '0')
+local target = math.min(admitted + tonumber(ARGV[1]), last)
+redis.call('SET', KEYS[2], target, 'EX', ARGV[3])
+return target - admitted
+`)
+
+// Trạng thái của người dùng trong phòng chờ, client gọi lại định kỳ tới khi nhận được vé vào cửa
+type WaitingRoomStatus struct {
+	Admitted       bool       `json:"admitted"`
+	Position       int        `json:"position,omitempty"`    // Số người phía trước tính cả bản thân
+	EtaSeconds     int        `json:"eta_seconds,omitempty"` // Thời gian chờ ước tính
+	AdmissionToken string     `json:"admission_token,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	PollAfter      int        `json:"poll_after_seconds,omitempty"` // Gợi ý thời gian gọi lại khi chưa tới lượt
+}
+
+// Số người được vào sau mỗi tick
+func waitingRoomAdmitPerTick(room *collections.EventWaitingRoom) int {
+	perTick := (room.AdmitPerMinute*configs.GetWaitingRoomTickSeconds() + 59) / 60
+	if perTick < 1 {
+		return 1
+	}
+	return perTick
+}
+
+func JoinWaitingRoom(ctx context.Context, event *collections.Event, accountID primitive.ObjectID, now time.Time) (*WaitingRoomStatus, error) {
+	if !event.WaitingRoomEnabled() {
+		return nil, consts.ErrWaitingRoomDisabled
+	}
+	rdb, err := getRedisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := newWaitingRoomKeys(event.ID)
+	ttl := int(time.Duration(configs.GetWaitingRoomQueueTTLHours()) * time.Hour / time.Second)
+	err = joinWaitingRoomScript.Run(ctx, rdb,
+		[]string{keys.queue, keys.seq},
+		accountID.Hex(), ttl,
+	).Err()
+	if err != nil {
+		return nil, fmt.Errorf("lỗi vào hàng chờ: %w", err)
+	}
+
+	return GetWaitingRoomStatus(ctx, event, accountID, now)
+}
+
+// Vị trí và thời gian chờ ước tính, tới lượt thì cấp vé vào cửa.
+// Hết hạn vé vào cửa thì bị đưa ra khỏi hàng để nhường chỗ, muốn mua tiếp phải xếp hàng lại.
+func GetWaitingRoomStatus(ctx context.Context, event *collections.Event, accountID primitive.ObjectID, now time.Time) (*WaitingRoomStatus, error) {
+	if !event.WaitingRoomEnabled() {
+		return nil, consts.ErrWaitingRoomDisabled
+	}
+	rdb, err := getRedisClient()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := newWaitingRoomKeys(event.ID)
+	member := accountID.Hex()
+	tickSeconds := configs.GetWaitingRoomTickSeconds()
+
+	score, err := rdb.ZScore(ctx, keys.queue, member).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, consts.ErrWaitingRoomNotJoined
+	}
+	if err != nil {
+		return nil, err
+	}
+	seq := int(score)
+
+	admitted, err := rdb.Get(ctx, keys.admitted).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	if seq > admitted {
+		position := seq - admitted
+		perTick := waitingRoomAdmitPerTick(event.WaitingRoom)
+		return &WaitingRoomStatus{
+			Position:   position,
+			EtaSeconds: (position + perTick - 1) / perTick * tickSeconds,
+			PollAfter:  tickSeconds,
+		}, nil
+	}
+
+	// Tới lượt: mốc tính hạn vé vào cửa chỉ ghi lần đầu
+	pipe := rdb.TxPipeline()
+	pipe.HSetNX(ctx, keys.issued, member, now.Unix())
+	pipe.Expire(ctx, keys.issued, time.Duration(configs.GetWaitingRoomQueueTTLHours())*time.Hour)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	issuedUnix, err := rdb.HGet(ctx, keys.issued, member).Int64()
+	if err != nil {
+		return nil, err
+	}
+	issuedAt := time.Unix(issuedUnix, 0)
+	expiresAt := issuedAt.Add(time.Duration(configs.GetWaitingRoomTokenTTLSeconds()) * time.Second)
+	if !now.Before(expiresAt) {
+		pipe = rdb.TxPipeline()
+		pipe.ZRem(ctx, keys.queue, member)
+		pipe.HDel(ctx, keys.issued, member)
+		if _, err = pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+		return nil, consts.ErrWaitingRoomExpired
+	}
+
+	token, err := utils.SignAdmissionToken(event.ID, accountID, issuedAt, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &WaitingRoomStatus{
+		Admitted:       true,
+		AdmissionToken: token,
+		ExpiresAt:      &expiresAt,
+	}, nil
+}
+
+// Cho người trong hàng chờ vào theo tốc độ của từng sự kiện, chạy mỗi tick_seconds
+func AdvanceWaitingRooms(ctx context.Context) (int, error) {
+	eventEntry := &collections.Event{}
+
+	rdb, err := getRedisClient()
+	if err != nil {
+		return 0, err
+	}
+
+	events, err := eventEntry.Find(ctx, utils.GetFilter(bson.M{"waiting_room.enabled": true}))
+	if err != nil {
+		return 0, err
+	}
+
+	tickSeconds := configs.GetWaitingRoomTickSeconds()
+	lockSeconds := tickSeconds - 1
+	if lockSeconds < 1 {
+		lockSeconds = 1
+	}
+	ttl := int(time.Duration(configs.GetWaitingRoomQueueTTLHours()) * time.Hour / time.Second)
+
+	total := 0
+	for _, event := range events {
+		keys := newWaitingRoomKeys(event.ID)
+		admitted, err := advanceWaitingRoomScript.Run(ctx, rdb,
+			[]string{keys.seq, keys.admitted, keys.tick},
+			waitingRoomAdmitPerTick(event.WaitingRoom), lockSeconds, ttl,
+		).Int()
+		if err != nil {
+			log.Printf("ERROR: Cập nhật phòng chờ sự kiện %s thất bại: %v", event.ID.Hex(), err)
+			continue
+		}
+		total += admitted
+	}
+	return total, nil
+}
+
+// Sự kiện bật phòng chờ thì yêu cầu đăng ký phải kèm vé vào cửa hợp lệ
+func CheckAdmission(ctx context.Context, eventID, accountID primitive.ObjectID, token string) error {
+	eventEntry := &collections.Event{}
+
+	err := eventEntry.First(ctx, bson.M{"_id": eventID})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Để controller trả lỗi không tìm thấy sự kiện
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !eventEntry.WaitingRoomEnabled() {
+		return nil
+	}
+
+	if token == "" {
+		return consts.ErrAdmissionTokenRequired
+	}
+	if err = utils.VerifyAdmissionToken(token, eventID, accountID); err != nil {
+		return fmt.Errorf("%w: %v", consts.ErrAdmissionTokenInvalid, err)
+	}
+	return nil
+}
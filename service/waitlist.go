@@ -0,0 +1,397 @@
+package service
+
+import (
+	"EventHunting/collections"
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/database"
+	"EventHunting/utils"
+	"EventHunting/view"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var activeWaitlistStatuses = []consts.WaitlistStatus{consts.WaitlistWaiting, consts.WaitlistOffered}
+
+// Tham gia danh sách chờ của loại vé không còn đủ số lượng. Mỗi tài khoản chỉ có một lượt chờ còn hiệu lực cho mỗi loại vé.
+func JoinWaitlist(ctx context.Context, ticketType collections.TicketType, accountID primitive.ObjectID, quantity int, now time.Time) (*collections.WaitlistEntry, error) {
+	var (
+		waitlistEntry = &collections.WaitlistEntry{}
+	)
+
+	if ticketType.Quantity == nil {
+		return nil, consts.ErrWaitlistUnlimited
+	}
+
+	// Còn vé và không ai đang chờ thì đăng ký trực tiếp
+	queued, err := waitlistEntry.CountDocuments(ctx, bson.M{
+		"ticket_type_id": ticketType.ID,
+		"status":         bson.M{"$in": activeWaitlistStatuses},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if queued == 0 && *ticketType.Quantity-ticketType.RegisteredCount >= quantity {
+		return nil, consts.ErrWaitlistTicketAvailable
+	}
+
+	err = waitlistEntry.First(ctx, bson.M{
+		"ticket_type_id": ticketType.ID,
+		"account_id":     accountID,
+		"status":         bson.M{"$in": activeWaitlistStatuses},
+	})
+	if err == nil {
+		return nil, consts.ErrWaitlistAlreadyJoined
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	newEntry := &collections.WaitlistEntry{
+		ID:           primitive.NewObjectID(),
+		EventID:      ticketType.EventID,
+		TicketTypeID: ticketType.ID,
+		AccountID:    accountID,
+		Quantity:     quantity,
+		Status:       consts.WaitlistWaiting,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err = newEntry.Create(ctx); err != nil {
+		return nil, err
+	}
+	return newEntry, nil
+}
+
+// Vị trí trong hàng chờ (bắt đầu từ 1), 0 nếu lượt chờ không còn xếp hàng
+func WaitlistPosition(ctx context.Context, entry collections.WaitlistEntry) (int, error) {
+	if entry.Status != consts.WaitlistWaiting {
+		return 0, nil
+	}
+	waitlistEntry := &collections.WaitlistEntry{}
+	ahead, err := waitlistEntry.CountDocuments(ctx, bson.M{
+		"ticket_type_id": entry.TicketTypeID,
+		"status":         consts.WaitlistWaiting,
+		"$or": bson.A{
+			bson.M{"created_at": bson.M{"$lt": entry.CreatedAt}},
+			bson.M{"created_at": entry.CreatedAt, "_id": bson.M{"$lt": entry.ID}},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(ahead) + 1, nil
+}
+
+// Giữ vé trống cho những người đầu hàng chờ và ghi email mời vào outbox, trả về tổng số vé đã giữ.
+// Phải gọi bằng session context của transaction vừa trả vé về kho để suất trống không bị người khác mua trước.
+func OfferWaitlist(ctx context.Context, ticketTypeID primitive.ObjectID, now time.Time) (int, error) {
+	held := 0
+	for {
+		var (
+			ticketTypeEntry = collections.TicketType{}
+			waitlistEntry   = collections.WaitlistEntry{}
+		)
+
+		err := ticketTypeEntry.First(ctx, bson.M{"_id": ticketTypeID})
+		if err != nil {
+			return held, fmt.Errorf("lỗi tìm loại vé: %w", err)
+		}
+		if ticketTypeEntry.Quantity == nil ||
+			ticketTypeEntry.Status != consts.TicketTypeActive ||
+			ticketTypeEntry.SaleStatus(now) == consts.TicketSaleEnded {
+			return held, nil
+		}
+		available := *ticketTypeEntry.Quantity - ticketTypeEntry.RegisteredCount
+		if available <= 0 {
+			return held, nil
+		}
+
+		err = waitlistEntry.First(ctx,
+			bson.M{"ticket_type_id": ticketTypeID, "status": consts.WaitlistWaiting},
+			options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}),
+		)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return held, nil
+		}
+		if err != nil {
+			return held, fmt.Errorf("lỗi tìm danh sách chờ: %w", err)
+		}
+
+		// Vé trống ít hơn số vé muốn mua thì giữ phần còn lại
+		quantity := waitlistEntry.Quantity
+		if quantity > available {
+			quantity = available
+		}
+
+		err = ticketTypeEntry.Update(ctx,
+			bson.M{"_id": ticketTypeID, "registered_count": bson.M{"$lte": *ticketTypeEntry.Quantity - quantity}},
+			bson.M{"$inc": bson.M{"registered_count": quantity}},
+		)
+		if err != nil {
+			return held, fmt.Errorf("lỗi giữ vé cho danh sách chờ: %w", err)
+		}
+
+		expiresAt := now.Add(time.Duration(configs.GetWaitlistOfferMinutes()) * time.Minute)
+		err = waitlistEntry.Update(ctx,
+			bson.M{"_id": waitlistEntry.ID, "status": consts.WaitlistWaiting},
+			bson.M{"$set": bson.M{
+				"status":           consts.WaitlistOffered,
+				"offered_quantity": quantity,
+				"offered_at":       now,
+				"offer_expires_at": expiresAt,
+				"updated_at":       now,
+			}},
+		)
+		if err != nil {
+			return held, fmt.Errorf("lỗi cập nhật danh sách chờ: %w", err)
+		}
+
+		if err = CreateWaitlistOfferOutbox(ctx, waitlistEntry.ID); err != nil {
+			return held, fmt.Errorf("lỗi ghi email mời giữ vé: %w", err)
+		}
+		held += quantity
+	}
+}
+
+// Trả suất đang giữ về kho và chuyển cho người kế tiếp. Gọi trong transaction.
+// Trả về số vé thực sự còn trống sau khi đã giữ cho người kế tiếp.
+func releaseWaitlistOffer(ctx context.Context, entry collections.WaitlistEntry, status consts.WaitlistStatus, now time.Time) (int, error) {
+	var (
+		waitlistEntry   = collections.WaitlistEntry{}
+		ticketTypeEntry = collections.TicketType{}
+	)
+
+	err := waitlistEntry.Update(ctx,
+		bson.M{"_id": entry.ID, "status": consts.WaitlistOffered},
+		bson.M{"$set": bson.M{"status": status, "updated_at": now}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, consts.ErrWaitlistInvalidState
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	err = ticketTypeEntry.Update(ctx,
+		bson.M{"_id": entry.TicketTypeID},
+		bson.M{"$inc": bson.M{"registered_count": -entry.OfferedQuantity}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("lỗi trả vé giữ về kho: %w", err)
+	}
+
+	held, err := OfferWaitlist(ctx, entry.TicketTypeID, now)
+	if err != nil {
+		return 0, err
+	}
+	return entry.OfferedQuantity - held, nil
+}
+
+// Quét các loại vé có người chờ: hết hạn giữ vé thì chuyển cho người kế tiếp, có vé trống (vd: ban tổ chức tăng số lượng) thì mời tiếp.
+// Mỗi loại vé xử lý trong một transaction riêng. Trả về số lượt hết hạn và số vé được giữ mới.
+func ProcessWaitlists(ctx context.Context) (int, int, error) {
+	var (
+		waitlistEntry = &collections.WaitlistEntry{}
+		expiredCount  = 0
+		heldCount     = 0
+	)
+
+	now := time.Now()
+	ticketTypeIDs, err := waitlistEntry.DistinctTicketTypeIDs(ctx, bson.M{
+		"$or": bson.A{
+			bson.M{"status": consts.WaitlistWaiting},
+			bson.M{"status": consts.WaitlistOffered, "offer_expires_at": bson.M{"$lte": now}},
+		},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	session, err := database.GetDB().Client().StartSession()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer session.EndSession(ctx)
+
+	for _, ticketTypeID := range ticketTypeIDs {
+		result, err := session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+			expiredEntries, err := waitlistEntry.Find(sessionContext, bson.M{
+				"ticket_type_id":   ticketTypeID,
+				"status":           consts.WaitlistOffered,
+				"offer_expires_at": bson.M{"$lte": now},
+			})
+			if err != nil {
+				return nil, err
+			}
+			// released: số vé còn trống thêm sau transaction, dùng để cập nhật bộ đếm tồn kho
+			released := 0
+			for _, entry := range expiredEntries {
+				freed, err := releaseWaitlistOffer(sessionContext, entry, consts.WaitlistExpired, now)
+				if err != nil {
+					return nil, err
+				}
+				released += freed
+			}
+
+			held, err := OfferWaitlist(sessionContext, ticketTypeID, now)
+			if err != nil {
+				return nil, err
+			}
+			return [3]int{len(expiredEntries), held, released - held}, nil
+		})
+		if err != nil {
+			log.Printf("ERROR: Xử lý danh sách chờ loại vé %s thất bại: %v", ticketTypeID.Hex(), err)
+			continue
+		}
+		counts := result.([3]int)
+		expiredCount += counts[0]
+		heldCount += counts[1]
+		AdjustInventory(ctx, map[primitive.ObjectID]int{ticketTypeID: counts[2]})
+	}
+
+	return expiredCount, heldCount, nil
+}
+
+// Người dùng rời danh sách chờ, suất đang giữ (nếu có) được chuyển cho người kế tiếp
+func CancelWaitlistEntry(ctx context.Context, entryID, accountID primitive.ObjectID) error {
+	var (
+		waitlistEntry = collections.WaitlistEntry{}
+	)
+
+	err := waitlistEntry.First(ctx, bson.M{"_id": entryID, "account_id": accountID})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return consts.ErrWaitlistNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	switch waitlistEntry.Status {
+	case consts.WaitlistWaiting:
+		err = waitlistEntry.Update(ctx,
+			bson.M{"_id": entryID, "status": consts.WaitlistWaiting},
+			bson.M{"$set": bson.M{"status": consts.WaitlistCancelled, "updated_at": now}},
+		)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return consts.ErrWaitlistInvalidState
+		}
+		return err
+	case consts.WaitlistOffered:
+		session, err := database.GetDB().Client().StartSession()
+		if err != nil {
+			return err
+		}
+		defer session.EndSession(ctx)
+
+		released, err := session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (interface{}, error) {
+			return releaseWaitlistOffer(sessionContext, waitlistEntry, consts.WaitlistCancelled, now)
+		})
+		if err != nil {
+			return err
+		}
+		AdjustInventory(ctx, map[primitive.ObjectID]int{waitlistEntry.TicketTypeID: released.(int)})
+		return nil
+	default:
+		return consts.ErrWaitlistInvalidState
+	}
+}
+
+// Lượt giữ vé còn hạn của tài khoản trong sự kiện, theo loại vé
+func FindWaitlistOffers(ctx context.Context, eventID, accountID primitive.ObjectID, now time.Time) (map[primitive.ObjectID]collections.WaitlistEntry, error) {
+	waitlistEntry := &collections.WaitlistEntry{}
+	entries, err := waitlistEntry.Find(ctx, bson.M{
+		"event_id":         eventID,
+		"account_id":       accountID,
+		"status":           consts.WaitlistOffered,
+		"offer_expires_at": bson.M{"$gt": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	offers := make(map[primitive.ObjectID]collections.WaitlistEntry, len(entries))
+	for _, entry := range entries {
+		offers[entry.TicketTypeID] = entry
+	}
+	return offers, nil
+}
+
+// Đánh dấu lượt giữ vé đã dùng cho đơn. Gọi trong transaction đăng ký; lượt giữ vừa hết hạn thì hủy cả đơn.
+func ClaimWaitlistOffer(ctx context.Context, entryID, regisID primitive.ObjectID, now time.Time) error {
+	waitlistEntry := &collections.WaitlistEntry{}
+	err := waitlistEntry.Update(ctx,
+		bson.M{"_id": entryID, "status": consts.WaitlistOffered, "offer_expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{
+			"status":          consts.WaitlistClaimed,
+			"registration_id": regisID,
+			"updated_at":      now,
+		}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return consts.ErrWaitlistInvalidState
+	}
+	return err
+}
+
+// Gửi email mời giữ vé. Lượt chờ đã hết hạn/đã dùng trước khi email được gửi thì bỏ qua.
+func SendWaitlistOfferEmail(entryID primitive.ObjectID) error {
+	var (
+		waitlistEntry   = &collections.WaitlistEntry{}
+		eventEntry      = &collections.Event{}
+		accountEntry    = &collections.Account{}
+		ticketTypeEntry = &collections.TicketType{}
+	)
+
+	err := waitlistEntry.First(nil, bson.M{"_id": entryID})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: Waitlist ID %s", consts.ErrFatalDataNotFound, entryID.Hex())
+		}
+		return err
+	}
+	if waitlistEntry.Status != consts.WaitlistOffered {
+		log.Printf("INFO: Lượt chờ %s không còn ở trạng thái giữ vé (%s). Bỏ qua email.", entryID.Hex(), waitlistEntry.Status)
+		return nil
+	}
+
+	if err = eventEntry.First(nil, bson.M{"_id": waitlistEntry.EventID}); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: Event ID %s", consts.ErrFatalDataNotFound, waitlistEntry.EventID.Hex())
+		}
+		return err
+	}
+	if err = accountEntry.First(bson.M{"_id": waitlistEntry.AccountID}); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: Account ID %s", consts.ErrFatalDataNotFound, waitlistEntry.AccountID.Hex())
+		}
+		return err
+	}
+	if err = ticketTypeEntry.First(nil, bson.M{"_id": waitlistEntry.TicketTypeID}); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: TicketType ID %s", consts.ErrFatalDataNotFound, waitlistEntry.TicketTypeID.Hex())
+		}
+		return err
+	}
+
+	subject, htmlBody, err := view.BuildWaitlistOfferEmail(eventEntry, accountEntry, ticketTypeEntry, waitlistEntry)
+	if err != nil {
+		return fmt.Errorf("%w: %v", consts.ErrEmailBuild, err)
+	}
+
+	emailService := utils.NewEmailService()
+	return emailService.SendEmail(utils.EmailPayload{
+		Subject:  subject,
+		To:       []string{accountEntry.Email},
+		HTMLBody: htmlBody,
+	})
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"EventHunting/configs"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Vé vào cửa phòng chờ: JWT HS256 ký bằng secret riêng, gắn với một sự kiện và một tài khoản
+const admissionTokenAudience = "waiting_room"
+
+type AdmissionClaims struct {
+	EventID string `json:"event_id"`
+	jwt.RegisteredClaims
+}
+
+func admissionSecret() ([]byte, error) {
+	secret := strings.TrimSpace(configs.GetWaitingRoomTokenSecret())
+	if secret == "" {
+		return nil, errors.New("chưa cấu hình waiting_room.token_secret")
+	}
+	return []byte(secret), nil
+}
+
+func SignAdmissionToken(eventID, accountID primitive.ObjectID, issuedAt, expiresAt time.Time) (string, error) {
+	secret, err := admissionSecret()
+	if err != nil {
+		return "", err
+	}
+
+	claims := &AdmissionClaims{
+		EventID: eventID.Hex(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   accountID.Hex(),
+			Audience:  jwt.ClaimStrings{admissionTokenAudience},
+			Issuer:    configs.GetJWTIssuer(),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// Kiểm tra chữ ký, hạn dùng và đúng sự kiện / tài khoản
+func VerifyAdmissionToken(tokenStr string, eventID, accountID primitive.ObjectID) error {
+	secret, err := admissionSecret()
+	if err != nil {
+		return err
+	}
+
+	claims := &AdmissionClaims{}
+	_, err = jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(admissionTokenAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return fmt.Errorf("vé vào cửa không hợp lệ: %w", err)
+	}
+	if claims.EventID != eventID.Hex() || claims.Subject != accountID.Hex() {
+		return errors.New("vé vào cửa không thuộc sự kiện hoặc tài khoản này")
+	}
+	return nil
+}
@@ -2,13 +2,12 @@ package utils
 
 import (
 	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
-	"fmt"
 	"regexp"
 	"slices"
 	"strings"
-	"time"
 	"unicode"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -89,12 +88,14 @@ func CanModifyResource(ownerID primitive.ObjectID, accountID primitive.ObjectID,
 	return ownerID == accountID
 }
 
-func GenerateInvoiceNumber() string {
-	return fmt.Sprintf("INV-%s-%d", time.Now().Format("20060102"), time.Now().UnixNano()%10000)
+func HmacSha512(secret string, data string) string {
+	h := hmac.New(sha512.New, []byte(secret))
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
 }
 
-func hmacSha512(secret string, data string) string {
-	h := hmac.New(sha512.New, []byte(secret))
+func HmacSha256(secret string, data string) string {
+	h := hmac.New(sha256.New, []byte(secret))
 	h.Write([]byte(data))
 	return hex.EncodeToString(h.Sum(nil))
 }
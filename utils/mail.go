@@ -34,6 +34,7 @@ type EmailPayload struct {
 	HTMLBody string
 
 	EmbeddedImages map[string][]byte
+	Attachments    map[string][]byte // Tên file -> nội dung
 }
 
 func (s *EmailService) SendEmail(payload EmailPayload) error {
@@ -58,6 +59,13 @@ func (s *EmailService) SendEmail(payload EmailPayload) error {
 		}
 	}
 
+	for filename, data := range payload.Attachments {
+		m.Attach(filename, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		}))
+	}
+
 	if err := s.dialer.DialAndSend(m); err != nil {
 		log.Printf("LỖI GỬI MAIL (tới %v): %v", payload.To, err)
 		return err
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var vietnameseDigits = []string{"không", "một", "hai", "ba", "bốn", "năm", "sáu", "bảy", "tám", "chín"}
+
+// Định dạng số tiền theo kiểu Việt Nam: 1200000 -> "1.200.000"
+func FormatVND(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+
+	var builder strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			builder.WriteByte('.')
+		}
+		builder.WriteRune(digit)
+	}
+	return sign + builder.String()
+}
+
+// Đọc số tiền bằng chữ cho hóa đơn: 1205000 -> "Một triệu hai trăm linh năm nghìn đồng"
+func VietnameseAmountInWords(amount int64) string {
+	if amount == 0 {
+		return "Không đồng"
+	}
+
+	prefix := ""
+	if amount < 0 {
+		prefix = "âm "
+		amount = -amount
+	}
+
+	words := prefix + readVietnameseNumber(amount, false) + " đồng"
+	first, size := utf8.DecodeRuneInString(words)
+	return string(unicode.ToUpper(first)) + words[size:]
+}
+
+// full = true khi đã có nhóm đứng trước, phải đọc đủ "không trăm", "linh"
+func readVietnameseNumber(number int64, full bool) string {
+	const billion = 1_000_000_000
+	if number >= billion {
+		words := readVietnameseNumber(number/billion, full) + " tỷ"
+		if rest := number % billion; rest > 0 {
+			words += " " + readVietnameseNumber(rest, true)
+		}
+		return words
+	}
+
+	groups := []struct {
+		value int64
+		unit  string
+	}{
+		{number / 1_000_000, "triệu"},
+		{number / 1000 % 1000, "nghìn"},
+		{number % 1000, ""},
+	}
+
+	var parts []string
+	for _, group := range groups {
+		if group.value == 0 {
+			continue
+		}
+		parts = append(parts, readVietnameseTriple(group.value, full))
+		if group.unit != "" {
+			parts = append(parts, group.unit)
+		}
+		full = true
+	}
+	return strings.Join(parts, " ")
+}
+
+func readVietnameseTriple(number int64, full bool) string {
+	hundreds, tens, ones := number/100, number/10%10, number%10
+
+	var words []string
+	if full || hundreds > 0 {
+		words = append(words, vietnameseDigits[hundreds], "trăm")
+	}
+
+	switch {
+	case tens == 0 && ones > 0:
+		if full || hundreds > 0 {
+			words = append(words, "linh")
+		}
+		words = append(words, vietnameseDigits[ones])
+	case tens == 1:
+		words = append(words, "mười")
+		if ones == 5 {
+			words = append(words, "lăm")
+		} else if ones > 0 {
+			words = append(words, vietnameseDigits[ones])
+		}
+	case tens > 1:
+		words = append(words, vietnameseDigits[tens], "mươi")
+		switch ones {
+		case 0:
+		case 1:
+			words = append(words, "mốt")
+		case 4:
+			words = append(words, "tư")
+		case 5:
+			words = append(words, "lăm")
+		default:
+			words = append(words, vietnameseDigits[ones])
+		}
+	}
+	return strings.Join(words, " ")
+}
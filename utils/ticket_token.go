@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"EventHunting/configs"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Mã QR có chữ ký: T1.<key_id>.<payload>.<signature>
+// payload = ticket_id | event_id | ticket_type_id (3 x 12 bytes), ký bằng Ed25519 để máy quét có thể verify offline bằng public key.
+const TicketTokenVersion = "T1"
+
+type TicketTokenClaims struct {
+	KeyID        string
+	TicketID     primitive.ObjectID
+	EventID      primitive.ObjectID
+	TicketTypeID primitive.ObjectID
+}
+
+type TicketTokenPublicKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"alg"`
+	PublicKey string `json:"public_key"`
+	Active    bool   `json:"active"`
+}
+
+var (
+	ticketTokenKeys     map[string]ed25519.PrivateKey
+	ticketTokenKeysErr  error
+	ticketTokenKeysOnce sync.Once
+	ticketTokenEncoding = base64.RawURLEncoding
+)
+
+// Đọc danh sách key dạng "k1:<seed base64>,k2:<seed base64>"
+func loadTicketTokenKeys() (map[string]ed25519.PrivateKey, error) {
+	ticketTokenKeysOnce.Do(func() {
+		ticketTokenKeys = make(map[string]ed25519.PrivateKey)
+		for _, entry := range strings.Split(configs.GetTicketQRSigningKeys(), ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			keyID, seedStr, found := strings.Cut(entry, ":")
+			if !found || keyID == "" {
+				ticketTokenKeysErr = fmt.Errorf("cấu hình key QR không hợp lệ: %q", entry)
+				return
+			}
+			seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(seedStr))
+			if err != nil || len(seed) != ed25519.SeedSize {
+				ticketTokenKeysErr = fmt.Errorf("seed của key QR %s phải là %d bytes base64", keyID, ed25519.SeedSize)
+				return
+			}
+			ticketTokenKeys[keyID] = ed25519.NewKeyFromSeed(seed)
+		}
+	})
+	return ticketTokenKeys, ticketTokenKeysErr
+}
+
+func IsSignedTicketToken(code string) bool {
+	return strings.HasPrefix(code, TicketTokenVersion+".")
+}
+
+// Ký mã QR cho vé bằng key đang active
+func SignTicketToken(ticketID, eventID, ticketTypeID primitive.ObjectID) (string, error) {
+	keys, err := loadTicketTokenKeys()
+	if err != nil {
+		return "", err
+	}
+
+	keyID := strings.TrimSpace(configs.GetTicketQRActiveKeyID())
+	privateKey, ok := keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("không tìm thấy key QR đang active: %q", keyID)
+	}
+
+	payload := make([]byte, 0, 36)
+	payload = append(payload, ticketID[:]...)
+	payload = append(payload, eventID[:]...)
+	payload = append(payload, ticketTypeID[:]...)
+
+	signingInput := TicketTokenVersion + "." + keyID + "." + ticketTokenEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(privateKey, []byte(signingInput))
+	return signingInput + "." + ticketTokenEncoding.EncodeToString(signature), nil
+}
+
+// Kiểm tra chữ ký mã QR, hỗ trợ mọi key còn trong cấu hình (xoay vòng key)
+func VerifyTicketToken(token string) (*TicketTokenClaims, error) {
+	keys, err := loadTicketTokenKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 || parts[0] != TicketTokenVersion {
+		return nil, errors.New("sai định dạng mã QR")
+	}
+
+	privateKey, ok := keys[parts[1]]
+	if !ok {
+		return nil, fmt.Errorf("key QR %q không tồn tại hoặc đã bị thu hồi", parts[1])
+	}
+
+	payload, err := ticketTokenEncoding.DecodeString(parts[2])
+	if err != nil || len(payload) != 36 {
+		return nil, errors.New("sai định dạng payload mã QR")
+	}
+	signature, err := ticketTokenEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, errors.New("sai định dạng chữ ký mã QR")
+	}
+
+	signingInput := parts[0] + "." + parts[1] + "." + parts[2]
+	if !ed25519.Verify(privateKey.Public().(ed25519.PublicKey), []byte(signingInput), signature) {
+		return nil, errors.New("sai chữ ký mã QR")
+	}
+
+	claims := &TicketTokenClaims{KeyID: parts[1]}
+	copy(claims.TicketID[:], payload[0:12])
+	copy(claims.EventID[:], payload[12:24])
+	copy(claims.TicketTypeID[:], payload[24:36])
+	return claims, nil
+}
+
+// Danh sách public key cho thiết bị check-in verify offline
+func GetTicketTokenPublicKeys() ([]TicketTokenPublicKey, error) {
+	keys, err := loadTicketTokenKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	activeKeyID := strings.TrimSpace(configs.GetTicketQRActiveKeyID())
+	result := []TicketTokenPublicKey{}
+	for keyID, privateKey := range keys {
+		result = append(result, TicketTokenPublicKey{
+			KeyID:     keyID,
+			Algorithm: "Ed25519",
+			PublicKey: base64.StdEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)),
+			Active:    keyID == activeKeyID,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].KeyID < result[j].KeyID })
+	return result, nil
+}
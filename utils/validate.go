@@ -11,12 +11,14 @@ import (
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var (
 	Validator         *validator.Validate = validator.New()
 	PhoneRegex        string              = `^(0|\+84)(3|5|7|8|9)\d{8}$`
 	vietnamPhoneRegex                     = regexp.MustCompile(`^(0|\+84)(3|5|7|8|9)[0-9]{8}$`)
+	promoCodeRegex                        = regexp.MustCompile(`^[A-Za-z0-9_-]{3,32}$`)
 )
 
 func HandlerValidation(err error) string {
@@ -465,6 +467,11 @@ func ValidateEventCreateReq(e dto.EventCreateReq) []string {
 		errors = append(errors, "Số người tham gia tối đa phải lớn hơn 0 (nếu được cung cấp).")
 	}
 
+	errors = append(errors, validateRefundPolicy(e.RefundPolicy)...)
+	errors = append(errors, validateWaitingRoom(e.WaitingRoom)...)
+	errors = append(errors, validateLottery(e.Lottery)...)
+	errors = append(errors, validateRegistrationForm(e.RegistrationForm)...)
+
 	if e.TopicIDs != nil && len(*e.TopicIDs) == 0 {
 		errors = append(errors, "Danh sách chủ đề (TopicIDs) không được rỗng (nếu được cung cấp).")
 	}
@@ -555,6 +562,11 @@ func ValidateEventUpdateReq(req dto.EventUpdateReq) []string {
 		errs = append(errs, "Số người tham gia tối đa phải là số dương")
 	}
 
+	errs = append(errs, validateRefundPolicy(req.RefundPolicy)...)
+	errs = append(errs, validateWaitingRoom(req.WaitingRoom)...)
+	errs = append(errs, validateLottery(req.Lottery)...)
+	errs = append(errs, validateRegistrationForm(req.RegistrationForm)...)
+
 	if req.EventTime != nil {
 		et := req.EventTime
 
@@ -657,6 +669,154 @@ func ValidateCreateRegistrationReq(req dto.CreateRegistrationEventRequest) []str
 	return errs
 }
 
+// Refund
+func ValidateCreateRefund(req dto.CreateRefundRequest) []string {
+	var errs []string
+	if len(req.TicketIDs) > 100 {
+		errs = append(errs, "Mỗi yêu cầu hoàn tiền tối đa 100 vé")
+	}
+	if len(req.Reason) > 1000 {
+		errs = append(errs, "Lý do hoàn tiền tối đa 1000 ký tự")
+	}
+	return errs
+}
+
+func ValidateRejectRefund(req dto.RejectRefundRequest) []string {
+	var errs []string
+	if strings.TrimSpace(req.Reason) == "" {
+		errs = append(errs, "Lý do từ chối là bắt buộc")
+	}
+	return errs
+}
+
+func ValidateResolvePaymentDiscrepancy(req dto.ResolvePaymentDiscrepancyRequest) []string {
+	var errs []string
+	if strings.TrimSpace(req.Note) == "" {
+		errs = append(errs, "Ghi chú cách xử lý là bắt buộc")
+	}
+	return errs
+}
+
+func validateRefundPolicy(policy *dto.EventRefundPolicyReq) []string {
+	var errs []string
+	if policy == nil || !policy.Enabled {
+		return errs
+	}
+	if policy.DeadlineHours < 0 {
+		errs = append(errs, "Hạn hoàn tiền (giờ trước sự kiện) không được âm")
+	}
+	if policy.RefundPercent <= 0 || policy.RefundPercent > 100 {
+		errs = append(errs, "Phần trăm hoàn tiền phải từ 1 đến 100")
+	}
+	return errs
+}
+
+func validateWaitingRoom(room *dto.EventWaitingRoomReq) []string {
+	var errs []string
+	if room == nil || !room.Enabled {
+		return errs
+	}
+	if room.AdmitPerMinute <= 0 || room.AdmitPerMinute > 100000 {
+		errs = append(errs, "Số người được vào mỗi phút của phòng chờ phải từ 1 đến 100000")
+	}
+	return errs
+}
+
+func validateLottery(lottery *dto.EventLotteryReq) []string {
+	var errs []string
+	if lottery == nil || !lottery.Enabled {
+		return errs
+	}
+	if lottery.ApplyStartAt == nil || lottery.ApplyEndAt == nil {
+		errs = append(errs, "Bốc thăm cần thời gian bắt đầu và kết thúc nhận đăng ký")
+		return errs
+	}
+	if !lottery.ApplyEndAt.After(*lottery.ApplyStartAt) {
+		errs = append(errs, "Thời gian kết thúc nhận đăng ký bốc thăm phải sau thời gian bắt đầu")
+	}
+	return errs
+}
+
+func validateRegistrationForm(form *dto.EventRegistrationFormReq) []string {
+	var errs []string
+	if form == nil {
+		return errs
+	}
+	if len(form.Fields) > 50 {
+		errs = append(errs, "Form đăng ký tối đa 50 câu hỏi")
+		return errs
+	}
+
+	fieldIDs := make(map[string]bool, len(form.Fields))
+	for i, field := range form.Fields {
+		id := strings.TrimSpace(field.ID)
+		switch {
+		case id == "":
+			errs = append(errs, fmt.Sprintf("Câu hỏi %d thiếu id", i+1))
+		case len(id) > 50:
+			errs = append(errs, fmt.Sprintf("Câu hỏi %d có id dài quá 50 ký tự", i+1))
+		case fieldIDs[id]:
+			errs = append(errs, fmt.Sprintf("Câu hỏi %d trùng id '%s'", i+1, id))
+		}
+		fieldIDs[id] = true
+
+		if strings.TrimSpace(field.Label) == "" {
+			errs = append(errs, fmt.Sprintf("Câu hỏi %d thiếu nội dung", i+1))
+		}
+
+		switch consts.FormFieldType(field.Type) {
+		case consts.FormFieldText:
+			if len(field.Options) > 0 {
+				errs = append(errs, fmt.Sprintf("Câu hỏi %d dạng text không có lựa chọn", i+1))
+			}
+		case consts.FormFieldChoice, consts.FormFieldCheckbox:
+			if len(field.Options) == 0 {
+				errs = append(errs, fmt.Sprintf("Câu hỏi %d cần ít nhất 1 lựa chọn", i+1))
+			}
+			options := make(map[string]bool, len(field.Options))
+			for _, option := range field.Options {
+				option = strings.TrimSpace(option)
+				if option == "" || options[option] {
+					errs = append(errs, fmt.Sprintf("Câu hỏi %d có lựa chọn rỗng hoặc trùng nhau", i+1))
+					break
+				}
+				options[option] = true
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("Câu hỏi %d có loại không hợp lệ (text, choice, checkbox)", i+1))
+		}
+	}
+	return errs
+}
+
+// Check-in offline
+func ValidateSyncOfflineCheckIn(req dto.SyncOfflineCheckInRequest) []string {
+	var errs []string
+
+	if strings.TrimSpace(req.DeviceID) == "" {
+		errs = append(errs, "device_id là bắt buộc")
+	}
+	if len(req.Scans) == 0 {
+		errs = append(errs, "scans không để trống")
+	}
+	if len(req.Scans) > 5000 {
+		errs = append(errs, "Mỗi lần đồng bộ tối đa 5000 lượt quét")
+	}
+	for i, scan := range req.Scans {
+		if strings.TrimSpace(scan.QRCodeData) == "" && strings.TrimSpace(scan.TicketID) == "" {
+			errs = append(errs, fmt.Sprintf("Lượt quét %d thiếu qr_code_data hoặc ticket_id", i))
+		}
+		if scan.TicketID != "" && !primitive.IsValidObjectID(scan.TicketID) {
+			errs = append(errs, fmt.Sprintf("Lượt quét %d có ticket_id không hợp lệ", i))
+		}
+		if scan.ScannedAt.IsZero() {
+			errs = append(errs, fmt.Sprintf("Lượt quét %d thiếu scanned_at", i))
+		}
+	}
+
+	return errs
+}
+
 // Ticket type
 func ValidateCreateTicketType(req dto.CreateTicketType) []string {
 	var errs []string
@@ -686,6 +846,22 @@ func ValidateCreateTicketType(req dto.CreateTicketType) []string {
 		errs = append(errs, "Trạng thái không hợp lệ")
 	}
 
+	// Kiểm tra chính sách check-in
+	if req.CheckInPolicy != nil && !isValidCheckInPolicy(*req.CheckInPolicy) {
+		errs = append(errs, "Chính sách check-in không hợp lệ (single, daily, unlimited)")
+	}
+
+	// Kiểm tra thời gian mở bán và bậc giá
+	if req.SaleStartAt != nil && req.SaleEndAt != nil && !req.SaleEndAt.After(*req.SaleStartAt) {
+		errs = append(errs, "Thời gian kết thúc bán phải sau thời gian mở bán")
+	}
+	errs = append(errs, validatePriceTiers(req.PriceTiers)...)
+
+	// Bộ đếm tồn kho chỉ có ý nghĩa với vé giới hạn số lượng
+	if req.HighDemand && req.Quantity == nil {
+		errs = append(errs, "Chế độ bán số lượng lớn (high_demand) chỉ áp dụng cho vé có giới hạn số lượng")
+	}
+
 	return errs
 }
 
@@ -714,6 +890,11 @@ func ValidateUpdateTicketType(req dto.UpdateTicketType) []string {
 		errs = append(errs, "Trạng thái không hợp lệ")
 	}
 
+	// Kiểm tra chính sách check-in
+	if req.CheckInPolicy != nil && !isValidCheckInPolicy(*req.CheckInPolicy) {
+		errs = append(errs, "Chính sách check-in không hợp lệ (single, daily, unlimited)")
+	}
+
 	// Kiểm tra logic Số lượng
 	if req.SetUnlimited != nil && *req.SetUnlimited && req.Quantity != nil {
 		errs = append(errs, "Không thể đồng thời đặt số lượng và đặt 'không giới hạn'")
@@ -723,6 +904,53 @@ func ValidateUpdateTicketType(req dto.UpdateTicketType) []string {
 		errs = append(errs, "Số lượng (nếu được cung cấp) phải lớn hơn 0")
 	}
 
+	// Kiểm tra thời gian mở bán và bậc giá
+	if req.ClearSaleWindow != nil && *req.ClearSaleWindow && (req.SaleStartAt != nil || req.SaleEndAt != nil) {
+		errs = append(errs, "Không thể đồng thời đặt thời gian bán và bỏ giới hạn thời gian bán")
+	}
+	if req.PriceTiers != nil {
+		errs = append(errs, validatePriceTiers(*req.PriceTiers)...)
+	}
+
+	return errs
+}
+
+// Bậc giá cần ít nhất một điều kiện kết thúc, các mốc thời gian và số lượng phải tăng dần theo thứ tự bậc
+func validatePriceTiers(tiers []dto.TicketPriceTier) []string {
+	var (
+		errs        []string
+		lastEndAt   *time.Time
+		lastMaxSold *int
+	)
+
+	for i, tier := range tiers {
+		label := fmt.Sprintf("Bậc giá %d", i+1)
+		if strings.TrimSpace(tier.Name) == "" {
+			errs = append(errs, label+": tên là bắt buộc")
+		}
+		if tier.Price < 0 {
+			errs = append(errs, label+": giá không được là số âm")
+		}
+		if tier.EndAt == nil && tier.MaxSold == nil {
+			errs = append(errs, label+": cần thời gian kết thúc (end_at) hoặc số vé tối đa (max_sold)")
+		}
+		if tier.MaxSold != nil && *tier.MaxSold <= 0 {
+			errs = append(errs, label+": số vé tối đa phải lớn hơn 0")
+		}
+		if tier.EndAt != nil {
+			if lastEndAt != nil && !tier.EndAt.After(*lastEndAt) {
+				errs = append(errs, label+": thời gian kết thúc phải sau bậc trước")
+			}
+			lastEndAt = tier.EndAt
+		}
+		if tier.MaxSold != nil {
+			if lastMaxSold != nil && *tier.MaxSold <= *lastMaxSold {
+				errs = append(errs, label+": số vé tối đa phải lớn hơn bậc trước")
+			}
+			lastMaxSold = tier.MaxSold
+		}
+	}
+
 	return errs
 }
 
@@ -737,6 +965,18 @@ func isValidTicketStatus(status consts.TicketTypeStatus) bool {
 		return false
 	}
 }
+
+func isValidCheckInPolicy(policy consts.CheckInPolicy) bool {
+	switch policy {
+	case consts.CheckInPolicySingle,
+		consts.CheckInPolicyDaily,
+		consts.CheckInPolicyUnlimited:
+		return true
+	default:
+		return false
+	}
+}
+
 func init() {
 	// Custom validator cho số điện thoại VN
 	_ = Validator.RegisterValidation("phoneVn", func(fl validator.FieldLevel) bool {
@@ -745,3 +985,85 @@ func init() {
 		return matched
 	})
 }
+
+func ValidateCreatePromoCode(req dto.CreatePromoCode) []string {
+	var errs []string
+
+	if _, err := primitive.ObjectIDFromHex(req.EventID); err != nil {
+		errs = append(errs, "Event ID không hợp lệ")
+	}
+
+	// Mã gồm chữ, số, '-' hoặc '_', dài 3-32 ký tự
+	if !promoCodeRegex.MatchString(strings.TrimSpace(req.Code)) {
+		errs = append(errs, "Mã giảm giá chỉ gồm chữ, số, '-' hoặc '_' và dài 3-32 ký tự")
+	}
+
+	if req.Description != nil && strings.TrimSpace(*req.Description) == "" {
+		errs = append(errs, "Mô tả không được trống")
+	}
+
+	switch req.DiscountType {
+	case consts.PromoDiscountPercent:
+		if req.DiscountValue <= 0 || req.DiscountValue > 100 {
+			errs = append(errs, "Phần trăm giảm phải trong khoảng 1-100")
+		}
+	case consts.PromoDiscountFixed:
+		if req.DiscountValue <= 0 {
+			errs = append(errs, "Số tiền giảm phải lớn hơn 0")
+		}
+		if req.MaxDiscount != nil {
+			errs = append(errs, "Mức giảm tối đa chỉ dùng cho mã giảm theo phần trăm")
+		}
+	default:
+		errs = append(errs, "Loại giảm giá không hợp lệ (percent, fixed)")
+	}
+
+	if req.MaxDiscount != nil && *req.MaxDiscount <= 0 {
+		errs = append(errs, "Mức giảm tối đa phải lớn hơn 0")
+	}
+
+	for _, id := range req.TicketTypeIDs {
+		if _, err := primitive.ObjectIDFromHex(id); err != nil {
+			errs = append(errs, fmt.Sprintf("Loại vé %s không hợp lệ", id))
+		}
+	}
+
+	errs = append(errs, validatePromoCodeLimits(req.MaxUses, req.MaxUsesPerUser, req.StartAt, req.EndAt, req.Status)...)
+
+	return errs
+}
+
+func ValidateUpdatePromoCode(req dto.UpdatePromoCode) []string {
+	var errs []string
+
+	if req.Description != nil && strings.TrimSpace(*req.Description) == "" {
+		errs = append(errs, "Mô tả không được rỗng")
+	}
+
+	if req.SetUnlimited != nil && *req.SetUnlimited && req.MaxUses != nil {
+		errs = append(errs, "Không thể đồng thời đặt số lượt và đặt 'không giới hạn'")
+	}
+
+	errs = append(errs, validatePromoCodeLimits(req.MaxUses, req.MaxUsesPerUser, req.StartAt, req.EndAt, req.Status)...)
+
+	return errs
+}
+
+func validatePromoCodeLimits(maxUses, maxUsesPerUser *int, startAt, endAt *time.Time, status *consts.PromoCodeStatus) []string {
+	var errs []string
+
+	if maxUses != nil && *maxUses <= 0 {
+		errs = append(errs, "Tổng số lượt (nếu được cung cấp) phải lớn hơn 0")
+	}
+	if maxUsesPerUser != nil && *maxUsesPerUser <= 0 {
+		errs = append(errs, "Số lượt mỗi người (nếu được cung cấp) phải lớn hơn 0")
+	}
+	if startAt != nil && endAt != nil && !endAt.After(*startAt) {
+		errs = append(errs, "Thời gian kết thúc phải sau thời gian bắt đầu")
+	}
+	if status != nil && *status != consts.PromoCodeActive && *status != consts.PromoCodeInactive {
+		errs = append(errs, "Trạng thái không hợp lệ (active, inactive)")
+	}
+
+	return errs
+}
@@ -0,0 +1,207 @@
+package view
+
+import (
+	"EventHunting/collections"
+	"EventHunting/configs"
+	"EventHunting/consts"
+	"EventHunting/utils"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	defaultInvoiceFontDir = "/usr/share/fonts/truetype/dejavu"
+	invoiceFontFamily     = "DejaVu"
+)
+
+var (
+	invoiceFontMu      sync.Mutex
+	invoiceFontRegular []byte
+	invoiceFontBold    []byte
+)
+
+// Cột bảng hàng hóa: STT, mô tả, số lượng, đơn giá, thành tiền (tổng 190mm = A4 trừ lề)
+var invoiceColumnWidths = []float64{12, 88, 15, 35, 40}
+
+// Font Unicode để in tiếng Việt, đọc một lần rồi dùng lại cho các lần sau
+func loadInvoiceFonts() ([]byte, []byte, error) {
+	invoiceFontMu.Lock()
+	defer invoiceFontMu.Unlock()
+
+	if invoiceFontRegular != nil && invoiceFontBold != nil {
+		return invoiceFontRegular, invoiceFontBold, nil
+	}
+
+	fontDir := strings.TrimSpace(configs.GetInvoicePDFFontDir())
+	if fontDir == "" {
+		fontDir = defaultInvoiceFontDir
+	}
+	regular, err := os.ReadFile(filepath.Join(fontDir, "DejaVuSans.ttf"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("không đọc được font hóa đơn: %w", err)
+	}
+	bold, err := os.ReadFile(filepath.Join(fontDir, "DejaVuSans-Bold.ttf"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("không đọc được font hóa đơn: %w", err)
+	}
+
+	invoiceFontRegular, invoiceFontBold = regular, bold
+	return regular, bold, nil
+}
+
+// Dựng file PDF cho hóa đơn hoặc hóa đơn điều chỉnh giảm (originalInvoice là hóa đơn gốc, nil nếu không có)
+func BuildInvoicePDF(invoice *collections.Invoice, originalInvoice *collections.Invoice) ([]byte, error) {
+	regularFont, boldFont, err := loadInvoiceFonts()
+	if err != nil {
+		return nil, err
+	}
+
+	loc := time.FixedZone("Asia/Ho_Chi_Minh", 7*60*60)
+	isCreditNote := invoice.Type == consts.InvoiceTypeCreditNote
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(10, 12, 10)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AddUTF8FontFromBytes(invoiceFontFamily, "", regularFont)
+	pdf.AddUTF8FontFromBytes(invoiceFontFamily, "B", boldFont)
+	pdf.SetTitle("Hóa đơn "+invoice.InvoiceNumber, true)
+	pdf.SetCreator("EventHunting", true)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(invoiceFontFamily, "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Hóa đơn %s - Trang %d/{nb}", invoice.InvoiceNumber, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	// Đơn vị bán hàng
+	if sellerName := strings.TrimSpace(configs.GetInvoiceSellerName()); sellerName != "" {
+		pdf.SetFont(invoiceFontFamily, "B", 12)
+		pdf.CellFormat(0, 6, sellerName, "", 1, "L", false, 0, "")
+	}
+	pdf.SetFont(invoiceFontFamily, "", 9)
+	if taxCode := strings.TrimSpace(configs.GetInvoiceSellerTaxCode()); taxCode != "" {
+		pdf.CellFormat(0, 5, "Mã số thuế: "+taxCode, "", 1, "L", false, 0, "")
+	}
+	if address := strings.TrimSpace(configs.GetInvoiceSellerAddress()); address != "" {
+		pdf.MultiCell(0, 5, "Địa chỉ: "+address, "", "L", false)
+	}
+	pdf.Ln(4)
+
+	// Tiêu đề
+	title := "HÓA ĐƠN BÁN HÀNG"
+	if isCreditNote {
+		title = "HÓA ĐƠN ĐIỀU CHỈNH GIẢM"
+	}
+	pdf.SetFont(invoiceFontFamily, "B", 16)
+	pdf.CellFormat(0, 9, title, "", 1, "C", false, 0, "")
+	pdf.SetFont(invoiceFontFamily, "", 10)
+	pdf.CellFormat(0, 5, "Số: "+invoice.InvoiceNumber, "", 1, "C", false, 0, "")
+	pdf.CellFormat(0, 5, "Ngày lập: "+invoice.CreatedAt.In(loc).Format("02/01/2006 15:04"), "", 1, "C", false, 0, "")
+	if isCreditNote && originalInvoice != nil {
+		pdf.CellFormat(0, 5, fmt.Sprintf("Điều chỉnh cho hóa đơn số %s ngày %s", originalInvoice.InvoiceNumber, originalInvoice.CreatedAt.In(loc).Format("02/01/2006")), "", 1, "C", false, 0, "")
+	}
+	pdf.Ln(5)
+
+	// Khách hàng và sự kiện
+	writeInvoiceField(pdf, "Khách hàng", invoice.CustomerDetails.Name)
+	writeInvoiceField(pdf, "Email", invoice.CustomerDetails.Email)
+	writeInvoiceField(pdf, "Điện thoại", invoice.CustomerDetails.Phone)
+	writeInvoiceField(pdf, "Sự kiện", invoice.EventDetails.Name)
+	if !invoice.EventDetails.StartDate.IsZero() {
+		eventTime := invoice.EventDetails.StartDate.In(loc).Format("02/01/2006")
+		if !invoice.EventDetails.EndDate.IsZero() && !invoice.EventDetails.EndDate.Equal(invoice.EventDetails.StartDate) {
+			eventTime += " - " + invoice.EventDetails.EndDate.In(loc).Format("02/01/2006")
+		}
+		writeInvoiceField(pdf, "Thời gian", eventTime)
+	}
+	writeInvoiceField(pdf, "Mã đơn", invoice.RegistrationID.Hex())
+	pdf.Ln(4)
+
+	// Bảng hàng hóa
+	pdf.SetFont(invoiceFontFamily, "B", 9)
+	pdf.SetFillColor(238, 238, 238)
+	for i, header := range []string{"STT", "Nội dung", "SL", "Đơn giá (đ)", "Thành tiền (đ)"} {
+		pdf.CellFormat(invoiceColumnWidths[i], 8, header, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont(invoiceFontFamily, "", 9)
+	for i, item := range invoice.LineItems {
+		writeInvoiceLineItem(pdf, i+1, item)
+	}
+
+	pdf.SetFont(invoiceFontFamily, "B", 10)
+	totalLabelWidth := invoiceColumnWidths[0] + invoiceColumnWidths[1] + invoiceColumnWidths[2] + invoiceColumnWidths[3]
+	pdf.CellFormat(totalLabelWidth, 8, "Tổng cộng", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(invoiceColumnWidths[4], 8, utils.FormatVND(int64(invoice.TotalAmount)), "1", 1, "R", false, 0, "")
+	pdf.Ln(2)
+	pdf.SetFont(invoiceFontFamily, "", 10)
+	pdf.MultiCell(0, 6, "Số tiền viết bằng chữ: "+utils.VietnameseAmountInWords(int64(invoice.TotalAmount)), "", "L", false)
+	pdf.Ln(4)
+
+	// Thanh toán
+	paymentLabel := "Thanh toán"
+	if isCreditNote {
+		paymentLabel = "Hoàn tiền"
+	}
+	pdf.SetFont(invoiceFontFamily, "B", 10)
+	pdf.CellFormat(0, 6, paymentLabel, "", 1, "L", false, 0, "")
+	writeInvoiceField(pdf, "Phương thức", invoice.PaymentDetails.Method)
+	writeInvoiceField(pdf, "Mã giao dịch", invoice.PaymentDetails.TransactionCode)
+	if !invoice.PaymentDetails.PaidAt.IsZero() {
+		writeInvoiceField(pdf, "Thời gian", invoice.PaymentDetails.PaidAt.In(loc).Format("02/01/2006 15:04"))
+	}
+	pdf.Ln(6)
+
+	pdf.SetFont(invoiceFontFamily, "", 8)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.MultiCell(0, 4, "Hóa đơn được tạo tự động từ hệ thống EventHunting và không thay thế hóa đơn điện tử theo quy định.", "", "C", false)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("lỗi tạo PDF hóa đơn: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeInvoiceField(pdf *fpdf.Fpdf, label string, value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+	pdf.SetFont(invoiceFontFamily, "", 10)
+	pdf.CellFormat(30, 6, label+":", "", 0, "L", false, 0, "")
+	pdf.MultiCell(0, 6, value, "", "L", false)
+}
+
+// Mô tả dài được xuống dòng, các cột còn lại cao bằng cột mô tả
+func writeInvoiceLineItem(pdf *fpdf.Fpdf, index int, item collections.InvoiceLstItem) {
+	const lineHeight = 6
+	lines := pdf.SplitText(item.Description, invoiceColumnWidths[1])
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	rowHeight := float64(len(lines)) * lineHeight
+
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottomMargin := pdf.GetMargins()
+	if pdf.GetY()+rowHeight > pageHeight-bottomMargin {
+		pdf.AddPage()
+	}
+
+	x, y := pdf.GetX(), pdf.GetY()
+	pdf.CellFormat(invoiceColumnWidths[0], rowHeight, fmt.Sprintf("%d", index), "1", 0, "C", false, 0, "")
+	pdf.MultiCell(invoiceColumnWidths[1], lineHeight, strings.Join(lines, "\n"), "1", "L", false)
+	pdf.SetXY(x+invoiceColumnWidths[0]+invoiceColumnWidths[1], y)
+	pdf.CellFormat(invoiceColumnWidths[2], rowHeight, fmt.Sprintf("%d", item.Quantity), "1", 0, "C", false, 0, "")
+	pdf.CellFormat(invoiceColumnWidths[3], rowHeight, utils.FormatVND(int64(item.UnitPrice)), "1", 0, "R", false, 0, "")
+	pdf.CellFormat(invoiceColumnWidths[4], rowHeight, utils.FormatVND(int64(item.TotalAmount)), "1", 1, "R", false, 0, "")
+}
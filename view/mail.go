@@ -3,6 +3,7 @@ package view
 import (
 	"EventHunting/collections"
 	"EventHunting/configs"
+	"EventHunting/consts"
 	"fmt"
 	"html/template"
 	"log"
@@ -28,6 +29,14 @@ type TicketTemplateData struct {
 	TicketPrice    int // Giả sử Price là int64
 	RegisterDate   string
 	TicketCode     string
+	AttendeeName   string
+	AttendeeEmail  string
+	Answers        []AnswerTemplateData
+}
+
+type AnswerTemplateData struct {
+	Label string
+	Value string
 }
 
 // Dùng html/template thay vì strings.Builder để an toàn và dễ bảo trì
@@ -57,6 +66,8 @@ var ticketEmailTemplate = template.Must(template.New("ticketEmail").Parse(`
                     <strong style='font-size: 16px; color: #333;'>{{.TicketTypeName}}</strong><br>
                     Giá vé: {{.TicketPrice}} VNĐ<br>
                     Ngày đăng ký: {{.RegisterDate}}<br>
+                    {{if .AttendeeName}}Người tham dự: {{.AttendeeName}} ({{.AttendeeEmail}})<br>{{end}}
+                    {{range .Answers}}{{.Label}}: {{.Value}}<br>{{end}}
                     Mã vé: <code style='font-size: 13px; background-color: #f4f4f4; padding: 2px 5px; border-radius: 4px;'>{{.TicketCode}}</code>
                 </td>
             </tr>
@@ -70,6 +81,16 @@ var ticketEmailTemplate = template.Must(template.New("ticketEmail").Parse(`
 </body></html>
 `))
 
+// Tạo ảnh PNG mã QR trỏ tới đường dẫn check-in của vé
+func BuildTicketQRCode(qrCodeData string, size int) ([]byte, error) {
+	qrLink := configs.GetServerDomain() + "/ticket/checkin/token?" + qrCodeData
+	qrCodePng, err := qrcode.Encode(qrLink, qrcode.Medium, size)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi tạo mã QR cho vé %s: %w", qrCodeData, err)
+	}
+	return qrCodePng, nil
+}
+
 func BuildTicketEmail(
 	eventEntry *collections.Event,
 	accountEntry *collections.Account,
@@ -92,10 +113,9 @@ func BuildTicketEmail(
 
 	for i, ticket := range tickets {
 		// Tạo QR
-		qrLink := configs.GetServerDomain() + "/ticket/checkin/token?" + ticket.QRCodeData
-		qrCodePng, err := qrcode.Encode(qrLink, qrcode.Medium, 256)
+		qrCodePng, err := BuildTicketQRCode(ticket.QRCodeData, 256)
 		if err != nil {
-			return "", "", nil, fmt.Errorf("lỗi tạo mã QR cho vé %s: %w", ticket.QRCodeData, err)
+			return "", "", nil, err
 		}
 
 		cid := fmt.Sprintf("qrcode%d.png", i)
@@ -115,6 +135,13 @@ func BuildTicketEmail(
 			RegisterDate:   ticket.CreatedAt.In(vietnamLoc).Format("15:04 02/01/2006"),
 			TicketCode:     ticket.QRCodeData,
 		}
+		if ticket.Attendee != nil {
+			ticketData.AttendeeName = ticket.Attendee.Name
+			ticketData.AttendeeEmail = ticket.Attendee.Email
+			for _, answer := range ticket.Attendee.Answers {
+				ticketData.Answers = append(ticketData.Answers, AnswerTemplateData{Label: answer.Label, Value: answer.Display()})
+			}
+		}
 
 		templateData.Tickets = append(templateData.Tickets, ticketData)
 	}
@@ -127,3 +154,106 @@ func BuildTicketEmail(
 	subject := fmt.Sprintf("Vé tham dự sự kiện: %s", eventEntry.Name)
 	return subject, emailBody.String(), embeddedFiles, nil
 }
+
+// Waitlist
+type WaitlistOfferTemplateData struct {
+	RecipientName  string
+	EventName      string
+	TicketTypeName string
+	Quantity       int
+	ExpiresAt      string
+}
+
+var waitlistOfferEmailTemplate = template.Must(template.New("waitlistOfferEmail").Parse(`
+<html><body style='font-family: Arial, sans-serif; line-height: 1.6; margin: 0; padding: 0;'>
+<div style='max-width: 640px; margin: 20px auto; padding: 20px; border: 1px solid #ddd; border-radius: 8px;'>
+    <h2>Xin chào {{.RecipientName}},</h2>
+    <p>Đã có vé trống cho sự kiện <strong>{{.EventName}}</strong> mà bạn đang chờ.</p>
+    <p style='margin: 5px 0;'><strong>Loại vé:</strong> {{.TicketTypeName}}</p>
+    <p style='margin: 5px 0;'><strong>Số vé được giữ:</strong> {{.Quantity}}</p>
+    <p style='margin: 5px 0;'><strong>Giữ đến:</strong> {{.ExpiresAt}}</p>
+    <p>Vui lòng đăng ký loại vé này trước thời hạn trên. Sau thời hạn, suất giữ vé sẽ được chuyển cho người kế tiếp trong danh sách chờ.</p>
+
+    <hr style='border: 0; border-top: 1px solid #eee; margin-top: 20px;'>
+    <p style='font-size: 12px; color: #777;'>Trân trọng,<br>Đội ngũ EventHunting</p>
+</div>
+</body></html>
+`))
+
+func BuildWaitlistOfferEmail(
+	eventEntry *collections.Event,
+	accountEntry *collections.Account,
+	ticketTypeEntry *collections.TicketType,
+	waitlistEntry *collections.WaitlistEntry,
+) (string, string, error) {
+	vietnamLoc := time.FixedZone("Asia/Ho_Chi_Minh", 7*60*60)
+
+	templateData := WaitlistOfferTemplateData{
+		RecipientName:  accountEntry.Name,
+		EventName:      eventEntry.Name,
+		TicketTypeName: ticketTypeEntry.Name,
+		Quantity:       waitlistEntry.OfferedQuantity,
+	}
+	if waitlistEntry.OfferExpiresAt != nil {
+		templateData.ExpiresAt = waitlistEntry.OfferExpiresAt.In(vietnamLoc).Format("15:04 02/01/2006")
+	}
+
+	var emailBody strings.Builder
+	if err := waitlistOfferEmailTemplate.Execute(&emailBody, templateData); err != nil {
+		return "", "", fmt.Errorf("lỗi render email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("Đã có vé trống: %s", eventEntry.Name)
+	return subject, emailBody.String(), nil
+}
+
+// Lottery
+type LotteryResultTemplateData struct {
+	RecipientName  string
+	EventName      string
+	TicketTypeName string
+	Quantity       int
+	Won            bool
+}
+
+var lotteryResultEmailTemplate = template.Must(template.New("lotteryResultEmail").Parse(`
+<html><body style='font-family: Arial, sans-serif; line-height: 1.6; margin: 0; padding: 0;'>
+<div style='max-width: 640px; margin: 20px auto; padding: 20px; border: 1px solid #ddd; border-radius: 8px;'>
+    <h2>Xin chào {{.RecipientName}},</h2>
+    <p>Sự kiện <strong>{{.EventName}}</strong> đã bốc thăm phân bổ vé.</p>
+    <p style='margin: 5px 0;'><strong>Loại vé:</strong> {{.TicketTypeName}}</p>
+    <p style='margin: 5px 0;'><strong>Số vé đăng ký:</strong> {{.Quantity}}</p>
+    {{if .Won}}
+    <p>Chúc mừng, bạn đã <strong>trúng</strong> bốc thăm! Đơn đăng ký đã được xác nhận, vé tham dự sẽ được gửi trong một email riêng.</p>
+    {{else}}
+    <p>Rất tiếc, lần này bạn <strong>không trúng</strong> bốc thăm. Cảm ơn bạn đã quan tâm tới sự kiện.</p>
+    {{end}}
+
+    <hr style='border: 0; border-top: 1px solid #eee; margin-top: 20px;'>
+    <p style='font-size: 12px; color: #777;'>Trân trọng,<br>Đội ngũ EventHunting</p>
+</div>
+</body></html>
+`))
+
+func BuildLotteryResultEmail(
+	eventEntry *collections.Event,
+	accountEntry *collections.Account,
+	ticketTypeEntry *collections.TicketType,
+	lotteryEntry *collections.LotteryEntry,
+) (string, string, error) {
+	templateData := LotteryResultTemplateData{
+		RecipientName:  accountEntry.Name,
+		EventName:      eventEntry.Name,
+		TicketTypeName: ticketTypeEntry.Name,
+		Quantity:       lotteryEntry.Quantity,
+		Won:            lotteryEntry.Status == consts.LotteryEntryWon,
+	}
+
+	var emailBody strings.Builder
+	if err := lotteryResultEmailTemplate.Execute(&emailBody, templateData); err != nil {
+		return "", "", fmt.Errorf("lỗi render email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("Kết quả bốc thăm: %s", eventEntry.Name)
+	return subject, emailBody.String(), nil
+}
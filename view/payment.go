@@ -0,0 +1,41 @@
+package view
+
+import (
+	"html/template"
+	"strings"
+)
+
+// Trang kết quả thanh toán hiển thị khi trình duyệt quay về từ cổng thanh toán
+type PaymentResultData struct {
+	Success      bool
+	OrderID      string
+	Amount       int64
+	ResponseCode string
+	Message      string
+}
+
+var paymentResultTemplate = template.Must(template.New("paymentResult").Parse(`
+<html><head><meta charset='utf-8'><title>Kết quả thanh toán</title></head>
+<body style='font-family: Arial, sans-serif; line-height: 1.6; margin: 0; padding: 0;'>
+<div style='max-width: 480px; margin: 40px auto; padding: 24px; border: 1px solid #ddd; border-radius: 8px; text-align: center;'>
+    {{if .Success}}
+    <h2 style='color: #2e7d32;'>Thanh toán thành công</h2>
+    <p>Vé sẽ được gửi tới email của bạn sau khi hệ thống xác nhận giao dịch.</p>
+    {{else}}
+    <h2 style='color: #c62828;'>Thanh toán không thành công</h2>
+    {{end}}
+    <p>{{.Message}}</p>
+    {{if .OrderID}}<p style='margin: 5px 0;'><strong>Mã đơn:</strong> {{.OrderID}}</p>{{end}}
+    {{if .Amount}}<p style='margin: 5px 0;'><strong>Số tiền:</strong> {{.Amount}} VNĐ</p>{{end}}
+    {{if .ResponseCode}}<p style='font-size: 12px; color: #777;'>Mã phản hồi: {{.ResponseCode}}</p>{{end}}
+</div>
+</body></html>
+`))
+
+func BuildPaymentResultPage(data PaymentResultData) (string, error) {
+	var body strings.Builder
+	if err := paymentResultTemplate.Execute(&body, data); err != nil {
+		return "", err
+	}
+	return body.String(), nil
+}